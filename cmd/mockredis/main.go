@@ -0,0 +1,26 @@
+// mockredis 本地模拟的Redis发布订阅服务，用于在开发和测试环境中验证多节点部署的redis广播总线
+//
+// 命令支持范围见 internal/mockredis。
+// 启动：go run ./cmd/mockredis -addr :6379 [-password secret]
+package main
+
+import (
+	"flag"
+	"gochat/internal/mockredis"
+	"log"
+	"net"
+)
+
+func main() {
+	addr := flag.String("addr", ":6379", "listen address")
+	password := flag.String("password", "", "password required by AUTH (empty disables authentication)")
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("mock redis listening on %s (auth %v)", *addr, *password != "")
+	log.Fatal(mockredis.NewServer(*password).Serve(listener))
+}
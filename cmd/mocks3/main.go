@@ -0,0 +1,296 @@
+// mocks3 本地模拟的S3兼容对象存储，用于在开发和测试环境中验证附件的s3存储后端
+//
+// 只支持路径风格地址（/<bucket>/<key>）的PUT、GET、HEAD、DELETE，对象保存在内存中，存储桶在首次写入时自动创建。
+// 校验Authorization头签名和预签名地址（AWS Signature Version 4），与 storage.s3 配置中的密钥一致时才放行。
+// 启动：go run ./cmd/mocks3 -addr :9000 -access-key gochat -secret-key gochat-secret
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	algorithm  = "AWS4-HMAC-SHA256"
+	timeFormat = "20060102T150405Z"
+	maxSkew    = 15 * time.Minute
+)
+
+type object struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+	etag        string
+}
+
+type mockS3 struct {
+	accessKey string
+	secretKey string
+	region    string
+
+	mutex   sync.RWMutex
+	objects map[string]*object // 键为 bucket/key
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	accessKey := flag.String("access-key", "gochat", "expected access key")
+	secretKey := flag.String("secret-key", "gochat-secret", "secret key used to verify signatures")
+	region := flag.String("region", "us-east-1", "expected region")
+	flag.Parse()
+
+	s := &mockS3{
+		accessKey: *accessKey,
+		secretKey: *secretKey,
+		region:    *region,
+		objects:   make(map[string]*object),
+	}
+
+	log.Printf("mock s3 listening on %s (access key %q, region %q)", *addr, *accessKey, *region)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
+
+func (s *mockS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket == "" || !ok || key == "" {
+		s3Error(w, http.StatusBadRequest, "InvalidRequest", "path-style object URL required")
+		return
+	}
+
+	if err := s.verify(r); err != "" {
+		log.Printf("%s %s rejected: %s", r.Method, r.URL.Path, err)
+		s3Error(w, http.StatusForbidden, "SignatureDoesNotMatch", err)
+		return
+	}
+
+	name := bucket + "/" + key
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			s3Error(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		sum := sha256.Sum256(data)
+		obj := &object{
+			data:        data,
+			contentType: r.Header.Get("Content-Type"),
+			modTime:     time.Now().UTC().Truncate(time.Second),
+			etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+		}
+		s.mutex.Lock()
+		s.objects[name] = obj
+		s.mutex.Unlock()
+
+		log.Printf("PUT %s (%d bytes, %s)", name, len(data), obj.contentType)
+		w.Header().Set("ETag", obj.etag)
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodGet, http.MethodHead:
+		s.mutex.RLock()
+		obj, exists := s.objects[name]
+		s.mutex.RUnlock()
+		if !exists {
+			s3Error(w, http.StatusNotFound, "NoSuchKey", "the specified key does not exist")
+			return
+		}
+
+		contentType := obj.contentType
+		if override := r.URL.Query().Get("response-content-type"); override != "" {
+			contentType = override
+		}
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		if disposition := r.URL.Query().Get("response-content-disposition"); disposition != "" {
+			w.Header().Set("Content-Disposition", disposition)
+		}
+		w.Header().Set("ETag", obj.etag)
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+
+		log.Printf("%s %s", r.Method, name)
+		http.ServeContent(w, r, "", obj.modTime, bytes.NewReader(obj.data))
+
+	case http.MethodDelete:
+		s.mutex.Lock()
+		delete(s.objects, name)
+		s.mutex.Unlock()
+
+		log.Printf("DELETE %s", name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// verify 校验请求签名，返回空字符串表示通过
+func (s *mockS3) verify(r *http.Request) string {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Signature") != "" {
+		return s.verifyPresigned(r, query)
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, algorithm+" ") {
+		return "missing or unsupported Authorization header"
+	}
+	fields := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(auth, algorithm+" "), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		fields[name] = value
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	t, err := time.Parse(timeFormat, amzDate)
+	if err != nil {
+		return "missing or invalid X-Amz-Date"
+	}
+	if skew := time.Since(t); skew > maxSkew || skew < -maxSkew {
+		return "request time too skewed"
+	}
+	if err := s.checkCredential(fields["Credential"], t); err != "" {
+		return err
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		return "missing X-Amz-Content-Sha256"
+	}
+	signedHeaders := fields["SignedHeaders"]
+	if !strings.Contains(";"+signedHeaders+";", ";host;") {
+		return "host header must be signed"
+	}
+
+	expected := s.sign(r, query, signedHeaders, payloadHash, t)
+	if !hmac.Equal([]byte(expected), []byte(fields["Signature"])) {
+		return "signature mismatch"
+	}
+	return ""
+}
+
+// verifyPresigned 校验预签名地址
+func (s *mockS3) verifyPresigned(r *http.Request, query url.Values) string {
+	if query.Get("X-Amz-Algorithm") != algorithm {
+		return "unsupported X-Amz-Algorithm"
+	}
+	t, err := time.Parse(timeFormat, query.Get("X-Amz-Date"))
+	if err != nil {
+		return "invalid X-Amz-Date"
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires <= 0 {
+		return "invalid X-Amz-Expires"
+	}
+	if time.Now().After(t.Add(time.Duration(expires) * time.Second)) {
+		return "request has expired"
+	}
+	if err := s.checkCredential(query.Get("X-Amz-Credential"), t); err != "" {
+		return err
+	}
+
+	signature := query.Get("X-Amz-Signature")
+	unsigned := url.Values{}
+	for name, values := range query {
+		if name != "X-Amz-Signature" {
+			unsigned[name] = values
+		}
+	}
+
+	expected := s.sign(r, unsigned, query.Get("X-Amz-SignedHeaders"), "UNSIGNED-PAYLOAD", t)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "signature mismatch"
+	}
+	return ""
+}
+
+// checkCredential 校验凭证中的access key和签名范围
+func (s *mockS3) checkCredential(credential string, t time.Time) string {
+	expected := fmt.Sprintf("%s/%s/%s/s3/aws4_request", s.accessKey, t.Format("20060102"), s.region)
+	if credential != expected {
+		return fmt.Sprintf("unexpected credential %q", credential)
+	}
+	return ""
+}
+
+// sign 按SigV4重新计算签名
+func (s *mockS3) sign(r *http.Request, query url.Values, signedHeaders, payloadHash string, t time.Time) string {
+	var canonicalHeaders strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	// 使用客户端发送的原始路径，避免重新编码造成差异
+	rawPath, _, _ := strings.Cut(r.RequestURI, "?")
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		rawPath,
+		canonicalQuery(query),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", t.Format("20060102"), s.region)
+	stringToSign := strings.Join([]string{algorithm, t.Format(timeFormat), scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQuery 按参数名排序的规范查询字符串
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, escape(key)+"="+escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escape RFC 3986编码（空格编码为%20）
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Error 返回S3风格的XML错误
+func s3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}
@@ -5,6 +5,8 @@ import (
 	"gochat/internal/config"
 	"gochat/internal/database"
 	"gochat/internal/router"
+	"gochat/internal/services"
+	"gochat/internal/storage"
 	ws "gochat/internal/websocket"
 	"gochat/pkg/logger"
 	"log"
@@ -33,11 +35,35 @@ func main() {
 	}
 	logger.Info("Database migration completed")
 
+	// 初始化附件存储
+	if err := storage.Init(&cfg.Storage); err != nil {
+		logger.Fatal("Failed to initialize attachment storage:", err)
+	}
+	logger.Info("Attachment storage initialized:", cfg.Storage.Driver)
+
+	// 初始化广播总线
+	broker, err := ws.NewBroker(&cfg.WebSocket, &cfg.Redis)
+	if err != nil {
+		logger.Fatal("Failed to create websocket broker:", err)
+	}
+	logger.Info("WebSocket broker initialized:", cfg.WebSocket.Broker)
+
 	// 初始化WebSocket Hub
-	wsHub := ws.NewHub()
+	wsHub, err := ws.NewHub(broker)
+	if err != nil {
+		logger.Fatal("Failed to create WebSocket Hub:", err)
+	}
 	go wsHub.Run()
 	logger.Info("WebSocket Hub started")
 
+	// 启动Webhook投递器
+	services.StartWebhookDispatcher(&cfg.Webhook)
+	logger.Info("Webhook dispatcher started")
+
+	// 启动过期令牌清理任务
+	services.StartTokenCleanup(&cfg.JWT)
+	logger.Info("Token cleanup started")
+
 	// 启动服务器
 	serverAddr := fmt.Sprintf(":%s", cfg.Server.Port)
 	logger.Info("Server starting on", serverAddr)
@@ -0,0 +1,107 @@
+// webhookrecv 本地Webhook接收端，用于在开发和测试环境中验证聊天室Webhook投递
+//
+// 校验 X-GoChat-Signature 签名并打印收到的事件；-fail N 使前N次请求返回500，用于观察重试和死信。
+// 启动：go run ./cmd/webhookrecv -addr :9500 -secret <创建Webhook时返回的secret>
+// 服务端需开启 webhook.allow_private_networks 才能投递到本机地址
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// maxSkew 允许的签名时间戳偏差，超出视为重放
+const maxSkew = 5 * time.Minute
+
+type receiver struct {
+	secret    string
+	failFirst int64
+	received  int64
+}
+
+func main() {
+	addr := flag.String("addr", ":9500", "listen address")
+	secret := flag.String("secret", "", "webhook secret used to verify signatures (empty to skip verification)")
+	fail := flag.Int64("fail", 0, "respond 500 to the first N requests")
+	flag.Parse()
+
+	r := &receiver{
+		secret:    *secret,
+		failFirst: *fail,
+	}
+
+	http.HandleFunc("/", r.handle)
+	log.Printf("webhook receiver listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func (r *receiver) handle(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "read body failed", http.StatusBadRequest)
+		return
+	}
+
+	n := atomic.AddInt64(&r.received, 1)
+	event := req.Header.Get("X-GoChat-Event")
+	deliveryID := req.Header.Get("X-GoChat-Delivery")
+
+	if r.secret != "" {
+		if err := r.verify(req.Header.Get("X-GoChat-Timestamp"), req.Header.Get("X-GoChat-Signature"), body); err != "" {
+			log.Printf("#%d delivery=%s event=%s rejected: %s", n, deliveryID, event, err)
+			http.Error(w, err, http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, body, "", "  ") != nil {
+		pretty.Write(body)
+	}
+
+	if n <= r.failFirst {
+		log.Printf("#%d delivery=%s event=%s -> 500 (simulated failure)", n, deliveryID, event)
+		http.Error(w, "simulated failure", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("#%d delivery=%s event=%s\n%s", n, deliveryID, event, pretty.String())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verify 校验签名，返回空字符串表示通过
+func (r *receiver) verify(timestamp, signature string, body []byte) string {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "missing or invalid timestamp"
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > maxSkew || skew < -maxSkew {
+		return "timestamp outside allowed window"
+	}
+
+	mac := hmac.New(sha256.New, []byte(r.secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "signature mismatch"
+	}
+	return ""
+}
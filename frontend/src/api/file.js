@@ -39,7 +39,8 @@ export const getPreviewUrl = (fileId) => {
   return `/api/files/${fileId}/preview?token=${token}`
 }
 
-// 获取静态文件URL
+// 获取静态文件URL（带认证token）
 export const getStaticUrl = (filePath) => {
-  return `/uploads/${filePath}`
+  const token = localStorage.getItem('token')
+  return `/uploads/${filePath}?token=${token}`
 } 
\ No newline at end of file
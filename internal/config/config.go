@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strconv"
@@ -16,6 +17,12 @@ type Config struct {
 	Database  DatabaseConfig  `yaml:"database"`
 	Redis     RedisConfig     `yaml:"redis"`
 	JWT       JWTConfig       `yaml:"jwt"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Mail      MailConfig      `yaml:"mail"`
+	OIDC      OIDCConfig      `yaml:"oidc"`
+	Webhook   WebhookConfig   `yaml:"webhook"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Upload    UploadConfig    `yaml:"upload"`
 	WebSocket WebSocketConfig `yaml:"websocket"`
 	Log       LogConfig       `yaml:"log"`
 }
@@ -39,17 +46,85 @@ type RedisConfig struct {
 	Port     int    `yaml:"port"`
 	Password string `yaml:"password"`
 	DB       int    `yaml:"db"`
+	Channel  string `yaml:"channel"` // 跨节点广播使用的发布订阅频道
 }
 
 type JWTConfig struct {
-	Secret      string        `yaml:"secret"`
-	ExpireHours time.Duration `yaml:"expire_hours"`
+	Secret             string        `yaml:"secret"`
+	ExpireHours        time.Duration `yaml:"expire_hours"`
+	RefreshExpireHours time.Duration `yaml:"refresh_expire_hours"` // 刷新令牌有效期
+}
+
+type AuthConfig struct {
+	MaxLoginAttempts   int    `yaml:"max_login_attempts"`   // 连续登录失败多少次后开始锁定
+	LockoutMinutes     int    `yaml:"lockout_minutes"`      // 首次锁定时长（分钟），之后每次失败翻倍
+	MaxLockoutMinutes  int    `yaml:"max_lockout_minutes"`  // 最长锁定时长（分钟）
+	ResetExpireMinutes int    `yaml:"reset_expire_minutes"` // 重置密码令牌有效期（分钟）
+	ResetURL           string `yaml:"reset_url"`            // 重置密码页面地址，令牌作为token查询参数附加
+}
+
+type MailConfig struct {
+	Driver   string `yaml:"driver"` // 邮件发送方式：log（输出到日志）, file（写入文件）, smtp
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	FilePath string `yaml:"file_path"` // driver为file时邮件写入的文件
+}
+
+type OIDCConfig struct {
+	SuccessURL string                        `yaml:"success_url"` // 登录成功后重定向的前端地址，令牌附加在URL片段中；为空时回调直接返回JSON
+	Providers  map[string]OIDCProviderConfig `yaml:"providers"`   // 身份提供方，键为路由中的provider名称
+}
+
+type OIDCProviderConfig struct {
+	Issuer       string   `yaml:"issuer"` // 签发方地址，通过 /.well-known/openid-configuration 发现端点
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"` // 公共客户端可留空，仅使用PKCE
+	RedirectURL  string   `yaml:"redirect_url"`  // 在身份提供方登记的回调地址
+	Scopes       []string `yaml:"scopes"`        // 默认 openid profile email
+	TrustEmail   bool     `yaml:"trust_email"`   // 是否将已验证邮箱关联到同邮箱的本地账号
+}
+
+type WebhookConfig struct {
+	MaxAttempts          int  `yaml:"max_attempts"`           // 最多投递次数，全部失败后记为死信
+	InitialBackoff       int  `yaml:"initial_backoff"`        // 首次重试间隔（秒），之后每次翻倍
+	MaxBackoff           int  `yaml:"max_backoff"`            // 最长重试间隔（秒）
+	TimeoutSeconds       int  `yaml:"timeout_seconds"`        // 单次投递超时（秒）
+	Workers              int  `yaml:"workers"`                // 并发投递数
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"` // 是否允许投递到内网和本机地址（本地调试时开启）
+}
+
+type StorageConfig struct {
+	Driver   string   `yaml:"driver"`    // 附件存储：local（本地磁盘）, s3（S3兼容对象存储）, memory（内存，仅用于测试）
+	LocalDir string   `yaml:"local_dir"` // driver为local时的存储目录
+	S3       S3Config `yaml:"s3"`
+}
+
+type S3Config struct {
+	Endpoint      string `yaml:"endpoint"` // 服务地址，如 https://s3.amazonaws.com 或 http://localhost:9000
+	Region        string `yaml:"region"`
+	Bucket        string `yaml:"bucket"`
+	AccessKey     string `yaml:"access_key"`
+	SecretKey     string `yaml:"secret_key"`
+	PathStyle     bool   `yaml:"path_style"`     // 使用 endpoint/bucket/key 形式的地址（MinIO等需要开启）
+	PresignExpiry int    `yaml:"presign_expiry"` // 预签名下载地址有效期（秒）
+}
+
+type UploadConfig struct {
+	MaxImageSize int `yaml:"max_image_size"` // 图片大小上限（MB）
+	MaxVideoSize int `yaml:"max_video_size"` // 视频大小上限（MB）
+	MaxFileSize  int `yaml:"max_file_size"`  // 其他文件大小上限（MB）
+	ChunkSize    int `yaml:"chunk_size"`     // 分片上传的分片大小（KB），不能超过服务器4MB的请求体上限
+	SessionTTL   int `yaml:"session_ttl"`    // 分片上传会话有效期（小时），过期未完成的分片会被清理
 }
 
 type WebSocketConfig struct {
-	ReadBufferSize  int  `yaml:"read_buffer_size"`
-	WriteBufferSize int  `yaml:"write_buffer_size"`
-	CheckOrigin     bool `yaml:"check_origin"`
+	ReadBufferSize  int    `yaml:"read_buffer_size"`
+	WriteBufferSize int    `yaml:"write_buffer_size"`
+	CheckOrigin     bool   `yaml:"check_origin"`
+	Broker          string `yaml:"broker"` // 广播总线：memory（单节点）, redis（多节点）
 }
 
 type LogConfig struct {
@@ -57,6 +132,13 @@ type LogConfig struct {
 	Format string `yaml:"format"`
 }
 
+// 分片上传的分片大小范围（KB），上限受服务器4MB的请求体大小限制
+const (
+	minUploadChunkSize = 1
+	maxUploadChunkSize = 4096
+)
+
+// Load 加载配置
 func Load() (*Config, error) {
 	// 加载 .env 文件（如果存在）
 	_ = godotenv.Load()
@@ -132,6 +214,220 @@ func Load() (*Config, error) {
 		}
 	}
 
+	if hours := getEnvAsInt("JWT_REFRESH_EXPIRE_HOURS", 0); hours != 0 {
+		cfg.JWT.RefreshExpireHours = time.Duration(hours) * time.Hour
+	} else if cfg.JWT.RefreshExpireHours != 0 {
+		// YAML中配置的是小时数
+		cfg.JWT.RefreshExpireHours = time.Duration(cfg.JWT.RefreshExpireHours) * time.Hour
+	} else {
+		cfg.JWT.RefreshExpireHours = 7 * 24 * time.Hour
+	}
+
+	// 认证配置
+	if attempts := getEnvAsInt("AUTH_MAX_LOGIN_ATTEMPTS", 0); attempts != 0 {
+		cfg.Auth.MaxLoginAttempts = attempts
+	} else if cfg.Auth.MaxLoginAttempts == 0 {
+		cfg.Auth.MaxLoginAttempts = 5
+	}
+
+	if minutes := getEnvAsInt("AUTH_LOCKOUT_MINUTES", 0); minutes != 0 {
+		cfg.Auth.LockoutMinutes = minutes
+	} else if cfg.Auth.LockoutMinutes == 0 {
+		cfg.Auth.LockoutMinutes = 1
+	}
+
+	if minutes := getEnvAsInt("AUTH_MAX_LOCKOUT_MINUTES", 0); minutes != 0 {
+		cfg.Auth.MaxLockoutMinutes = minutes
+	} else if cfg.Auth.MaxLockoutMinutes == 0 {
+		cfg.Auth.MaxLockoutMinutes = 60
+	}
+
+	if minutes := getEnvAsInt("AUTH_RESET_EXPIRE_MINUTES", 0); minutes != 0 {
+		cfg.Auth.ResetExpireMinutes = minutes
+	} else if cfg.Auth.ResetExpireMinutes == 0 {
+		cfg.Auth.ResetExpireMinutes = 30
+	}
+
+	if resetURL := getEnv("AUTH_RESET_URL", ""); resetURL != "" {
+		cfg.Auth.ResetURL = resetURL
+	} else if cfg.Auth.ResetURL == "" {
+		cfg.Auth.ResetURL = "http://localhost:3000/reset-password"
+	}
+
+	// 邮件配置
+	if driver := getEnv("MAIL_DRIVER", ""); driver != "" {
+		cfg.Mail.Driver = driver
+	} else if cfg.Mail.Driver == "" {
+		cfg.Mail.Driver = "log"
+	}
+
+	if host := getEnv("MAIL_HOST", ""); host != "" {
+		cfg.Mail.Host = host
+	}
+
+	if port := getEnvAsInt("MAIL_PORT", 0); port != 0 {
+		cfg.Mail.Port = port
+	} else if cfg.Mail.Port == 0 {
+		cfg.Mail.Port = 587
+	}
+
+	if username := getEnv("MAIL_USERNAME", ""); username != "" {
+		cfg.Mail.Username = username
+	}
+
+	if password := getEnv("MAIL_PASSWORD", ""); password != "" {
+		cfg.Mail.Password = password
+	}
+
+	if from := getEnv("MAIL_FROM", ""); from != "" {
+		cfg.Mail.From = from
+	} else if cfg.Mail.From == "" {
+		cfg.Mail.From = "GoChat <noreply@gochat.local>"
+	}
+
+	if filePath := getEnv("MAIL_FILE_PATH", ""); filePath != "" {
+		cfg.Mail.FilePath = filePath
+	} else if cfg.Mail.FilePath == "" {
+		cfg.Mail.FilePath = "logs/mail.log"
+	}
+
+	// Webhook 配置
+	if attempts := getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 0); attempts != 0 {
+		cfg.Webhook.MaxAttempts = attempts
+	} else if cfg.Webhook.MaxAttempts == 0 {
+		cfg.Webhook.MaxAttempts = 6
+	}
+
+	if backoff := getEnvAsInt("WEBHOOK_INITIAL_BACKOFF", 0); backoff != 0 {
+		cfg.Webhook.InitialBackoff = backoff
+	} else if cfg.Webhook.InitialBackoff == 0 {
+		cfg.Webhook.InitialBackoff = 10
+	}
+
+	if backoff := getEnvAsInt("WEBHOOK_MAX_BACKOFF", 0); backoff != 0 {
+		cfg.Webhook.MaxBackoff = backoff
+	} else if cfg.Webhook.MaxBackoff == 0 {
+		cfg.Webhook.MaxBackoff = 3600
+	}
+
+	if timeout := getEnvAsInt("WEBHOOK_TIMEOUT_SECONDS", 0); timeout != 0 {
+		cfg.Webhook.TimeoutSeconds = timeout
+	} else if cfg.Webhook.TimeoutSeconds == 0 {
+		cfg.Webhook.TimeoutSeconds = 10
+	}
+
+	if workers := getEnvAsInt("WEBHOOK_WORKERS", 0); workers != 0 {
+		cfg.Webhook.Workers = workers
+	} else if cfg.Webhook.Workers == 0 {
+		cfg.Webhook.Workers = 4
+	}
+
+	cfg.Webhook.AllowPrivateNetworks = getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", cfg.Webhook.AllowPrivateNetworks)
+
+	// 附件存储配置
+	if driver := getEnv("STORAGE_DRIVER", ""); driver != "" {
+		cfg.Storage.Driver = driver
+	} else if cfg.Storage.Driver == "" {
+		cfg.Storage.Driver = "local"
+	}
+
+	if dir := getEnv("STORAGE_LOCAL_DIR", ""); dir != "" {
+		cfg.Storage.LocalDir = dir
+	} else if cfg.Storage.LocalDir == "" {
+		cfg.Storage.LocalDir = "./uploads"
+	}
+
+	if endpoint := getEnv("S3_ENDPOINT", ""); endpoint != "" {
+		cfg.Storage.S3.Endpoint = endpoint
+	}
+
+	if region := getEnv("S3_REGION", ""); region != "" {
+		cfg.Storage.S3.Region = region
+	} else if cfg.Storage.S3.Region == "" {
+		cfg.Storage.S3.Region = "us-east-1"
+	}
+
+	if bucket := getEnv("S3_BUCKET", ""); bucket != "" {
+		cfg.Storage.S3.Bucket = bucket
+	}
+
+	if accessKey := getEnv("S3_ACCESS_KEY", ""); accessKey != "" {
+		cfg.Storage.S3.AccessKey = accessKey
+	}
+
+	if secretKey := getEnv("S3_SECRET_KEY", ""); secretKey != "" {
+		cfg.Storage.S3.SecretKey = secretKey
+	}
+
+	cfg.Storage.S3.PathStyle = getEnvAsBool("S3_PATH_STYLE", cfg.Storage.S3.PathStyle)
+
+	if expiry := getEnvAsInt("S3_PRESIGN_EXPIRY", 0); expiry != 0 {
+		cfg.Storage.S3.PresignExpiry = expiry
+	} else if cfg.Storage.S3.PresignExpiry == 0 {
+		cfg.Storage.S3.PresignExpiry = 300
+	}
+
+	// 上传配置
+	if size := getEnvAsInt("UPLOAD_MAX_IMAGE_SIZE", 0); size != 0 {
+		cfg.Upload.MaxImageSize = size
+	} else if cfg.Upload.MaxImageSize == 0 {
+		cfg.Upload.MaxImageSize = 50
+	}
+
+	if size := getEnvAsInt("UPLOAD_MAX_VIDEO_SIZE", 0); size != 0 {
+		cfg.Upload.MaxVideoSize = size
+	} else if cfg.Upload.MaxVideoSize == 0 {
+		cfg.Upload.MaxVideoSize = 1024
+	}
+
+	if size := getEnvAsInt("UPLOAD_MAX_FILE_SIZE", 0); size != 0 {
+		cfg.Upload.MaxFileSize = size
+	} else if cfg.Upload.MaxFileSize == 0 {
+		cfg.Upload.MaxFileSize = 512
+	}
+
+	if size := getEnvAsInt("UPLOAD_CHUNK_SIZE", 0); size != 0 {
+		cfg.Upload.ChunkSize = size
+	} else if cfg.Upload.ChunkSize == 0 {
+		cfg.Upload.ChunkSize = 2048
+	}
+	if cfg.Upload.ChunkSize < minUploadChunkSize || cfg.Upload.ChunkSize > maxUploadChunkSize {
+		return nil, fmt.Errorf("upload.chunk_size must be between %d and %d KB, got %d", minUploadChunkSize, maxUploadChunkSize, cfg.Upload.ChunkSize)
+	}
+
+	if ttl := getEnvAsInt("UPLOAD_SESSION_TTL", 0); ttl != 0 {
+		cfg.Upload.SessionTTL = ttl
+	} else if cfg.Upload.SessionTTL == 0 {
+		cfg.Upload.SessionTTL = 24
+	}
+
+	// Redis 配置
+	if host := getEnv("REDIS_HOST", ""); host != "" {
+		cfg.Redis.Host = host
+	} else if cfg.Redis.Host == "" {
+		cfg.Redis.Host = "localhost"
+	}
+
+	if port := getEnvAsInt("REDIS_PORT", 0); port != 0 {
+		cfg.Redis.Port = port
+	} else if cfg.Redis.Port == 0 {
+		cfg.Redis.Port = 6379
+	}
+
+	if password := getEnv("REDIS_PASSWORD", ""); password != "" {
+		cfg.Redis.Password = password
+	}
+
+	if db := getEnvAsInt("REDIS_DB", -1); db >= 0 {
+		cfg.Redis.DB = db
+	}
+
+	if channel := getEnv("REDIS_CHANNEL", ""); channel != "" {
+		cfg.Redis.Channel = channel
+	} else if cfg.Redis.Channel == "" {
+		cfg.Redis.Channel = "gochat:broadcast"
+	}
+
 	// 日志配置
 	if level := getEnv("LOG_LEVEL", ""); level != "" {
 		cfg.Log.Level = level
@@ -153,6 +449,12 @@ func Load() (*Config, error) {
 		cfg.WebSocket.WriteBufferSize = 1024
 	}
 
+	if broker := getEnv("WS_BROKER", ""); broker != "" {
+		cfg.WebSocket.Broker = broker
+	} else if cfg.WebSocket.Broker == "" {
+		cfg.WebSocket.Broker = "memory"
+	}
+
 	return cfg, nil
 }
 
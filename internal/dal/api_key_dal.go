@@ -0,0 +1,103 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyDAL API Key数据访问层
+type APIKeyDAL struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyDAL 创建API Key DAL实例
+func NewAPIKeyDAL() *APIKeyDAL {
+	return &APIKeyDAL{
+		db: database.DB,
+	}
+}
+
+// CreateWithRooms 创建API Key并记录允许访问的聊天室
+func (d *APIKeyDAL) CreateWithRooms(key *entities.APIKey, roomIDs []uint) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		for _, roomID := range roomIDs {
+			if err := tx.Create(&entities.APIKeyRoom{APIKeyID: key.ID, RoomID: roomID}).Error; err != nil {
+				return err
+			}
+		}
+		key.RoomIDs = roomIDs
+		return nil
+	})
+}
+
+// GetByHash 根据摘要获取API Key，同时加载允许访问的聊天室
+func (d *APIKeyDAL) GetByHash(keyHash string) (*entities.APIKey, error) {
+	var key entities.APIKey
+	if err := d.db.Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	if err := d.loadRooms([]*entities.APIKey{&key}); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetByUser 获取机器人的所有API Key，最新创建的在前
+func (d *APIKeyDAL) GetByUser(userID uint) ([]*entities.APIKey, error) {
+	var keys []*entities.APIKey
+	err := d.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := d.loadRooms(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke 撤销机器人的指定API Key，返回是否有记录被撤销
+func (d *APIKeyDAL) Revoke(userID, keyID uint) (bool, error) {
+	result := d.db.Model(&entities.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// Touch 更新API Key最后使用时间
+func (d *APIKeyDAL) Touch(keyID uint) error {
+	return d.db.Model(&entities.APIKey{}).
+		Where("id = ?", keyID).
+		Update("last_used_at", time.Now()).Error
+}
+
+// loadRooms 批量加载API Key允许访问的聊天室ID
+func (d *APIKeyDAL) loadRooms(keys []*entities.APIKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	keyIDs := make([]uint, 0, len(keys))
+	byID := make(map[uint]*entities.APIKey, len(keys))
+	for _, key := range keys {
+		keyIDs = append(keyIDs, key.ID)
+		byID[key.ID] = key
+		key.RoomIDs = []uint{}
+	}
+
+	var links []entities.APIKeyRoom
+	if err := d.db.Where("api_key_id IN ?", keyIDs).Order("room_id").Find(&links).Error; err != nil {
+		return err
+	}
+	for _, link := range links {
+		byID[link.APIKeyID].RoomIDs = append(byID[link.APIKeyID].RoomIDs, link.RoomID)
+	}
+	return nil
+}
@@ -36,6 +36,15 @@ func (d *AttachmentDAL) GetByID(attachmentID uint) (*entities.Attachment, error)
 	return &attachment, nil
 }
 
+// GetByFilePath 根据存储路径获取附件
+func (d *AttachmentDAL) GetByFilePath(filePath string) (*entities.Attachment, error) {
+	var attachment entities.Attachment
+	if err := d.db.Where("file_path = ?", filePath).First(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
 // GetByMessageID 根据消息ID获取附件列表
 func (d *AttachmentDAL) GetByMessageID(messageID uint) ([]*entities.Attachment, error) {
 	var attachments []*entities.Attachment
@@ -55,7 +64,14 @@ func (d *AttachmentDAL) DeleteByMessageID(messageID uint) error {
 	return d.db.Where("message_id = ?", messageID).Delete(&entities.Attachment{}).Error
 }
 
-// UpdateMessageID 更新附件的消息ID
-func (d *AttachmentDAL) UpdateMessageID(attachmentID uint, messageID *uint) error {
-	return d.db.Model(&entities.Attachment{}).Where("id = ?", attachmentID).Update("message_id", messageID).Error
+// AttachToMessage 将上传者的临时附件关联到消息和聊天室
+// 只有仍未关联消息的附件会被更新，返回是否关联成功，避免认领他人或已使用的附件
+func (d *AttachmentDAL) AttachToMessage(attachmentID, uploaderID, messageID, roomID uint) (bool, error) {
+	result := d.db.Model(&entities.Attachment{}).
+		Where("id = ? AND uploader_id = ? AND message_id IS NULL", attachmentID, uploaderID).
+		Updates(map[string]interface{}{
+			"message_id": messageID,
+			"room_id":    roomID,
+		})
+	return result.RowsAffected > 0, result.Error
 }
@@ -0,0 +1,95 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IdentityDAL 外部身份数据访问层（关联身份与OIDC授权状态）
+type IdentityDAL struct {
+	db *gorm.DB
+}
+
+// NewIdentityDAL 创建外部身份DAL实例
+func NewIdentityDAL() *IdentityDAL {
+	return &IdentityDAL{
+		db: database.DB,
+	}
+}
+
+// GetIdentity 根据身份提供方和sub获取关联身份
+func (d *IdentityDAL) GetIdentity(provider, subject string) (*entities.UserIdentity, error) {
+	var identity entities.UserIdentity
+	err := d.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetIdentitiesByUser 获取用户关联的所有外部身份
+func (d *IdentityDAL) GetIdentitiesByUser(userID uint) ([]*entities.UserIdentity, error) {
+	var identities []*entities.UserIdentity
+	err := d.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+// CreateIdentity 关联外部身份
+func (d *IdentityDAL) CreateIdentity(identity *entities.UserIdentity) error {
+	return d.db.Create(identity).Error
+}
+
+// CreateUserWithIdentity 在同一事务中创建用户并关联外部身份（首次登录自动开通账号）
+func (d *IdentityDAL) CreateUserWithIdentity(user *entities.User, identity *entities.UserIdentity) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+// TouchIdentity 更新外部身份的最近登录时间和邮箱
+func (d *IdentityDAL) TouchIdentity(id uint, email string) error {
+	return d.db.Model(&entities.UserIdentity{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"email":         email,
+			"last_login_at": time.Now(),
+		}).Error
+}
+
+// CreateState 保存OIDC授权状态
+func (d *IdentityDAL) CreateState(state *entities.OIDCState) error {
+	return d.db.Create(state).Error
+}
+
+// ConsumeState 取出并删除OIDC授权状态，保证每个state只能使用一次
+func (d *IdentityDAL) ConsumeState(stateHash string) (*entities.OIDCState, error) {
+	var state entities.OIDCState
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state_hash = ?", stateHash).First(&state).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&entities.OIDCState{}, state.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// DeleteExpiredStates 清理已过期的OIDC授权状态
+func (d *IdentityDAL) DeleteExpiredStates() error {
+	return d.db.Where("expires_at < ?", time.Now()).Delete(&entities.OIDCState{}).Error
+}
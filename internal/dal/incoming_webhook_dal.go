@@ -0,0 +1,81 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IncomingWebhookDAL 传入Webhook数据访问层
+type IncomingWebhookDAL struct {
+	db *gorm.DB
+}
+
+// NewIncomingWebhookDAL 创建传入Webhook DAL实例
+func NewIncomingWebhookDAL() *IncomingWebhookDAL {
+	return &IncomingWebhookDAL{
+		db: database.DB,
+	}
+}
+
+// Create 创建传入Webhook
+func (d *IncomingWebhookDAL) Create(hook *entities.IncomingWebhook) error {
+	return d.db.Create(hook).Error
+}
+
+// CreateWithBot 在同一事务中创建集成机器人、将其加入聊天室并创建传入Webhook
+func (d *IncomingWebhookDAL) CreateWithBot(bot *entities.User, hook *entities.IncomingWebhook) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(bot).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&entities.RoomMember{
+			RoomID: hook.RoomID,
+			UserID: bot.ID,
+			Role:   entities.RoomRoleMember,
+		}).Error; err != nil {
+			return err
+		}
+		hook.UserID = bot.ID
+		return tx.Create(hook).Error
+	})
+}
+
+// GetByID 根据ID获取传入Webhook
+func (d *IncomingWebhookDAL) GetByID(id uint) (*entities.IncomingWebhook, error) {
+	var hook entities.IncomingWebhook
+	if err := d.db.First(&hook, id).Error; err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// GetByTokenHash 根据令牌摘要获取传入Webhook
+func (d *IncomingWebhookDAL) GetByTokenHash(tokenHash string) (*entities.IncomingWebhook, error) {
+	var hook entities.IncomingWebhook
+	if err := d.db.Where("token_hash = ?", tokenHash).First(&hook).Error; err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// GetByRoom 获取聊天室的所有传入Webhook
+func (d *IncomingWebhookDAL) GetByRoom(roomID uint) ([]*entities.IncomingWebhook, error) {
+	var hooks []*entities.IncomingWebhook
+	err := d.db.Where("room_id = ?", roomID).Order("id ASC").Find(&hooks).Error
+	return hooks, err
+}
+
+// Delete 删除传入Webhook，URL立即失效
+func (d *IncomingWebhookDAL) Delete(id uint) error {
+	return d.db.Delete(&entities.IncomingWebhook{}, id).Error
+}
+
+// Touch 更新最后使用时间
+func (d *IncomingWebhookDAL) Touch(id uint) error {
+	return d.db.Model(&entities.IncomingWebhook{}).
+		Where("id = ?", id).
+		Update("last_used_at", time.Now()).Error
+}
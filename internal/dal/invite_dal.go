@@ -0,0 +1,135 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RoomInviteDAL 聊天室邀请数据访问层
+type RoomInviteDAL struct {
+	db *gorm.DB
+}
+
+// NewRoomInviteDAL 创建聊天室邀请DAL实例
+func NewRoomInviteDAL() *RoomInviteDAL {
+	return &RoomInviteDAL{
+		db: database.DB,
+	}
+}
+
+// Create 创建邀请
+func (d *RoomInviteDAL) Create(invite *entities.RoomInvite) error {
+	return d.db.Create(invite).Error
+}
+
+// GetByToken 根据令牌获取邀请
+func (d *RoomInviteDAL) GetByToken(token string) (*entities.RoomInvite, error) {
+	var invite entities.RoomInvite
+	err := d.db.Where("token = ?", token).Preload("Room").First(&invite).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// GetActiveByRoom 获取聊天室中仍可使用的邀请
+func (d *RoomInviteDAL) GetActiveByRoom(roomID uint) ([]*entities.RoomInvite, error) {
+	var invites []*entities.RoomInvite
+	err := d.db.Where("room_id = ? AND revoked_at IS NULL", roomID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Where("max_uses = 0 OR use_count < max_uses").
+		Order("created_at DESC").
+		Find(&invites).Error
+	return invites, err
+}
+
+// Revoke 撤销邀请，返回是否撤销了记录
+func (d *RoomInviteDAL) Revoke(roomID, inviteID uint) (bool, error) {
+	result := d.db.Model(&entities.RoomInvite{}).
+		Where("id = ? AND room_id = ? AND revoked_at IS NULL", inviteID, roomID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Consume 使用一次邀请，邀请已失效时返回false
+// 条件更新保证并发使用时不会超过最大使用次数
+func (d *RoomInviteDAL) Consume(inviteID uint) (bool, error) {
+	result := d.db.Model(&entities.RoomInvite{}).
+		Where("id = ? AND revoked_at IS NULL", inviteID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Where("max_uses = 0 OR use_count < max_uses").
+		Update("use_count", gorm.Expr("use_count + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RoomJoinRequestDAL 聊天室加入申请数据访问层
+type RoomJoinRequestDAL struct {
+	db *gorm.DB
+}
+
+// NewRoomJoinRequestDAL 创建聊天室加入申请DAL实例
+func NewRoomJoinRequestDAL() *RoomJoinRequestDAL {
+	return &RoomJoinRequestDAL{
+		db: database.DB,
+	}
+}
+
+// Create 创建加入申请
+func (d *RoomJoinRequestDAL) Create(request *entities.RoomJoinRequest) error {
+	return d.db.Create(request).Error
+}
+
+// GetByID 根据ID获取加入申请
+func (d *RoomJoinRequestDAL) GetByID(requestID uint) (*entities.RoomJoinRequest, error) {
+	var request entities.RoomJoinRequest
+	err := d.db.Preload("User").First(&request, requestID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetPending 获取用户在聊天室中待审批的申请
+func (d *RoomJoinRequestDAL) GetPending(roomID, userID uint) (*entities.RoomJoinRequest, error) {
+	var request entities.RoomJoinRequest
+	err := d.db.Where("room_id = ? AND user_id = ? AND status = ?", roomID, userID, entities.JoinRequestPending).
+		First(&request).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetPendingByRoom 获取聊天室中所有待审批的申请
+func (d *RoomJoinRequestDAL) GetPendingByRoom(roomID uint) ([]*entities.RoomJoinRequest, error) {
+	var requests []*entities.RoomJoinRequest
+	err := d.db.Where("room_id = ? AND status = ?", roomID, entities.JoinRequestPending).
+		Preload("User").
+		Order("created_at ASC").
+		Find(&requests).Error
+	return requests, err
+}
+
+// Review 审批申请，仅待审批的申请会被更新，返回是否更新了记录
+func (d *RoomJoinRequestDAL) Review(requestID uint, status string, reviewerID uint) (bool, error) {
+	result := d.db.Model(&entities.RoomJoinRequest{}).
+		Where("id = ? AND status = ?", requestID, entities.JoinRequestPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": reviewerID,
+			"reviewed_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
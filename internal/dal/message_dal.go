@@ -3,6 +3,7 @@ package dal
 import (
 	"gochat/internal/database"
 	"gochat/internal/models/entities"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -36,6 +37,18 @@ func (d *MessageDAL) GetByID(messageID uint) (*entities.Message, error) {
 	return &message, nil
 }
 
+// GetByClientMsgID 根据用户和客户端消息ID获取消息（包括已删除的，避免重试时重复创建）
+func (d *MessageDAL) GetByClientMsgID(userID uint, clientMsgID string) (*entities.Message, error) {
+	var message entities.Message
+	err := d.db.Unscoped().
+		Where("user_id = ? AND client_msg_id = ?", userID, clientMsgID).
+		First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
 // GetByRoomID 根据聊天室ID获取消息列表
 func (d *MessageDAL) GetByRoomID(roomID uint, limit, offset int) ([]*entities.Message, error) {
 	var messages []*entities.Message
@@ -54,40 +67,145 @@ func (d *MessageDAL) GetByRoomID(roomID uint, limit, offset int) ([]*entities.Me
 	if err := query.Find(&messages).Error; err != nil {
 		return nil, err
 	}
+	if err := d.loadReactions(messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetAfterID 获取聊天室中指定消息ID之后的消息（按ID升序，用于断线重连补发）
+func (d *MessageDAL) GetAfterID(roomID, afterID uint, limit int) ([]*entities.Message, error) {
+	var messages []*entities.Message
+	query := d.db.Where("room_id = ? AND id > ?", roomID, afterID).
+		Preload("User").
+		Preload("Attachments").
+		Order("id ASC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	if err := d.loadReactions(messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetThreadReplies 获取话题的回复列表（按时间升序）
+func (d *MessageDAL) GetThreadReplies(rootID uint, limit, offset int) ([]*entities.Message, error) {
+	var messages []*entities.Message
+	query := d.db.Where("thread_root_id = ?", rootID).
+		Preload("User").
+		Preload("Attachments").
+		Order("id ASC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	if err := d.loadReactions(messages); err != nil {
+		return nil, err
+	}
 	return messages, nil
 }
 
-// Update 更新消息内容
+// IncrementReplyCount 增加话题回复数并更新最后回复时间
+func (d *MessageDAL) IncrementReplyCount(rootID uint, repliedAt time.Time) error {
+	return d.db.Model(&entities.Message{}).Where("id = ?", rootID).
+		Updates(map[string]interface{}{
+			"reply_count":   gorm.Expr("reply_count + 1"),
+			"last_reply_at": repliedAt,
+		}).Error
+}
+
+// DecrementReplyCount 减少话题回复数
+func (d *MessageDAL) DecrementReplyCount(rootID uint) error {
+	return d.db.Model(&entities.Message{}).Where("id = ? AND reply_count > 0", rootID).
+		Update("reply_count", gorm.Expr("reply_count - 1")).Error
+}
+
+// Update 更新消息内容，并记录编辑时间
 func (d *MessageDAL) Update(messageID uint, content string) (*entities.Message, error) {
 	var message entities.Message
 	if err := d.db.First(&message, messageID).Error; err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
 	message.Content = content
-	if err := d.db.Save(&message).Error; err != nil {
+	message.EditedAt = &now
+	// 只更新内容相关字段，避免覆盖并发更新的话题回复数
+	if err := d.db.Model(&message).Updates(map[string]interface{}{
+		"content":   message.Content,
+		"edited_at": message.EditedAt,
+	}).Error; err != nil {
 		return nil, err
 	}
 
 	return &message, nil
 }
 
-// Delete 删除消息
+// Delete 删除消息（软删除，保留deleted_at作为墓碑记录）
 func (d *MessageDAL) Delete(messageID uint) error {
 	return d.db.Delete(&entities.Message{}, messageID).Error
 }
 
-// GetUnreadCount 获取用户在指定聊天室的未读消息数量
+// GetUnreadCount 获取用户在指定聊天室的未读消息数量（已读位置之后他人发送的消息）
 func (d *MessageDAL) GetUnreadCount(userID, roomID uint) (int64, error) {
 	var count int64
-	// 这里简化处理，实际应该有读取状态表
-	// 当前仅返回该用户在该聊天室的所有消息数量
 	err := d.db.Model(&entities.Message{}).
 		Where("room_id = ? AND user_id != ?", roomID, userID).
+		Where("id > COALESCE((SELECT last_read_message_id FROM room_read_state WHERE room_id = ? AND user_id = ?), 0)", roomID, userID).
 		Count(&count).Error
 	return count, err
 }
 
+// GetUnreadCounts 批量获取用户在多个聊天室的未读消息数量
+func (d *MessageDAL) GetUnreadCounts(userID uint, roomIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(roomIDs))
+	if len(roomIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		RoomID uint
+		Count  int64
+	}
+	err := d.db.Model(&entities.Message{}).
+		Select("messages.room_id AS room_id, COUNT(*) AS count").
+		Joins("LEFT JOIN room_read_state ON room_read_state.room_id = messages.room_id AND room_read_state.user_id = ?", userID).
+		Where("messages.room_id IN ? AND messages.user_id != ?", roomIDs, userID).
+		Where("messages.id > COALESCE(room_read_state.last_read_message_id, 0)").
+		Group("messages.room_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.RoomID] = row.Count
+	}
+	return counts, nil
+}
+
+// ExistsInRoom 检查消息是否属于指定聊天室（包括已删除的消息）
+func (d *MessageDAL) ExistsInRoom(messageID, roomID uint) (bool, error) {
+	var count int64
+	err := d.db.Unscoped().Model(&entities.Message{}).
+		Where("id = ? AND room_id = ?", messageID, roomID).
+		Count(&count).Error
+	return count > 0, err
+}
+
 // GetRecentMessages 获取最近消息
 func (d *MessageDAL) GetRecentMessages(roomID uint, limit int) ([]*entities.Message, error) {
 	var messages []*entities.Message
@@ -98,3 +216,25 @@ func (d *MessageDAL) GetRecentMessages(roomID uint, limit int) ([]*entities.Mess
 		Find(&messages).Error
 	return messages, err
 }
+
+// loadReactions 为消息列表填充表情回应聚合
+func (d *MessageDAL) loadReactions(messages []*entities.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	messageIDs := make([]uint, 0, len(messages))
+	for _, message := range messages {
+		messageIDs = append(messageIDs, message.ID)
+	}
+
+	summaries, err := (&ReactionDAL{db: d.db}).GetSummaries(messageIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		message.Reactions = summaries[message.ID]
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetDAL 重置密码令牌数据访问层
+type PasswordResetDAL struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetDAL 创建重置密码令牌DAL实例
+func NewPasswordResetDAL() *PasswordResetDAL {
+	return &PasswordResetDAL{
+		db: database.DB,
+	}
+}
+
+// Create 保存重置密码令牌
+func (d *PasswordResetDAL) Create(token *entities.PasswordResetToken) error {
+	return d.db.Create(token).Error
+}
+
+// GetByHash 根据摘要获取重置密码令牌
+func (d *PasswordResetDAL) GetByHash(tokenHash string) (*entities.PasswordResetToken, error) {
+	var token entities.PasswordResetToken
+	err := d.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Consume 将令牌标记为已使用，返回是否成功（已使用的令牌返回false）
+func (d *PasswordResetDAL) Consume(id uint) (bool, error) {
+	result := d.db.Model(&entities.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// InvalidateByUser 使用户所有未使用的重置令牌失效
+func (d *PasswordResetDAL) InvalidateByUser(userID uint) error {
+	return d.db.Model(&entities.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
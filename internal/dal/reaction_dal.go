@@ -0,0 +1,77 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReactionDAL 表情回应数据访问层
+type ReactionDAL struct {
+	db *gorm.DB
+}
+
+// NewReactionDAL 创建表情回应DAL实例
+func NewReactionDAL() *ReactionDAL {
+	return &ReactionDAL{
+		db: database.DB,
+	}
+}
+
+// Create 添加表情回应，返回是否新增（已存在时不重复添加）
+func (d *ReactionDAL) Create(reaction *entities.MessageReaction) (bool, error) {
+	result := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(reaction)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Delete 取消表情回应，返回是否删除了记录
+func (d *ReactionDAL) Delete(messageID, userID uint, emoji string) (bool, error) {
+	result := d.db.Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+		Delete(&entities.MessageReaction{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetSummaries 批量获取消息的表情回应聚合，按消息ID分组
+func (d *ReactionDAL) GetSummaries(messageIDs []uint) (map[uint][]entities.ReactionSummary, error) {
+	summaries := make(map[uint][]entities.ReactionSummary, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return summaries, nil
+	}
+
+	var reactions []entities.MessageReaction
+	if err := d.db.Where("message_id IN ?", messageIDs).Order("id ASC").Find(&reactions).Error; err != nil {
+		return nil, err
+	}
+
+	// 按首次回应的顺序聚合同一表情
+	for _, reaction := range reactions {
+		list := summaries[reaction.MessageID]
+		found := false
+		for i := range list {
+			if list[i].Emoji == reaction.Emoji {
+				list[i].Count++
+				list[i].UserIDs = append(list[i].UserIDs, reaction.UserID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, entities.ReactionSummary{
+				Emoji:   reaction.Emoji,
+				Count:   1,
+				UserIDs: []uint{reaction.UserID},
+			})
+		}
+		summaries[reaction.MessageID] = list
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,60 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReadStateDAL 已读状态数据访问层
+type ReadStateDAL struct {
+	db *gorm.DB
+}
+
+// NewReadStateDAL 创建已读状态DAL实例
+func NewReadStateDAL() *ReadStateDAL {
+	return &ReadStateDAL{
+		db: database.DB,
+	}
+}
+
+// Get 获取用户在聊天室中的已读状态
+func (d *ReadStateDAL) Get(roomID, userID uint) (*entities.RoomReadState, error) {
+	var state entities.RoomReadState
+	err := d.db.Where("room_id = ? AND user_id = ?", roomID, userID).First(&state).Error
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Advance 推进已读位置（只会向前移动），返回已读位置是否发生变化
+func (d *ReadStateDAL) Advance(roomID, userID, messageID uint) (bool, error) {
+	result := d.db.Model(&entities.RoomReadState{}).
+		Where("room_id = ? AND user_id = ? AND last_read_message_id < ?", roomID, userID, messageID).
+		Updates(map[string]interface{}{
+			"last_read_message_id": messageID,
+			"updated_at":           time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// 没有更新任何记录时，可能是已读状态尚不存在
+	state := &entities.RoomReadState{
+		RoomID:            roomID,
+		UserID:            userID,
+		LastReadMessageID: messageID,
+	}
+	result = d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(state)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,63 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SanctionDAL 聊天室处罚数据访问层
+type SanctionDAL struct {
+	db *gorm.DB
+}
+
+// NewSanctionDAL 创建聊天室处罚DAL实例
+func NewSanctionDAL() *SanctionDAL {
+	return &SanctionDAL{
+		db: database.DB,
+	}
+}
+
+// Upsert 创建或更新处罚记录（同一用户同类处罚只保留一条）
+func (d *SanctionDAL) Upsert(sanction *entities.RoomSanction) error {
+	return d.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "room_id"}, {Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason", "expires_at", "created_by", "created_at"}),
+	}).Create(sanction).Error
+}
+
+// Delete 解除处罚，返回是否删除了记录
+func (d *SanctionDAL) Delete(roomID, userID uint, sanctionType string) (bool, error) {
+	result := d.db.Where("room_id = ? AND user_id = ? AND type = ?", roomID, userID, sanctionType).
+		Delete(&entities.RoomSanction{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetActive 获取用户在聊天室中仍然生效的处罚
+func (d *SanctionDAL) GetActive(roomID, userID uint, sanctionType string) (*entities.RoomSanction, error) {
+	var sanction entities.RoomSanction
+	err := d.db.Where("room_id = ? AND user_id = ? AND type = ?", roomID, userID, sanctionType).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		First(&sanction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sanction, nil
+}
+
+// GetActiveByRoom 获取聊天室中所有仍然生效的处罚
+func (d *SanctionDAL) GetActiveByRoom(roomID uint) ([]*entities.RoomSanction, error) {
+	var sanctions []*entities.RoomSanction
+	err := d.db.Where("room_id = ?", roomID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Preload("User").
+		Order("created_at DESC").
+		Find(&sanctions).Error
+	return sanctions, err
+}
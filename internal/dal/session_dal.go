@@ -0,0 +1,81 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SessionDAL 登录会话数据访问层
+type SessionDAL struct {
+	db *gorm.DB
+}
+
+// NewSessionDAL 创建登录会话DAL实例
+func NewSessionDAL() *SessionDAL {
+	return &SessionDAL{
+		db: database.DB,
+	}
+}
+
+// Create 创建登录会话
+func (d *SessionDAL) Create(session *entities.UserSession) error {
+	return d.db.Create(session).Error
+}
+
+// GetByID 根据ID获取登录会话
+func (d *SessionDAL) GetByID(id uint) (*entities.UserSession, error) {
+	var session entities.UserSession
+	err := d.db.First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActiveByUser 获取用户所有有效的登录会话，最近使用的在前
+func (d *SessionDAL) GetActiveByUser(userID uint) ([]*entities.UserSession, error) {
+	var sessions []*entities.UserSession
+	err := d.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch 更新会话最后使用时间
+func (d *SessionDAL) Touch(id uint) error {
+	return d.db.Model(&entities.UserSession{}).
+		Where("id = ?", id).
+		Update("last_used_at", time.Now()).Error
+}
+
+// Extend 刷新令牌时延长会话有效期
+func (d *SessionDAL) Extend(id uint, expiresAt time.Time) error {
+	now := time.Now()
+	return d.db.Model(&entities.UserSession{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"expires_at":   expiresAt,
+			"last_used_at": now,
+		}).Error
+}
+
+// Revoke 撤销登录会话，返回是否撤销了记录（已撤销的会话返回false）
+func (d *SessionDAL) Revoke(id uint) (bool, error) {
+	result := d.db.Model(&entities.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeByUser 撤销用户的所有登录会话
+func (d *SessionDAL) RevokeByUser(userID uint) error {
+	return d.db.Model(&entities.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,79 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenDAL 令牌数据访问层（刷新令牌与访问令牌黑名单）
+type TokenDAL struct {
+	db *gorm.DB
+}
+
+// NewTokenDAL 创建令牌DAL实例
+func NewTokenDAL() *TokenDAL {
+	return &TokenDAL{
+		db: database.DB,
+	}
+}
+
+// CreateRefreshToken 保存刷新令牌
+func (d *TokenDAL) CreateRefreshToken(token *entities.RefreshToken) error {
+	return d.db.Create(token).Error
+}
+
+// GetRefreshToken 根据摘要获取刷新令牌
+func (d *TokenDAL) GetRefreshToken(tokenHash string) (*entities.RefreshToken, error) {
+	var token entities.RefreshToken
+	err := d.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeRefreshToken 撤销刷新令牌，返回是否撤销了记录（已撤销的令牌返回false）
+func (d *TokenDAL) RevokeRefreshToken(tokenID uint) (bool, error) {
+	result := d.db.Model(&entities.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", tokenID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeUserRefreshTokens 撤销用户的所有刷新令牌
+func (d *TokenDAL) RevokeUserRefreshTokens(userID uint) error {
+	return d.db.Model(&entities.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeSessionRefreshTokens 撤销登录会话的所有刷新令牌
+func (d *TokenDAL) RevokeSessionRefreshTokens(sessionID uint) error {
+	return d.db.Model(&entities.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAccessToken 将访问令牌加入黑名单
+func (d *TokenDAL) RevokeAccessToken(token *entities.RevokedToken) error {
+	return d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+// IsAccessTokenRevoked 检查访问令牌是否在黑名单中
+func (d *TokenDAL) IsAccessTokenRevoked(jti string) (bool, error) {
+	var count int64
+	err := d.db.Model(&entities.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteExpiredRevokedTokens 清理已过期的黑名单记录
+func (d *TokenDAL) DeleteExpiredRevokedTokens() error {
+	return d.db.Where("expires_at < ?", time.Now()).Delete(&entities.RevokedToken{}).Error
+}
@@ -0,0 +1,100 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TwoFactorDAL 两步验证数据访问层（恢复码与登录挑战）
+type TwoFactorDAL struct {
+	db *gorm.DB
+}
+
+// NewTwoFactorDAL 创建两步验证DAL实例
+func NewTwoFactorDAL() *TwoFactorDAL {
+	return &TwoFactorDAL{
+		db: database.DB,
+	}
+}
+
+// ReplaceRecoveryCodes 替换用户的全部恢复码
+func (d *TwoFactorDAL) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entities.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]entities.RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, entities.RecoveryCode{
+				UserID:   userID,
+				CodeHash: hash,
+			})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// ConsumeRecoveryCode 使用恢复码，返回是否成功（不存在或已使用的返回false）
+func (d *TwoFactorDAL) ConsumeRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := d.db.Model(&entities.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Limit(1).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountUnusedRecoveryCodes 统计用户未使用的恢复码数量
+func (d *TwoFactorDAL) CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := d.db.Model(&entities.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteRecoveryCodes 删除用户的全部恢复码
+func (d *TwoFactorDAL) DeleteRecoveryCodes(userID uint) error {
+	return d.db.Where("user_id = ?", userID).Delete(&entities.RecoveryCode{}).Error
+}
+
+// CreateChallenge 保存登录挑战
+func (d *TwoFactorDAL) CreateChallenge(challenge *entities.LoginChallenge) error {
+	return d.db.Create(challenge).Error
+}
+
+// GetChallenge 根据摘要获取登录挑战
+func (d *TwoFactorDAL) GetChallenge(tokenHash string) (*entities.LoginChallenge, error) {
+	var challenge entities.LoginChallenge
+	err := d.db.Where("token_hash = ?", tokenHash).First(&challenge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// IncrementChallengeAttempts 递增登录挑战的失败次数
+func (d *TwoFactorDAL) IncrementChallengeAttempts(id uint) error {
+	return d.db.Model(&entities.LoginChallenge{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// DeleteChallenge 删除登录挑战，返回是否删除了记录（用于保证挑战只能完成一次）
+func (d *TwoFactorDAL) DeleteChallenge(id uint) (bool, error) {
+	result := d.db.Delete(&entities.LoginChallenge{}, id)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteExpiredChallenges 清理已过期的登录挑战
+func (d *TwoFactorDAL) DeleteExpiredChallenges() error {
+	return d.db.Where("expires_at < ?", time.Now()).Delete(&entities.LoginChallenge{}).Error
+}
@@ -0,0 +1,91 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UploadDAL 分片上传数据访问层
+type UploadDAL struct {
+	db *gorm.DB
+}
+
+// NewUploadDAL 创建分片上传DAL实例
+func NewUploadDAL() *UploadDAL {
+	return &UploadDAL{
+		db: database.DB,
+	}
+}
+
+// CreateSession 创建上传会话
+func (d *UploadDAL) CreateSession(session *entities.UploadSession) error {
+	return d.db.Create(session).Error
+}
+
+// GetSession 根据ID获取上传会话
+func (d *UploadDAL) GetSession(id string) (*entities.UploadSession, error) {
+	var session entities.UploadSession
+	if err := d.db.Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetExpiredSessions 获取已过期的上传会话
+func (d *UploadDAL) GetExpiredSessions(limit int) ([]*entities.UploadSession, error) {
+	var sessions []*entities.UploadSession
+	err := d.db.Where("expires_at < ?", time.Now()).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// GetChunks 获取会话已上传的分片，按序号排列
+func (d *UploadDAL) GetChunks(sessionID string) ([]*entities.UploadChunk, error) {
+	var chunks []*entities.UploadChunk
+	err := d.db.Where("session_id = ?", sessionID).Order(clause.OrderByColumn{Column: clause.Column{Name: "index"}}).Find(&chunks).Error
+	return chunks, err
+}
+
+// SaveChunk 记录已上传的分片，重传的分片覆盖原记录
+func (d *UploadDAL) SaveChunk(chunk *entities.UploadChunk) error {
+	return d.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "session_id"}, {Name: "index"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size", "checksum", "created_at"}),
+	}).Create(chunk).Error
+}
+
+// MarkCompleted 记录合并生成的附件，返回是否由本次调用完成（并发合并时只有一次成功）
+// 分片记录随之删除
+func (d *UploadDAL) MarkCompleted(sessionID string, attachmentID uint) (bool, error) {
+	var completed bool
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entities.UploadSession{}).
+			Where("id = ? AND attachment_id IS NULL", sessionID).
+			Update("attachment_id", attachmentID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		completed = true
+		return tx.Where("session_id = ?", sessionID).Delete(&entities.UploadChunk{}).Error
+	})
+	return completed, err
+}
+
+// DeleteSession 删除上传会话及其分片记录
+func (d *UploadDAL) DeleteSession(sessionID string) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id = ?", sessionID).Delete(&entities.UploadChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", sessionID).Delete(&entities.UploadSession{}).Error
+	})
+}
@@ -139,6 +139,35 @@ func (d *UserDAL) IsUserDeleted(userID uint) (bool, error) {
 	return user.DeletedAt.Valid, nil
 }
 
+// List 分页获取用户列表，includeDeleted为true时包括已删除的用户
+func (d *UserDAL) List(includeDeleted bool, limit, offset int) ([]entities.User, int64, error) {
+	query := d.db.Model(&entities.User{})
+	if includeDeleted {
+		query = query.Unscoped()
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []entities.User
+	err := query.Order("id ASC").Limit(limit).Offset(offset).Find(&users).Error
+	return users, total, err
+}
+
+// UpdateRole 更新用户全局角色
+func (d *UserDAL) UpdateRole(userID uint, role string) error {
+	return d.db.Model(&entities.User{}).Where("id = ?", userID).Update("role", role).Error
+}
+
+// ListBots 获取所有机器人账号
+func (d *UserDAL) ListBots() ([]entities.User, error) {
+	var users []entities.User
+	err := d.db.Where("is_bot = ?", true).Order("id ASC").Find(&users).Error
+	return users, err
+}
+
 // ===================== 房间相关软删除方法 =====================
 
 // RoomDAL 房间数据访问层
@@ -190,7 +219,7 @@ func (d *RoomDAL) GetByID(roomID uint) (*entities.Room, error) {
 // GetByBlurName 根据模糊名称搜索聊天室
 func (d *RoomDAL) GetByBlurName(name string) ([]*entities.Room, error) {
 	var rooms []*entities.Room
-	if err := d.db.Where("name LIKE ?", "%"+name+"%").
+	if err := d.db.Where("name LIKE ? AND kind <> ?", "%"+name+"%", entities.RoomKindDirect).
 		Preload("Creator").
 		Find(&rooms).Error; err != nil {
 		return nil, err
@@ -198,16 +227,57 @@ func (d *RoomDAL) GetByBlurName(name string) ([]*entities.Room, error) {
 	return rooms, nil
 }
 
-// GetUserRooms 获取用户加入的聊天室
+// GetUserRooms 获取用户加入的聊天室（不包括私聊）
 func (d *RoomDAL) GetUserRooms(userID uint) ([]*entities.Room, error) {
 	var rooms []*entities.Room
 	err := d.db.Joins("JOIN room_members ON rooms.id = room_members.room_id").
 		Where("room_members.user_id = ? AND room_members.deleted_at IS NULL", userID).
+		Where("rooms.kind <> ?", entities.RoomKindDirect).
 		Preload("Creator").
 		Find(&rooms).Error
 	return rooms, err
 }
 
+// GetUserDirectRooms 获取用户的私聊列表
+func (d *RoomDAL) GetUserDirectRooms(userID uint) ([]*entities.Room, error) {
+	var rooms []*entities.Room
+	err := d.db.Joins("JOIN room_members ON rooms.id = room_members.room_id").
+		Where("room_members.user_id = ? AND room_members.deleted_at IS NULL", userID).
+		Where("rooms.kind = ?", entities.RoomKindDirect).
+		Order("rooms.updated_at DESC").
+		Find(&rooms).Error
+	return rooms, err
+}
+
+// GetByDirectKey 根据私聊唯一标识获取聊天室（包括已软删除的，direct_key唯一索引不区分是否删除）
+func (d *RoomDAL) GetByDirectKey(directKey string) (*entities.Room, error) {
+	var room entities.Room
+	if err := d.db.Unscoped().Where("direct_key = ?", directKey).First(&room).Error; err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// CreateWithMembers 在同一事务中创建聊天室及其成员
+func (d *RoomDAL) CreateWithMembers(room *entities.Room, members []*entities.RoomMember) (*entities.Room, error) {
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(room).Error; err != nil {
+			return err
+		}
+		for _, member := range members {
+			member.RoomID = room.ID
+			if err := tx.Create(member).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
 // Update 更新聊天室信息
 func (d *RoomDAL) Update(roomID uint, name, description string) (*entities.Room, error) {
 	var room entities.Room
@@ -293,8 +363,153 @@ func (d *RoomMemberDAL) IsMember(roomID, userID uint) (bool, error) {
 	return count > 0, err
 }
 
+// GetMember 获取用户在聊天室中的成员记录
+func (d *RoomMemberDAL) GetMember(roomID, userID uint) (*entities.RoomMember, error) {
+	var member entities.RoomMember
+	err := d.db.Where("room_id = ? AND user_id = ?", roomID, userID).First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetPeers 获取多个聊天室中除指定用户外的成员（用于私聊列表展示对方信息）
+func (d *RoomMemberDAL) GetPeers(roomIDs []uint, excludeUserID uint) ([]*entities.RoomMember, error) {
+	var members []*entities.RoomMember
+	if len(roomIDs) == 0 {
+		return members, nil
+	}
+	err := d.db.Where("room_id IN ? AND user_id <> ?", roomIDs, excludeUserID).
+		Preload("User").
+		Find(&members).Error
+	return members, err
+}
+
 // Delete 删除聊天室成员关系（软删除）
 func (d *RoomMemberDAL) Delete(roomID, userID uint) error {
 	return d.db.Where("room_id = ? AND user_id = ?", roomID, userID).
 		Delete(&entities.RoomMember{}).Error
 }
+
+// UpdateRole 更新聊天室成员角色
+func (d *RoomMemberDAL) UpdateRole(roomID, userID uint, role string) error {
+	return d.db.Model(&entities.RoomMember{}).
+		Where("room_id = ? AND user_id = ?", roomID, userID).
+		Update("role", role).Error
+}
+
+// TransferOwnership 在同一事务中转让群主，原群主降为管理员，聊天室创建者同步改为新群主
+func (d *RoomMemberDAL) TransferOwnership(roomID, fromUserID, toUserID uint) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entities.Room{}).
+			Where("id = ?", roomID).
+			Update("created_by", toUserID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&entities.RoomMember{}).
+			Where("room_id = ? AND user_id = ?", roomID, toUserID).
+			Update("role", entities.RoomRoleOwner).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&entities.RoomMember{}).
+			Where("room_id = ? AND user_id = ?", roomID, fromUserID).
+			Update("role", entities.RoomRoleAdmin)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+
+		// 早期创建的聊天室中创建者可能没有成员记录，补充一条管理员记录
+		return tx.Create(&entities.RoomMember{
+			RoomID: roomID,
+			UserID: fromUserID,
+			Role:   entities.RoomRoleAdmin,
+		}).Error
+	})
+}
+
+// HasOwnerRecord 检查聊天室是否有过群主成员记录（包括已退出的）
+// 早期创建的聊天室没有任何群主记录
+func (d *RoomMemberDAL) HasOwnerRecord(roomID uint) (bool, error) {
+	var count int64
+	err := d.db.Unscoped().Model(&entities.RoomMember{}).
+		Where("room_id = ? AND role = ?", roomID, entities.RoomRoleOwner).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetUserIDsByRoles 获取聊天室中指定角色成员的用户ID
+func (d *RoomMemberDAL) GetUserIDsByRoles(roomID uint, roles []string) ([]uint, error) {
+	var userIDs []uint
+	err := d.db.Model(&entities.RoomMember{}).
+		Where("room_id = ? AND role IN ?", roomID, roles).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// IncrementTokenVersion 递增用户token版本，使已签发的token全部失效
+func (d *UserDAL) IncrementTokenVersion(userID uint) error {
+	return d.db.Unscoped().Model(&entities.User{}).Where("id = ?", userID).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+}
+
+// UpdatePassword 更新用户密码
+func (d *UserDAL) UpdatePassword(userID uint, passwordHash string) error {
+	return d.db.Model(&entities.User{}).Where("id = ?", userID).
+		Update("password_hash", passwordHash).Error
+}
+
+// IncrementFailedLogins 递增连续登录失败次数，返回递增后的次数
+func (d *UserDAL) IncrementFailedLogins(userID uint) (int, error) {
+	err := d.db.Model(&entities.User{}).Where("id = ?", userID).
+		Update("failed_logins", gorm.Expr("failed_logins + 1")).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var user entities.User
+	if err := d.db.Select("failed_logins").First(&user, userID).Error; err != nil {
+		return 0, err
+	}
+	return user.FailedLogins, nil
+}
+
+// LockUntil 锁定用户登录直到指定时间
+func (d *UserDAL) LockUntil(userID uint, lockedUntil time.Time) error {
+	return d.db.Model(&entities.User{}).Where("id = ?", userID).
+		Update("locked_until", lockedUntil).Error
+}
+
+// ResetFailedLogins 清除登录失败次数和锁定状态
+func (d *UserDAL) ResetFailedLogins(userID uint) error {
+	return d.db.Model(&entities.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"failed_logins": 0,
+			"locked_until":  nil,
+		}).Error
+}
+
+// UpdateTOTP 更新用户两步验证密钥和启用状态
+func (d *UserDAL) UpdateTOTP(userID uint, secret string, enabled bool) error {
+	return d.db.Model(&entities.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"totp_secret":  secret,
+			"totp_enabled": enabled,
+			"totp_counter": 0,
+		}).Error
+}
+
+// AdvanceTOTPCounter 记录已使用的验证码时间步，返回是否成功（时间步未超过上次使用的返回false）
+func (d *UserDAL) AdvanceTOTPCounter(userID uint, counter int64) (bool, error) {
+	result := d.db.Model(&entities.User{}).
+		Where("id = ? AND totp_counter < ?", userID, counter).
+		Update("totp_counter", counter)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,170 @@
+package dal
+
+import (
+	"gochat/internal/database"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookDAL Webhook及投递记录数据访问层
+type WebhookDAL struct {
+	db *gorm.DB
+}
+
+// NewWebhookDAL 创建Webhook DAL实例
+func NewWebhookDAL() *WebhookDAL {
+	return &WebhookDAL{
+		db: database.DB,
+	}
+}
+
+// Create 创建Webhook
+func (d *WebhookDAL) Create(webhook *entities.Webhook) error {
+	return d.db.Create(webhook).Error
+}
+
+// GetByID 根据ID获取Webhook
+func (d *WebhookDAL) GetByID(id uint) (*entities.Webhook, error) {
+	var webhook entities.Webhook
+	if err := d.db.First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// GetByRoom 获取聊天室的所有Webhook
+func (d *WebhookDAL) GetByRoom(roomID uint) ([]*entities.Webhook, error) {
+	var webhooks []*entities.Webhook
+	err := d.db.Where("room_id = ?", roomID).Order("id ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// GetActiveByRoom 获取聊天室所有启用的Webhook
+func (d *WebhookDAL) GetActiveByRoom(roomID uint) ([]*entities.Webhook, error) {
+	var webhooks []*entities.Webhook
+	err := d.db.Where("room_id = ? AND is_active = ?", roomID, true).Find(&webhooks).Error
+	return webhooks, err
+}
+
+// Update 更新Webhook的地址、订阅事件和启用状态
+func (d *WebhookDAL) Update(webhook *entities.Webhook) error {
+	return d.db.Model(webhook).Select("url", "events", "is_active").Updates(webhook).Error
+}
+
+// Delete 删除Webhook及其投递记录
+func (d *WebhookDAL) Delete(id uint) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&entities.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entities.Webhook{}, id).Error
+	})
+}
+
+// CreateDeliveries 批量创建投递记录
+func (d *WebhookDAL) CreateDeliveries(deliveries []*entities.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return d.db.Create(&deliveries).Error
+}
+
+// ClaimDueDeliveries 领取到期待投递的记录，领取后在lease时间内其他节点不会重复领取
+func (d *WebhookDAL) ClaimDueDeliveries(limit int, lease time.Duration) ([]*entities.WebhookDelivery, error) {
+	now := time.Now()
+
+	var candidates []*entities.WebhookDelivery
+	err := d.db.Where("status = ? AND next_attempt_at <= ? AND (locked_until IS NULL OR locked_until < ?)",
+		entities.WebhookDeliveryPending, now, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	lockedUntil := now.Add(lease)
+	claimed := make([]*entities.WebhookDelivery, 0, len(candidates))
+	for _, delivery := range candidates {
+		result := d.db.Model(&entities.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND (locked_until IS NULL OR locked_until < ?)",
+				delivery.ID, entities.WebhookDeliveryPending, now).
+			Update("locked_until", lockedUntil)
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			delivery.LockedUntil = &lockedUntil
+			claimed = append(claimed, delivery)
+		}
+	}
+	return claimed, nil
+}
+
+// MarkDelivered 记录投递成功
+func (d *WebhookDAL) MarkDelivered(id uint, attempts, statusCode int) error {
+	return d.db.Model(&entities.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":           entities.WebhookDeliverySucceeded,
+			"attempts":         attempts,
+			"last_status_code": statusCode,
+			"last_error":       "",
+			"locked_until":     nil,
+			"delivered_at":     time.Now(),
+		}).Error
+}
+
+// MarkFailed 记录投递失败，status为pending时在nextAttemptAt重试，为dead时转为死信
+func (d *WebhookDAL) MarkFailed(id uint, status string, attempts, statusCode int, lastError string, nextAttemptAt time.Time) error {
+	return d.db.Model(&entities.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":           status,
+			"attempts":         attempts,
+			"last_status_code": statusCode,
+			"last_error":       lastError,
+			"locked_until":     nil,
+			"next_attempt_at":  nextAttemptAt,
+		}).Error
+}
+
+// GetDeliveries 分页获取Webhook的投递记录，status为空时返回全部，最新的在前
+func (d *WebhookDAL) GetDeliveries(webhookID uint, status string, limit, offset int) ([]*entities.WebhookDelivery, int64, error) {
+	query := d.db.Model(&entities.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []*entities.WebhookDelivery
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+// GetDelivery 根据ID获取投递记录
+func (d *WebhookDAL) GetDelivery(id uint) (*entities.WebhookDelivery, error) {
+	var delivery entities.WebhookDelivery
+	if err := d.db.First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ResetDelivery 将投递记录（通常是死信）重新放回队列，立即重新投递
+func (d *WebhookDAL) ResetDelivery(id uint) error {
+	return d.db.Model(&entities.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          entities.WebhookDeliveryPending,
+			"attempts":        0,
+			"locked_until":    nil,
+			"next_attempt_at": time.Now(),
+		}).Error
+}
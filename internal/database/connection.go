@@ -48,6 +48,26 @@ func AutoMigrate() error {
 		&entities.Message{},
 		&entities.RoomMember{},
 		&entities.Attachment{}, // 添加附件表
+		&entities.UploadSession{},
+		&entities.UploadChunk{},
+		&entities.RoomReadState{},
+		&entities.MessageReaction{},
+		&entities.RoomSanction{},
+		&entities.RoomInvite{},
+		&entities.RoomJoinRequest{},
+		&entities.RefreshToken{},
+		&entities.RevokedToken{},
+		&entities.UserSession{},
+		&entities.PasswordResetToken{},
+		&entities.RecoveryCode{},
+		&entities.LoginChallenge{},
+		&entities.UserIdentity{},
+		&entities.OIDCState{},
+		&entities.APIKey{},
+		&entities.APIKeyRoom{},
+		&entities.Webhook{},
+		&entities.WebhookDelivery{},
+		&entities.IncomingWebhook{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to auto migrate: %w", err)
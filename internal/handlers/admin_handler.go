@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gochat/internal/middleware"
+	"gochat/internal/models/requests"
+	"gochat/internal/services"
+	ws "gochat/internal/websocket"
+	"gochat/pkg/response"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// AdminHandler 管理后台处理器
+type AdminHandler struct {
+	adminService *services.AdminService
+	hub          *ws.Hub
+}
+
+// NewAdminHandler 创建管理后台处理器实例
+func NewAdminHandler(hub *ws.Hub) *AdminHandler {
+	return &AdminHandler{
+		adminService: services.NewAdminService(),
+		hub:          hub,
+	}
+}
+
+// ListUsers 分页获取用户列表
+func (h *AdminHandler) ListUsers(ctx context.Context, c *app.RequestContext) {
+	// 分页参数
+	limitStr := c.DefaultQuery("limit", "50")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, _ := strconv.Atoi(limitStr)
+	offset, _ := strconv.Atoi(offsetStr)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	includeDeleted := c.Query("include_deleted") == "true"
+
+	users, err := h.adminService.ListUsers(includeDeleted, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, users)
+}
+
+// GetDisabledUsers 获取已禁用的用户列表
+func (h *AdminHandler) GetDisabledUsers(ctx context.Context, c *app.RequestContext) {
+	users, err := h.adminService.GetDisabledUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, users)
+}
+
+// DisableUser 禁用用户
+func (h *AdminHandler) DisableUser(ctx context.Context, c *app.RequestContext) {
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+
+	err = h.adminService.DisableUser(middleware.GetUserID(c), middleware.GetRole(c), uint(userID))
+	if err != nil {
+		c.JSON(adminErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 断开该用户的所有WebSocket连接
+	h.hub.DisconnectUser(uint(userID), 0, "账号已被禁用")
+
+	response.Success(ctx, c, "用户已禁用")
+}
+
+// RestoreUser 恢复已禁用的用户
+func (h *AdminHandler) RestoreUser(ctx context.Context, c *app.RequestContext) {
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+
+	err = h.adminService.RestoreUser(middleware.GetUserID(c), middleware.GetRole(c), uint(userID))
+	if err != nil {
+		c.JSON(adminErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, "用户已恢复")
+}
+
+// UpdateUserRole 调整用户全局角色
+func (h *AdminHandler) UpdateUserRole(ctx context.Context, c *app.RequestContext) {
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+
+	var req requests.UpdateUserRoleRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, changed, err := h.adminService.SetUserRole(middleware.GetUserID(c), middleware.GetRole(c), uint(userID), req.Role)
+	if err != nil {
+		c.JSON(adminErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 角色变化后令牌已失效，断开该用户的所有WebSocket连接
+	if changed {
+		h.hub.DisconnectUser(uint(userID), 0, "账号角色已变更，请重新登录")
+	}
+
+	response.Success(ctx, c, user)
+}
+
+// adminErrorStatus 将管理后台错误转换为HTTP状态码
+func adminErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAdminNoAuthority):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrAdminSelf):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,11 +2,16 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"gochat/internal/config"
+	"gochat/internal/mailer"
+	"gochat/internal/middleware"
 	"gochat/internal/models/requests"
 	"gochat/internal/services"
+	ws "gochat/internal/websocket"
 	"gochat/pkg/response"
 	"net/http"
+	"strconv"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/common/utils"
@@ -14,13 +19,17 @@ import (
 
 // AuthHandler 认证处理器
 type AuthHandler struct {
-	authService *services.AuthService
+	authService    *services.AuthService
+	sessionService *services.SessionService
+	hub            *ws.Hub
 }
 
 // NewAuthHandler 创建认证处理器实例
-func NewAuthHandler(cfg *config.JWTConfig) *AuthHandler {
+func NewAuthHandler(cfg *config.Config, mail mailer.Mailer, hub *ws.Hub) *AuthHandler {
 	return &AuthHandler{
-		authService: services.NewAuthService(cfg),
+		authService:    services.NewAuthService(&cfg.JWT, &cfg.Auth, mail),
+		sessionService: services.NewSessionService(&cfg.JWT),
+		hub:            hub,
 	}
 }
 
@@ -35,7 +44,7 @@ func (h *AuthHandler) Register(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	resp, err := h.authService.Register(&req)
+	resp, err := h.authService.Register(&req, sessionMeta(c, req.DeviceName))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, utils.H{
 			"error": err.Error(),
@@ -57,7 +66,57 @@ func (h *AuthHandler) Login(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	resp, err := h.authService.Login(&req)
+	resp, challenge, err := h.authService.Login(&req, sessionMeta(c, req.DeviceName))
+	if err != nil {
+		c.JSON(loginErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 启用两步验证的用户需使用pending_token完成第二步
+	if challenge != nil {
+		response.Success(ctx, c, challenge)
+		return
+	}
+
+	response.Success(ctx, c, resp)
+}
+
+// LoginTwoFactor 两步验证登录，使用登录返回的pending_token和验证码换取正式令牌
+func (h *AuthHandler) LoginTwoFactor(ctx context.Context, c *app.RequestContext) {
+	var req requests.TwoFactorLoginRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.authService.LoginTwoFactor(&req, sessionMeta(c, req.DeviceName))
+	if err != nil {
+		c.JSON(loginErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, resp)
+}
+
+// Refresh 使用刷新令牌换取新的令牌对（刷新令牌只能使用一次）
+func (h *AuthHandler) Refresh(ctx context.Context, c *app.RequestContext) {
+	var req requests.RefreshTokenRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.authService.RefreshToken(req.RefreshToken)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, utils.H{
 			"error": err.Error(),
@@ -68,6 +127,126 @@ func (h *AuthHandler) Login(ctx context.Context, c *app.RequestContext) {
 	response.Success(ctx, c, resp)
 }
 
+// Logout 退出登录，撤销当前令牌；all为true时退出所有设备
+func (h *AuthHandler) Logout(ctx context.Context, c *app.RequestContext) {
+	var req requests.LogoutRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "未认证",
+		})
+		return
+	}
+
+	if err := h.authService.Logout(claims, req.RefreshToken, req.All); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 断开已注销会话建立的WebSocket连接
+	if req.All {
+		h.hub.DisconnectUser(claims.UserID, 0, "已退出登录")
+	} else if claims.SessionID != 0 {
+		h.hub.DisconnectUser(claims.UserID, claims.SessionID, "已退出登录")
+	}
+
+	response.Success(ctx, c, "已退出登录")
+}
+
+// ChangePassword 修改密码，成功后其他设备全部下线，返回当前设备新的令牌
+func (h *AuthHandler) ChangePassword(ctx context.Context, c *app.RequestContext) {
+	var req requests.ChangePasswordRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	resp, err := h.authService.ChangePassword(userID, &req, sessionMeta(c, req.DeviceName))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrWrongPassword):
+			status = http.StatusBadRequest
+		case errors.Is(err, services.ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrAccountLocked):
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 旧的登录会话已全部失效，断开其WebSocket连接
+	h.hub.DisconnectUser(userID, 0, "密码已修改，请重新登录")
+
+	response.Success(ctx, c, resp)
+}
+
+// ForgotPassword 申请重置密码，无论邮箱是否存在都返回相同结果
+func (h *AuthHandler) ForgotPassword(ctx context.Context, c *app.RequestContext) {
+	var req requests.ForgotPasswordRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ForgotPassword(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, "如果该邮箱已注册，重置密码邮件已发送")
+}
+
+// ResetPassword 使用邮件中的令牌重置密码
+func (h *AuthHandler) ResetPassword(ctx context.Context, c *app.RequestContext) {
+	var req requests.ResetPasswordRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, err := h.authService.ResetPassword(req.Token, req.NewPassword)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrResetTokenInvalid) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.hub.DisconnectUser(userID, 0, "密码已重置，请重新登录")
+
+	response.Success(ctx, c, "密码已重置，请重新登录")
+}
+
 // GetUserInfo 获取当前用户信息
 func (h *AuthHandler) GetUserInfo(ctx context.Context, c *app.RequestContext) {
 	token := c.GetHeader("Authorization")
@@ -93,3 +272,66 @@ func (h *AuthHandler) GetUserInfo(ctx context.Context, c *app.RequestContext) {
 
 	response.Success(ctx, c, userInfo)
 }
+
+// GetSessions 获取当前用户的登录设备列表
+func (h *AuthHandler) GetSessions(ctx context.Context, c *app.RequestContext) {
+	var currentSessionID uint
+	if claims := middleware.GetClaims(c); claims != nil {
+		currentSessionID = claims.SessionID
+	}
+
+	sessions, err := h.sessionService.ListSessions(middleware.GetUserID(c), currentSessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, sessions)
+}
+
+// DeleteSession 注销指定的登录会话，并断开该会话建立的WebSocket连接
+func (h *AuthHandler) DeleteSession(ctx context.Context, c *app.RequestContext) {
+	sessionIDStr := c.Param("id")
+	sessionID, err := strconv.ParseUint(sessionIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的会话ID",
+		})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	if err := h.sessionService.RevokeSession(userID, uint(sessionID)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.hub.DisconnectUser(userID, uint(sessionID), "登录会话已注销")
+
+	response.Success(ctx, c, "会话已注销")
+}
+
+// loginErrorStatus 将登录错误转换为HTTP状态码
+func loginErrorStatus(err error) int {
+	if errors.Is(err, services.ErrAccountLocked) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusUnauthorized
+}
+
+// sessionMeta 从请求中提取登录设备信息
+func sessionMeta(c *app.RequestContext, deviceName string) *services.SessionMeta {
+	return &services.SessionMeta{
+		DeviceName: deviceName,
+		UserAgent:  string(c.UserAgent()),
+		IPAddress:  c.ClientIP(),
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gochat/internal/middleware"
+	"gochat/internal/models/requests"
+	"gochat/internal/services"
+	"gochat/pkg/response"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// BotHandler 机器人账号与API Key管理处理器（管理后台）
+type BotHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+// NewBotHandler 创建机器人处理器实例
+func NewBotHandler() *BotHandler {
+	return &BotHandler{
+		apiKeyService: services.NewAPIKeyService(),
+	}
+}
+
+// ListBots 获取机器人账号列表
+func (h *BotHandler) ListBots(ctx context.Context, c *app.RequestContext) {
+	bots, err := h.apiKeyService.ListBots()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, bots)
+}
+
+// CreateBot 创建机器人账号
+func (h *BotHandler) CreateBot(ctx context.Context, c *app.RequestContext) {
+	var req requests.CreateBotRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	bot, err := h.apiKeyService.CreateBot(middleware.GetUserID(c), req.Username, req.AvatarURL)
+	if err != nil {
+		c.JSON(botErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, bot)
+}
+
+// GetKeys 获取机器人的API Key列表
+func (h *BotHandler) GetKeys(ctx context.Context, c *app.RequestContext) {
+	botIDStr := c.Param("id")
+	botID, err := strconv.ParseUint(botIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的机器人ID",
+		})
+		return
+	}
+
+	keys, err := h.apiKeyService.ListKeys(uint(botID))
+	if err != nil {
+		c.JSON(botErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, keys)
+}
+
+// CreateKey 为机器人创建API Key，明文只在响应中返回一次
+func (h *BotHandler) CreateKey(ctx context.Context, c *app.RequestContext) {
+	botIDStr := c.Param("id")
+	botID, err := strconv.ParseUint(botIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的机器人ID",
+		})
+		return
+	}
+
+	var req requests.CreateAPIKeyRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	key, err := h.apiKeyService.CreateKey(middleware.GetUserID(c), uint(botID), req.Name, req.Scopes, req.RoomIDs, req.ExpiresIn)
+	if err != nil {
+		c.JSON(botErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, key)
+}
+
+// RevokeKey 撤销机器人的API Key
+func (h *BotHandler) RevokeKey(ctx context.Context, c *app.RequestContext) {
+	botIDStr := c.Param("id")
+	botID, err := strconv.ParseUint(botIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的机器人ID",
+		})
+		return
+	}
+
+	keyIDStr := c.Param("keyId")
+	keyID, err := strconv.ParseUint(keyIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的API Key ID",
+		})
+		return
+	}
+
+	if err := h.apiKeyService.RevokeKey(uint(botID), uint(keyID)); err != nil {
+		c.JSON(botErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, "API Key已撤销")
+}
+
+// botErrorStatus 将机器人管理错误转换为HTTP状态码
+func botErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrBotNotFound), errors.Is(err, services.ErrAPIKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrBotUsernameExists):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrAPIKeyScope), errors.Is(err, services.ErrAPIKeyRoom):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrRoomBanned):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gochat/internal/services"
+	ws "gochat/internal/websocket"
+	"gochat/pkg/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// DirectHandler 私聊处理器
+type DirectHandler struct {
+	roomService *services.RoomService
+	hub         *ws.Hub
+}
+
+// NewDirectHandler 创建私聊处理器实例
+func NewDirectHandler(hub *ws.Hub) *DirectHandler {
+	return &DirectHandler{
+		roomService: services.NewRoomService(),
+		hub:         hub,
+	}
+}
+
+// OpenDirectRoom 获取或创建与指定用户的私聊
+func (h *DirectHandler) OpenDirectRoom(ctx context.Context, c *app.RequestContext) {
+	peerIDStr := c.Param("userId")
+	peerID, err := strconv.ParseUint(peerIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	room, created, err := h.roomService.GetOrCreateDirectRoom(userID.(uint), uint(peerID))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDirectRoomSelf):
+			c.JSON(http.StatusBadRequest, utils.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, utils.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, utils.H{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	// 新建的私聊推送给对方的在线连接
+	if created {
+		username, _ := c.Get("username")
+		name, _ := username.(string)
+		h.hub.SendToUser(uint(peerID), &ws.WSMessage{
+			Type:      ws.MessageTypeDMCreated,
+			RoomID:    room.ID,
+			UserID:    userID.(uint),
+			Username:  name,
+			Content:   name + " 发起了私聊",
+			Room:      room,
+			Timestamp: time.Now(),
+		})
+	}
+
+	response.Success(ctx, c, room)
+}
+
+// GetDirectRooms 获取当前用户的私聊列表
+func (h *DirectHandler) GetDirectRooms(ctx context.Context, c *app.RequestContext) {
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	rooms, err := h.roomService.GetUserDirectRooms(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, rooms)
+}
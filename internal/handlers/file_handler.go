@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"gochat/internal/config"
+	"gochat/internal/middleware"
 	"gochat/internal/services"
 	"gochat/pkg/response"
 	"net/http"
-	"os"
 	"strconv"
 
 	"github.com/cloudwego/hertz/pkg/app"
@@ -16,12 +18,14 @@ import (
 // FileHandler 文件处理器
 type FileHandler struct {
 	attachmentService *services.AttachmentService
+	uploadService     *services.UploadService
 }
 
 // NewFileHandler 创建文件处理器实例
-func NewFileHandler() *FileHandler {
+func NewFileHandler(cfg *config.Config) *FileHandler {
 	return &FileHandler{
 		attachmentService: services.NewAttachmentService(),
+		uploadService:     services.NewUploadService(&cfg.Upload),
 	}
 }
 
@@ -52,7 +56,7 @@ func (h *FileHandler) UploadFile(ctx context.Context, c *app.RequestContext) {
 	}
 
 	// 验证文件
-	if err := h.attachmentService.ValidateFile(file); err != nil {
+	if err := h.uploadService.ValidateFile(file.Filename, file.Size); err != nil {
 		c.JSON(http.StatusBadRequest, utils.H{
 			"error": err.Error(),
 		})
@@ -60,8 +64,14 @@ func (h *FileHandler) UploadFile(ctx context.Context, c *app.RequestContext) {
 	}
 
 	// 上传文件（messageID为0时创建临时附件）
-	attachment, err := h.attachmentService.UploadFile(file, messageID)
+	attachment, err := h.attachmentService.UploadFile(file, middleware.GetUserID(c), messageID)
 	if err != nil {
+		if status := fileErrorStatus(err); status != http.StatusInternalServerError {
+			c.JSON(status, utils.H{
+				"error": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, utils.H{
 			"error": fmt.Sprintf("文件上传失败: %v", err),
 		})
@@ -82,30 +92,16 @@ func (h *FileHandler) DownloadFile(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	// 获取附件信息
-	attachment, err := h.attachmentService.GetAttachment(uint(attachmentID))
+	// 获取附件信息（检查访问权限）
+	attachment, err := h.attachmentService.GetAttachmentForUser(uint(attachmentID), middleware.GetUserID(c))
 	if err != nil {
-		c.JSON(http.StatusNotFound, utils.H{
-			"error": "附件不存在",
-		})
-		return
-	}
-
-	// 检查文件是否存在
-	if _, err := os.Stat(attachment.FilePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, utils.H{
-			"error": "文件不存在",
+		c.JSON(fileErrorStatus(err), utils.H{
+			"error": err.Error(),
 		})
 		return
 	}
 
-	// 设置响应头
-	c.Header("Content-Type", attachment.FileType)
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", attachment.FileName))
-	c.Header("Content-Length", fmt.Sprintf("%d", attachment.FileSize))
-
-	// 发送文件
-	c.File(attachment.FilePath)
+	h.serveBlob(ctx, c, h.attachmentService.OriginalBlob(attachment), true)
 }
 
 // PreviewFile 预览文件（主要用于图片和视频）
@@ -119,11 +115,11 @@ func (h *FileHandler) PreviewFile(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	// 获取附件信息
-	attachment, err := h.attachmentService.GetAttachment(uint(attachmentID))
+	// 获取附件信息（检查访问权限）
+	attachment, err := h.attachmentService.GetAttachmentForUser(uint(attachmentID), middleware.GetUserID(c))
 	if err != nil {
-		c.JSON(http.StatusNotFound, utils.H{
-			"error": "附件不存在",
+		c.JSON(fileErrorStatus(err), utils.H{
+			"error": err.Error(),
 		})
 		return
 	}
@@ -136,20 +132,46 @@ func (h *FileHandler) PreviewFile(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(attachment.FilePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, utils.H{
-			"error": "文件不存在",
+	h.serveBlob(ctx, c, h.attachmentService.OriginalBlob(attachment), false)
+}
+
+// GetThumbnail 获取图片缩略图（?size=256|1024，默认256），图片不超过该尺寸时返回原图
+func (h *FileHandler) GetThumbnail(ctx context.Context, c *app.RequestContext) {
+	attachmentIDStr := c.Param("id")
+	attachmentID, err := strconv.ParseUint(attachmentIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的附件ID",
 		})
 		return
 	}
 
-	// 设置响应头
-	c.Header("Content-Type", attachment.FileType)
-	c.Header("Cache-Control", "public, max-age=31536000") // 缓存一年
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(services.ThumbnailSizes[0])))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": services.ErrThumbnailSize.Error(),
+		})
+		return
+	}
 
-	// 发送文件
-	c.File(attachment.FilePath)
+	// 获取附件信息（检查访问权限）
+	attachment, err := h.attachmentService.GetAttachmentForUser(uint(attachmentID), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(fileErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	blob, err := h.attachmentService.ThumbnailBlob(attachment, size)
+	if err != nil {
+		c.JSON(fileErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.serveBlob(ctx, c, blob, false)
 }
 
 // GetFileInfo 获取文件信息
@@ -163,11 +185,11 @@ func (h *FileHandler) GetFileInfo(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	// 获取附件信息
-	attachment, err := h.attachmentService.GetAttachment(uint(attachmentID))
+	// 获取附件信息（检查访问权限）
+	attachment, err := h.attachmentService.GetAttachmentForUser(uint(attachmentID), middleware.GetUserID(c))
 	if err != nil {
-		c.JSON(http.StatusNotFound, utils.H{
-			"error": "附件不存在",
+		c.JSON(fileErrorStatus(err), utils.H{
+			"error": err.Error(),
 		})
 		return
 	}
@@ -186,8 +208,14 @@ func (h *FileHandler) DeleteFile(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	// 删除附件
-	if err := h.attachmentService.DeleteAttachment(uint(attachmentID)); err != nil {
+	// 删除附件（仅上传者或聊天室管理员）
+	if err := h.attachmentService.DeleteAttachment(uint(attachmentID), middleware.GetUserID(c)); err != nil {
+		if status := fileErrorStatus(err); status != http.StatusInternalServerError {
+			c.JSON(status, utils.H{
+				"error": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, utils.H{
 			"error": fmt.Sprintf("删除文件失败: %v", err),
 		})
@@ -198,3 +226,119 @@ func (h *FileHandler) DeleteFile(ctx context.Context, c *app.RequestContext) {
 		"message": "文件删除成功",
 	})
 }
+
+// ServeUpload 按存储路径访问上传的文件（/uploads/*filepath），与下载接口相同的权限检查
+func (h *FileHandler) ServeUpload(ctx context.Context, c *app.RequestContext) {
+	attachment, err := h.attachmentService.GetAttachmentByPathForUser(c.Param("filepath"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(fileErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.serveBlob(ctx, c, h.attachmentService.OriginalBlob(attachment), false)
+}
+
+// serveBlob 输出附件原文件或缩略图
+// 存储后端支持预签名时重定向到预签名地址（Range和条件请求由存储服务处理），否则从存储后端流式读取，
+// 支持ETag/Last-Modified条件请求和单段Range请求；download为true时以附件形式下载
+func (h *FileHandler) serveBlob(ctx context.Context, c *app.RequestContext, blob *services.AttachmentBlob, download bool) {
+	url, err := h.attachmentService.PresignBlob(ctx, blob, download)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error": fmt.Sprintf("读取文件失败: %v", err),
+		})
+		return
+	}
+	if url != "" {
+		// 预签名地址会过期，重定向本身不能被缓存
+		c.Header("Cache-Control", "no-store")
+		c.Redirect(http.StatusFound, []byte(url))
+		return
+	}
+
+	info, err := h.attachmentService.StatBlob(ctx, blob)
+	if err != nil {
+		writeBlobError(c, err)
+		return
+	}
+
+	// 设置响应头
+	c.Header("Content-Type", blob.ContentType)
+	c.Header("Accept-Ranges", "bytes")
+	if info.ETag != "" {
+		c.Header("ETag", info.ETag)
+	}
+	if !info.ModTime.IsZero() {
+		c.Header("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if download {
+		c.Header("Content-Disposition", services.ContentDisposition(blob.FileName))
+	} else {
+		c.Header("Cache-Control", "private, max-age=31536000") // 缓存一年，需鉴权的内容不允许共享缓存
+	}
+
+	// 客户端缓存仍然有效，304响应保留ETag和缓存相关的响应头
+	if isNotModified(c, info) {
+		c.SetStatusCode(http.StatusNotModified)
+		return
+	}
+
+	// 单段Range请求返回206，视频播放器拖动进度条时只加载需要的部分
+	start, length, ranged, err := requestRange(c, info)
+	if err != nil {
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+		c.JSON(http.StatusRequestedRangeNotSatisfiable, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if ranged {
+		reader, _, err := h.attachmentService.OpenBlobRange(ctx, blob, start, length)
+		if err != nil {
+			writeBlobError(c, err)
+			return
+		}
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, info.Size))
+		c.SetStatusCode(http.StatusPartialContent)
+		c.SetBodyStream(reader, int(length))
+		return
+	}
+
+	reader, info, err := h.attachmentService.OpenBlob(ctx, blob)
+	if err != nil {
+		writeBlobError(c, err)
+		return
+	}
+
+	// 流式发送文件，发送完成后由框架关闭Reader
+	c.SetBodyStream(reader, int(info.Size))
+}
+
+// writeBlobError 输出读取存储对象失败的错误
+func writeBlobError(c *app.RequestContext, err error) {
+	if errors.Is(err, services.ErrAttachmentFileNotFound) {
+		c.JSON(http.StatusNotFound, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, utils.H{
+		"error": fmt.Sprintf("读取文件失败: %v", err),
+	})
+}
+
+// fileErrorStatus 将附件错误转换为HTTP状态码
+func fileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAttachmentNotFound), errors.Is(err, services.ErrMessageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAttachmentForbidden), errors.Is(err, services.ErrAttachmentDeleteForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrThumbnailSize), errors.Is(err, services.ErrThumbnailUnsupported):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"gochat/internal/storage"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// Range请求错误
+var (
+	errRangeInvalid        = errors.New("无效的Range请求头")
+	errRangeMultiple       = errors.New("不支持多段Range请求")
+	errRangeNotSatisfiable = errors.New("请求的范围超出文件大小")
+)
+
+// isNotModified 根据If-None-Match或If-Modified-Since判断客户端缓存是否仍然有效
+// 两者同时存在时以If-None-Match为准
+func isNotModified(c *app.RequestContext, info *storage.BlobInfo) bool {
+	if ifNoneMatch := string(c.GetHeader("If-None-Match")); ifNoneMatch != "" {
+		return matchETag(ifNoneMatch, info.ETag, true)
+	}
+	return notModifiedSince(string(c.GetHeader("If-Modified-Since")), info.ModTime)
+}
+
+// requestRange 获取请求的字节范围，ranged为false时应返回完整文件
+// 没有Range头、Range头无法解析或If-Range与当前版本不一致时返回完整文件
+func requestRange(c *app.RequestContext, info *storage.BlobInfo) (start, length int64, ranged bool, err error) {
+	rangeHeader := string(c.GetHeader("Range"))
+	if rangeHeader == "" || !ifRangeMatches(string(c.GetHeader("If-Range")), info) {
+		return 0, 0, false, nil
+	}
+
+	start, length, err = parseRange(rangeHeader, info.Size)
+	if errors.Is(err, errRangeInvalid) {
+		return 0, 0, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return start, length, true, nil
+}
+
+// ifRangeMatches 判断If-Range是否与当前版本一致，值可以是强ETag或HTTP日期
+func ifRangeMatches(ifRange string, info *storage.BlobInfo) bool {
+	ifRange = strings.TrimSpace(ifRange)
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, "\"") || strings.HasPrefix(ifRange, "W/") {
+		return matchETag(ifRange, info.ETag, false)
+	}
+	since, err := http.ParseTime(ifRange)
+	if err != nil || info.ModTime.IsZero() {
+		return false
+	}
+	return info.ModTime.Truncate(time.Second).Equal(since)
+}
+
+// parseRange 解析单段字节范围（RFC 9110 14.1.2），返回起始位置和长度
+// 支持 bytes=0-99、bytes=100- 和 bytes=-100，结束位置超出文件时截断到文件末尾
+// 语法错误或非bytes单位返回errRangeInvalid，调用方应忽略该请求头
+func parseRange(header string, size int64) (start, length int64, err error) {
+	unit, spec, found := strings.Cut(strings.TrimSpace(header), "=")
+	if !found || !strings.EqualFold(strings.TrimSpace(unit), "bytes") {
+		return 0, 0, errRangeInvalid
+	}
+	if strings.Contains(spec, ",") {
+		return 0, 0, errRangeMultiple
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, errRangeInvalid
+	}
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+	// bytes=-N：最后N个字节
+	if first == "" {
+		suffix, err := parseRangeInt(last)
+		if err != nil {
+			return 0, 0, err
+		}
+		if suffix == 0 || size == 0 {
+			return 0, 0, errRangeNotSatisfiable
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return size - suffix, suffix, nil
+	}
+
+	start, err = parseRangeInt(first)
+	if err != nil {
+		return 0, 0, err
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = parseRangeInt(last); err != nil {
+			return 0, 0, err
+		}
+		if end < start {
+			return 0, 0, errRangeInvalid
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, errRangeNotSatisfiable
+	}
+	return start, end - start + 1, nil
+}
+
+// matchETag 判断If-Match/If-None-Match等请求头中的实体标签列表是否包含etag
+// weak为true时使用弱比较（忽略W/前缀），否则弱标签不匹配任何值
+func matchETag(header, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if !strings.HasPrefix(candidate, "W/") && !strings.HasPrefix(etag, "W/") && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModifiedSince 判断资源自If-Modified-Since等请求头中的时间之后是否未被修改（HTTP日期精确到秒）
+func notModifiedSince(header string, modTime time.Time) bool {
+	if header == "" || modTime.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(header)
+	if err != nil {
+		return false
+	}
+	return !modTime.Truncate(time.Second).After(since)
+}
+
+// parseRangeInt 解析范围中的非负整数
+func parseRangeInt(value string) (int64, error) {
+	if value == "" || strings.HasPrefix(value, "+") {
+		return 0, errRangeInvalid
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errRangeInvalid
+	}
+	return n, nil
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gochat/internal/middleware"
+	"gochat/internal/models/requests"
+	"gochat/internal/services"
+	ws "gochat/internal/websocket"
+	"gochat/pkg/response"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// IncomingWebhookHandler 传入Webhook处理器
+type IncomingWebhookHandler struct {
+	hookService *services.IncomingWebhookService
+	hub         *ws.Hub
+}
+
+// NewIncomingWebhookHandler 创建传入Webhook处理器实例
+func NewIncomingWebhookHandler(hub *ws.Hub) *IncomingWebhookHandler {
+	return &IncomingWebhookHandler{
+		hookService: services.NewIncomingWebhookService(),
+		hub:         hub,
+	}
+}
+
+// CreateIncomingWebhook 创建传入Webhook（管理员及以上），响应中的URL只返回一次
+func (h *IncomingWebhookHandler) CreateIncomingWebhook(ctx context.Context, c *app.RequestContext) {
+	roomID, ok := parseUintParam(c, "id", "无效的聊天室ID")
+	if !ok {
+		return
+	}
+
+	var req requests.CreateIncomingWebhookRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	hook, err := h.hookService.CreateIncomingWebhook(middleware.GetUserID(c), roomID, &req)
+	if err != nil {
+		c.JSON(incomingWebhookErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 服务返回的是路径，按请求的协议和主机补全为完整URL
+	hook.URL = requestOrigin(c) + hook.URL
+	response.Success(ctx, c, hook)
+}
+
+// GetIncomingWebhooks 获取聊天室的传入Webhook列表
+func (h *IncomingWebhookHandler) GetIncomingWebhooks(ctx context.Context, c *app.RequestContext) {
+	roomID, ok := parseUintParam(c, "id", "无效的聊天室ID")
+	if !ok {
+		return
+	}
+
+	hooks, err := h.hookService.ListIncomingWebhooks(middleware.GetUserID(c), roomID)
+	if err != nil {
+		c.JSON(incomingWebhookErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, hooks)
+}
+
+// DeleteIncomingWebhook 删除传入Webhook
+func (h *IncomingWebhookHandler) DeleteIncomingWebhook(ctx context.Context, c *app.RequestContext) {
+	roomID, ok := parseUintParam(c, "id", "无效的聊天室ID")
+	if !ok {
+		return
+	}
+	hookID, ok := parseUintParam(c, "hookId", "无效的传入Webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := h.hookService.DeleteIncomingWebhook(middleware.GetUserID(c), roomID, hookID); err != nil {
+		c.JSON(incomingWebhookErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, "传入Webhook已删除")
+}
+
+// PostHook 接收Slack格式的消息（POST /hooks/:token）
+// 为了让现有的Slack集成无需修改即可使用，请求体和响应都沿用Slack incoming-webhook的约定：
+// 接受JSON请求体或表单中的payload字段，成功返回纯文本ok，失败返回Slack的错误码
+func (h *IncomingWebhookHandler) PostHook(ctx context.Context, c *app.RequestContext) {
+	body := c.Request.Body()
+	if strings.HasPrefix(string(c.ContentType()), "application/x-www-form-urlencoded") {
+		body = []byte(c.PostForm("payload"))
+	}
+
+	var msg requests.SlackMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.String(http.StatusBadRequest, "invalid_payload")
+		return
+	}
+
+	message, err := h.hookService.Post(c.Param("token"), &msg)
+	if err != nil {
+		status, code := slackErrorCode(err)
+		c.String(status, code)
+		return
+	}
+
+	h.hub.PublishMessage(message)
+	c.String(http.StatusOK, "ok")
+}
+
+// requestOrigin 根据请求（及反向代理头）得到 scheme://host
+func requestOrigin(c *app.RequestContext) string {
+	scheme := string(c.GetHeader("X-Forwarded-Proto"))
+	if scheme == "" {
+		scheme = string(c.URI().Scheme())
+	}
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + string(c.Host())
+}
+
+// slackErrorCode 将传入Webhook的错误转换为Slack风格的状态码和错误码
+func slackErrorCode(err error) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrIncomingWebhookNotFound), errors.Is(err, services.ErrRoomNotFound):
+		return http.StatusNotFound, "no_service"
+	case errors.Is(err, services.ErrIncomingWebhookNoText):
+		return http.StatusBadRequest, "no_text"
+	case errors.Is(err, services.ErrIncomingWebhookTooLong):
+		return http.StatusBadRequest, "msg_too_long"
+	case errors.Is(err, services.ErrNotRoomMember), errors.Is(err, services.ErrMemberMuted):
+		return http.StatusForbidden, "action_prohibited"
+	default:
+		return http.StatusInternalServerError, "internal_error"
+	}
+}
+
+// incomingWebhookErrorStatus 将传入Webhook管理错误转换为HTTP状态码
+func incomingWebhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrIncomingWebhookNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrRoomNoAuthority):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrDirectRoomManage):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrBotUsernameExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gochat/internal/models/requests"
+	"gochat/internal/services"
+	ws "gochat/internal/websocket"
+	"gochat/pkg/logger"
+	"gochat/pkg/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// InviteHandler 聊天室邀请与加入申请处理器
+type InviteHandler struct {
+	inviteService *services.InviteService
+	hub           *ws.Hub
+}
+
+// NewInviteHandler 创建邀请处理器实例
+func NewInviteHandler(hub *ws.Hub) *InviteHandler {
+	return &InviteHandler{
+		inviteService: services.NewInviteService(),
+		hub:           hub,
+	}
+}
+
+// CreateInvite 创建邀请链接
+func (h *InviteHandler) CreateInvite(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	var req requests.CreateInviteRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	invite, err := h.inviteService.CreateInvite(userID.(uint), uint(roomID), req.MaxUses, time.Duration(req.ExpiresIn)*time.Second)
+	if err != nil {
+		c.JSON(inviteErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, invite)
+}
+
+// GetInvites 获取聊天室中仍可使用的邀请链接
+func (h *InviteHandler) GetInvites(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	invites, err := h.inviteService.GetActiveInvites(userID.(uint), uint(roomID))
+	if err != nil {
+		c.JSON(inviteErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, invites)
+}
+
+// RevokeInvite 撤销邀请链接
+func (h *InviteHandler) RevokeInvite(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	inviteIDStr := c.Param("inviteId")
+	inviteID, err := strconv.ParseUint(inviteIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的邀请ID",
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	if err := h.inviteService.RevokeInvite(userID.(uint), uint(roomID), uint(inviteID)); err != nil {
+		c.JSON(inviteErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, "邀请已撤销")
+}
+
+// AcceptInvite 通过邀请链接加入聊天室
+func (h *InviteHandler) AcceptInvite(ctx context.Context, c *app.RequestContext) {
+	token := c.Param("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "邀请令牌不能为空",
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	room, err := h.inviteService.AcceptInvite(userID.(uint), token)
+	if err != nil {
+		c.JSON(inviteErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, room)
+}
+
+// CreateJoinRequest 申请加入私有聊天室
+func (h *InviteHandler) CreateJoinRequest(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	var req requests.JoinRequestRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	request, err := h.inviteService.CreateJoinRequest(userID.(uint), uint(roomID), req.Message)
+	if err != nil {
+		c.JSON(inviteErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 推送给在线的聊天室管理员
+	adminIDs, err := h.inviteService.GetRoomAdminIDs(uint(roomID))
+	if err != nil {
+		logger.Error("Failed to get room admins:", err)
+	} else {
+		h.hub.NotifyJoinRequest(request, adminIDs)
+	}
+
+	response.Success(ctx, c, request)
+}
+
+// GetJoinRequests 获取聊天室待审批的加入申请
+func (h *InviteHandler) GetJoinRequests(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	joinRequests, err := h.inviteService.GetPendingJoinRequests(userID.(uint), uint(roomID))
+	if err != nil {
+		c.JSON(inviteErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, joinRequests)
+}
+
+// ApproveJoinRequest 同意加入申请
+func (h *InviteHandler) ApproveJoinRequest(ctx context.Context, c *app.RequestContext) {
+	h.reviewJoinRequest(ctx, c, true)
+}
+
+// DenyJoinRequest 拒绝加入申请
+func (h *InviteHandler) DenyJoinRequest(ctx context.Context, c *app.RequestContext) {
+	h.reviewJoinRequest(ctx, c, false)
+}
+
+// reviewJoinRequest 审批加入申请并通知申请人
+func (h *InviteHandler) reviewJoinRequest(ctx context.Context, c *app.RequestContext, approve bool) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	requestIDStr := c.Param("requestId")
+	requestID, err := strconv.ParseUint(requestIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的申请ID",
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	request, err := h.inviteService.ReviewJoinRequest(userID.(uint), uint(roomID), uint(requestID), approve)
+	if err != nil {
+		c.JSON(inviteErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.hub.NotifyJoinReviewed(request, c.GetString("username"))
+
+	response.Success(ctx, c, request)
+}
+
+// inviteErrorStatus 将邀请与加入申请错误转换为HTTP状态码
+func inviteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInviteInvalid), errors.Is(err, services.ErrInviteNotFound),
+		errors.Is(err, services.ErrJoinRequestNotFound), errors.Is(err, services.ErrRoomNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrRoomNoAuthority), errors.Is(err, services.ErrRoomBanned):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrJoinRequestExists), errors.Is(err, services.ErrAlreadyMember):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrJoinRequestNotNeeded), errors.Is(err, services.ErrDirectRoomJoin),
+		errors.Is(err, services.ErrDirectRoomManage):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
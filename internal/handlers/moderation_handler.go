@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gochat/internal/models/requests"
+	"gochat/internal/services"
+	ws "gochat/internal/websocket"
+	"gochat/pkg/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// ModerationHandler 聊天室管理处理器
+type ModerationHandler struct {
+	moderationService *services.ModerationService
+	hub               *ws.Hub
+}
+
+// NewModerationHandler 创建聊天室管理处理器实例
+func NewModerationHandler(hub *ws.Hub) *ModerationHandler {
+	return &ModerationHandler{
+		moderationService: services.NewModerationService(),
+		hub:               hub,
+	}
+}
+
+// KickMember 将成员踢出聊天室
+func (h *ModerationHandler) KickMember(ctx context.Context, c *app.RequestContext) {
+	operatorID, roomID, targetID, ok := parseModerationParams(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.moderationService.KickMember(operatorID, roomID, targetID)
+	h.respond(ctx, c, result, err)
+}
+
+// BanMember 封禁用户
+func (h *ModerationHandler) BanMember(ctx context.Context, c *app.RequestContext) {
+	operatorID, roomID, targetID, ok := parseModerationParams(c)
+	if !ok {
+		return
+	}
+
+	var req requests.SanctionRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.moderationService.BanMember(operatorID, roomID, targetID, req.Reason, time.Duration(req.Duration)*time.Second)
+	h.respond(ctx, c, result, err)
+}
+
+// UnbanMember 解除封禁
+func (h *ModerationHandler) UnbanMember(ctx context.Context, c *app.RequestContext) {
+	operatorID, roomID, targetID, ok := parseModerationParams(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.moderationService.UnbanMember(operatorID, roomID, targetID)
+	h.respond(ctx, c, result, err)
+}
+
+// MuteMember 禁言成员
+func (h *ModerationHandler) MuteMember(ctx context.Context, c *app.RequestContext) {
+	operatorID, roomID, targetID, ok := parseModerationParams(c)
+	if !ok {
+		return
+	}
+
+	var req requests.SanctionRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.moderationService.MuteMember(operatorID, roomID, targetID, req.Reason, time.Duration(req.Duration)*time.Second)
+	h.respond(ctx, c, result, err)
+}
+
+// UnmuteMember 解除禁言
+func (h *ModerationHandler) UnmuteMember(ctx context.Context, c *app.RequestContext) {
+	operatorID, roomID, targetID, ok := parseModerationParams(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.moderationService.UnmuteMember(operatorID, roomID, targetID)
+	h.respond(ctx, c, result, err)
+}
+
+// UpdateMemberRole 调整成员角色
+func (h *ModerationHandler) UpdateMemberRole(ctx context.Context, c *app.RequestContext) {
+	operatorID, roomID, targetID, ok := parseModerationParams(c)
+	if !ok {
+		return
+	}
+
+	var req requests.UpdateMemberRoleRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.moderationService.SetMemberRole(operatorID, roomID, targetID, req.Role)
+	h.respond(ctx, c, result, err)
+}
+
+// GetSanctions 获取聊天室中生效的封禁和禁言
+func (h *ModerationHandler) GetSanctions(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	sanctions, err := h.moderationService.GetActiveSanctions(userID.(uint), uint(roomID))
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, sanctions)
+}
+
+// respond 返回管理操作结果，成功时通知聊天室
+func (h *ModerationHandler) respond(ctx context.Context, c *app.RequestContext, result *services.ModerationResult, err error) {
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.hub.NotifyModeration(result)
+	response.Success(ctx, c, result)
+}
+
+// parseModerationParams 解析管理操作的聊天室ID、目标用户ID和当前用户ID
+func parseModerationParams(c *app.RequestContext) (operatorID, roomID, targetID uint, ok bool) {
+	roomIDVal, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return 0, 0, 0, false
+	}
+
+	targetIDVal, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的用户ID",
+		})
+		return 0, 0, 0, false
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return 0, 0, 0, false
+	}
+
+	return userID.(uint), uint(roomIDVal), uint(targetIDVal), true
+}
+
+// moderationErrorStatus 将管理操作错误转换为HTTP状态码
+func moderationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrModerationNoAuthority):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrModerationNotMember), errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrModerationSelf), errors.Is(err, services.ErrModerationInvalidRole),
+		errors.Is(err, services.ErrModerationDirectRoom):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gochat/internal/config"
+	"gochat/internal/mailer"
+	"gochat/internal/models/responses"
+	"gochat/internal/services"
+	"gochat/pkg/response"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+	"github.com/cloudwego/hertz/pkg/protocol"
+)
+
+// OIDC浏览器绑定Cookie，登录回调只接受发起登录的浏览器
+const (
+	oidcBindingCookie = "gochat_oidc_binding"
+	oidcCookiePath    = "/api/auth/oidc"
+)
+
+// OIDCHandler OpenID Connect登录处理器
+type OIDCHandler struct {
+	oidcService *services.OIDCService
+	successURL  string
+}
+
+// NewOIDCHandler 创建OIDC登录处理器实例
+func NewOIDCHandler(cfg *config.Config, mail mailer.Mailer) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: services.NewOIDCService(cfg, mail),
+		successURL:  cfg.OIDC.SuccessURL,
+	}
+}
+
+// Login 跳转到身份提供方登录
+func (h *OIDCHandler) Login(ctx context.Context, c *app.RequestContext) {
+	authURL, binding, err := h.oidcService.BeginLogin(ctx, c.Param("provider"))
+	if err != nil {
+		c.JSON(oidcErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// SameSite=Lax允许身份提供方重定向回来时携带Cookie
+	c.SetCookie(oidcBindingCookie, binding, int(services.OIDCStateTTL.Seconds()), oidcCookiePath, "", protocol.CookieSameSiteLaxMode, true, true)
+
+	c.Redirect(http.StatusFound, []byte(authURL))
+}
+
+// Callback 身份提供方回调，登录成功后签发令牌；启用两步验证的用户返回登录挑战
+// 配置了success_url时重定向到前端并将令牌（或挑战）附加在URL片段中，否则直接返回JSON
+func (h *OIDCHandler) Callback(ctx context.Context, c *app.RequestContext) {
+	// 绑定值只能使用一次，读取后立即清除Cookie
+	binding := string(c.Cookie(oidcBindingCookie))
+	c.SetCookie(oidcBindingCookie, "", -1, oidcCookiePath, "", protocol.CookieSameSiteLaxMode, true, true)
+
+	if idpError := c.Query("error"); idpError != "" {
+		description := c.Query("error_description")
+		if description == "" {
+			description = idpError
+		}
+		h.fail(c, http.StatusBadRequest, services.ErrOIDCLoginFailed.Error()+": "+description)
+		return
+	}
+
+	meta := sessionMeta(c, "")
+	resp, challenge, err := h.oidcService.CompleteLogin(ctx, c.Param("provider"), c.Query("state"), c.Query("code"), binding, meta)
+	if err != nil {
+		h.fail(c, oidcErrorStatus(err), err.Error())
+		return
+	}
+
+	// 启用两步验证的用户需使用pending_token完成第二步
+	if challenge != nil {
+		if h.successURL == "" {
+			response.Success(ctx, c, challenge)
+			return
+		}
+		c.Redirect(http.StatusFound, []byte(h.successURL+"#"+challengeFragment(challenge).Encode()))
+		return
+	}
+
+	if h.successURL == "" {
+		response.Success(ctx, c, resp)
+		return
+	}
+	c.Redirect(http.StatusFound, []byte(h.successURL+"#"+tokenFragment(resp).Encode()))
+}
+
+// fail 返回登录失败信息，配置了success_url时重定向到前端
+func (h *OIDCHandler) fail(c *app.RequestContext, status int, message string) {
+	if h.successURL == "" {
+		c.JSON(status, utils.H{
+			"error": message,
+		})
+		return
+	}
+
+	fragment := url.Values{}
+	fragment.Set("error", message)
+	c.Redirect(http.StatusFound, []byte(h.successURL+"#"+fragment.Encode()))
+}
+
+// tokenFragment 将令牌编码为URL片段参数（片段不会发送到服务器或记录在访问日志中）
+func tokenFragment(resp *responses.AuthResponse) url.Values {
+	fragment := url.Values{}
+	fragment.Set("token", resp.Token)
+	fragment.Set("expires_at", resp.ExpiresAt.Format(time.RFC3339))
+	fragment.Set("refresh_token", resp.RefreshToken)
+	fragment.Set("refresh_expires_at", resp.RefreshExpiresAt.Format(time.RFC3339))
+	fragment.Set("session_id", strconv.FormatUint(uint64(resp.SessionID), 10))
+	return fragment
+}
+
+// challengeFragment 将两步验证登录挑战编码为URL片段参数
+func challengeFragment(challenge *responses.TwoFactorChallenge) url.Values {
+	fragment := url.Values{}
+	fragment.Set("two_factor_required", "true")
+	fragment.Set("pending_token", challenge.PendingToken)
+	fragment.Set("expires_at", challenge.ExpiresAt.Format(time.RFC3339))
+	return fragment
+}
+
+// oidcErrorStatus 将OIDC登录错误转换为HTTP状态码
+func oidcErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOIDCProviderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrOIDCStateInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrOIDCLoginFailed):
+		return http.StatusBadGateway
+	case errors.Is(err, services.ErrOIDCEmailConflict):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrOIDCAccountDisabled):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrAccountLocked):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"gochat/internal/middleware"
 	"gochat/internal/models/entities"
 	"gochat/internal/models/requests"
+	"gochat/internal/models/responses"
 	"gochat/internal/services"
+	ws "gochat/internal/websocket"
 	"gochat/pkg/response"
 	"net/http"
 	"strconv"
@@ -16,12 +20,14 @@ import (
 // RoomHandler 聊天室处理器
 type RoomHandler struct {
 	roomService *services.RoomService
+	hub         *ws.Hub
 }
 
 // NewRoomHandler 创建聊天室处理器实例
-func NewRoomHandler() *RoomHandler {
+func NewRoomHandler(hub *ws.Hub) *RoomHandler {
 	return &RoomHandler{
 		roomService: services.NewRoomService(),
+		hub:         hub,
 	}
 }
 
@@ -63,6 +69,124 @@ func (h *RoomHandler) CreateRoom(ctx context.Context, c *app.RequestContext) {
 	response.Success(ctx, c, createdRoom)
 }
 
+// UpdateRoom 更新聊天室信息
+func (h *RoomHandler) UpdateRoom(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	var req requests.UpdateRoomRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	room, err := h.roomService.UpdateRoom(userID.(uint), uint(roomID), req.Name, req.Description)
+	if err != nil {
+		c.JSON(roomErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	username := c.GetString("username")
+	h.hub.NotifyRoomUpdated(room, userID.(uint), username, username+" 更新了聊天室信息")
+
+	response.Success(ctx, c, room)
+}
+
+// DeleteRoom 删除聊天室
+func (h *RoomHandler) DeleteRoom(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	if err := h.roomService.DeleteRoom(userID.(uint), uint(roomID)); err != nil {
+		c.JSON(roomErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.hub.NotifyRoomDeleted(uint(roomID), userID.(uint), c.GetString("username"))
+
+	response.Success(ctx, c, "聊天室已删除")
+}
+
+// TransferRoom 转让群主
+func (h *RoomHandler) TransferRoom(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	var req requests.TransferRoomRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 从JWT token中获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.H{
+			"error": "用户未认证",
+		})
+		return
+	}
+
+	room, newOwner, err := h.roomService.TransferOwnership(userID.(uint), uint(roomID), req.UserID)
+	if err != nil {
+		c.JSON(roomErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	username := c.GetString("username")
+	h.hub.NotifyRoomUpdated(room, userID.(uint), username, username+" 将群主转让给了 "+newOwner.Username)
+
+	response.Success(ctx, c, "群主已转让")
+}
+
 // GetRooms 获取聊天室列表
 func (h *RoomHandler) GetRooms(ctx context.Context, c *app.RequestContext) {
 	// 从JWT token中获取用户ID
@@ -74,7 +198,7 @@ func (h *RoomHandler) GetRooms(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	rooms, err := h.roomService.GetUserRooms(userID.(uint))
+	rooms, err := h.roomService.GetUserRoomsWithUnread(userID.(uint))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.H{
 			"error": err.Error(),
@@ -149,7 +273,11 @@ func (h *RoomHandler) JoinRoom(ctx context.Context, c *app.RequestContext) {
 
 	err = h.roomService.JoinRoom(userID.(uint), uint(roomID))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.H{
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrRoomBanned) || errors.Is(err, services.ErrPrivateRoomJoin) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, utils.H{
 			"error": err.Error(),
 		})
 		return
@@ -229,6 +357,22 @@ func (h *RoomHandler) GetRoomMessages(ctx context.Context, c *app.RequestContext
 	limit, _ := strconv.Atoi(limitStr)
 	offset, _ := strconv.Atoi(offsetStr)
 
+	// API Key只能读取授权的聊天室
+	if key := middleware.GetAPIKey(c); key != nil && !key.AllowsRoom(uint(roomID)) {
+		c.JSON(http.StatusForbidden, utils.H{
+			"error": "API Key无权访问该聊天室",
+		})
+		return
+	}
+
+	// 私有聊天室和私聊只有成员可以读取，机器人必须仍是聊天室成员
+	if err := h.roomService.CheckReadAccess(uint(roomID), middleware.GetUserID(c), middleware.GetAPIKey(c) != nil); err != nil {
+		c.JSON(postMessageErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	messageService := services.NewMessageService()
 	messages, err := messageService.GetMessagesByRoom(uint(roomID), limit, offset)
 	if err != nil {
@@ -240,3 +384,132 @@ func (h *RoomHandler) GetRoomMessages(ctx context.Context, c *app.RequestContext
 
 	response.Success(ctx, c, messages)
 }
+
+// PostMessage 通过REST接口发送文本消息，支持用户JWT和拥有messages:write权限的机器人API Key
+// 消息与WebSocket发送的消息一样持久化并广播给聊天室所有在线成员
+func (h *RoomHandler) PostMessage(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	var req requests.PostMessageRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// API Key只能向授权的聊天室发送消息
+	if key := middleware.GetAPIKey(c); key != nil && !key.AllowsRoom(uint(roomID)) {
+		c.JSON(http.StatusForbidden, utils.H{
+			"error": "API Key无权访问该聊天室",
+		})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	messageService := services.NewMessageService()
+	savedMessage, err := messageService.PostMessage(userID, uint(roomID), req.Content, req.ReplyToID)
+	if err != nil {
+		c.JSON(postMessageErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.hub.PublishMessage(savedMessage)
+
+	response.Success(ctx, c, ws.NewWSMessageFromEntity(savedMessage))
+}
+
+// GetThreadMessages 获取话题消息
+func (h *RoomHandler) GetThreadMessages(ctx context.Context, c *app.RequestContext) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的聊天室ID",
+		})
+		return
+	}
+
+	msgIDStr := c.Param("msgId")
+	msgID, err := strconv.ParseUint(msgIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的消息ID",
+		})
+		return
+	}
+
+	// 分页参数
+	limitStr := c.DefaultQuery("limit", "50")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, _ := strconv.Atoi(limitStr)
+	offset, _ := strconv.Atoi(offsetStr)
+
+	// 私有聊天室和私聊只有成员可以读取
+	if err := h.roomService.CheckReadAccess(uint(roomID), middleware.GetUserID(c), false); err != nil {
+		c.JSON(postMessageErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	messageService := services.NewMessageService()
+	root, replies, err := messageService.GetThread(uint(roomID), uint(msgID), limit, offset)
+	if err != nil {
+		if errors.Is(err, services.ErrMessageNotFound) {
+			c.JSON(http.StatusNotFound, utils.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, &responses.ThreadResponse{
+		Root:    root,
+		Replies: replies,
+	})
+}
+
+// roomErrorStatus 将聊天室管理错误转换为HTTP状态码
+func roomErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrRoomNoAuthority):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrDirectRoomManage), errors.Is(err, services.ErrTransferSelf),
+		errors.Is(err, services.ErrTransferNotMember):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// postMessageErrorStatus 将发送消息错误转换为HTTP状态码
+func postMessageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrRoomNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrNotRoomMember), errors.Is(err, services.ErrMemberMuted):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrReplyTargetInvalid):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gochat/internal/config"
+	"gochat/internal/middleware"
+	"gochat/internal/models/requests"
+	"gochat/internal/services"
+	"gochat/pkg/response"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// TwoFactorHandler 两步验证处理器
+type TwoFactorHandler struct {
+	twoFactorService *services.TwoFactorService
+}
+
+// NewTwoFactorHandler 创建两步验证处理器实例
+func NewTwoFactorHandler(cfg *config.Config) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: services.NewTwoFactorService(&cfg.Auth),
+	}
+}
+
+// GetStatus 获取当前用户的两步验证状态
+func (h *TwoFactorHandler) GetStatus(ctx context.Context, c *app.RequestContext) {
+	status, err := h.twoFactorService.GetStatus(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, status)
+}
+
+// Enroll 生成两步验证密钥、otpauth URI和恢复码
+func (h *TwoFactorHandler) Enroll(ctx context.Context, c *app.RequestContext) {
+	var req requests.TwoFactorEnrollRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	enrollment, err := h.twoFactorService.Enroll(middleware.GetUserID(c), req.Password)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, enrollment)
+}
+
+// Verify 验证验证码并启用两步验证
+func (h *TwoFactorHandler) Verify(ctx context.Context, c *app.RequestContext) {
+	var req requests.TwoFactorVerifyRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.twoFactorService.Verify(middleware.GetUserID(c), req.Code); err != nil {
+		c.JSON(twoFactorErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, "两步验证已启用")
+}
+
+// Disable 关闭两步验证
+func (h *TwoFactorHandler) Disable(ctx context.Context, c *app.RequestContext) {
+	var req requests.TwoFactorDisableRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.twoFactorService.Disable(middleware.GetUserID(c), req.Password, req.Code); err != nil {
+		c.JSON(twoFactorErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, "两步验证已关闭")
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(ctx context.Context, c *app.RequestContext) {
+	var req requests.TwoFactorEnrollRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(middleware.GetUserID(c), req.Password)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, utils.H{
+		"recovery_codes": codes,
+	})
+}
+
+// twoFactorErrorStatus 将两步验证错误转换为HTTP状态码
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrWrongPassword),
+		errors.Is(err, services.ErrTwoFactorCodeInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrTwoFactorEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrAccountLocked):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gochat/internal/config"
+	"gochat/internal/middleware"
+	"gochat/internal/models/requests"
+	"gochat/internal/services"
+	"gochat/pkg/response"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// 分片上传请求头
+const (
+	uploadOffsetHeader   = "X-Upload-Offset" // 分片在文件中的起始偏移量（字节）
+	uploadChecksumHeader = "X-Chunk-SHA256"  // 分片内容的SHA-256（十六进制）
+)
+
+// UploadHandler 分片上传处理器
+type UploadHandler struct {
+	uploadService *services.UploadService
+}
+
+// NewUploadHandler 创建分片上传处理器实例
+func NewUploadHandler(cfg *config.Config) *UploadHandler {
+	return &UploadHandler{
+		uploadService: services.NewUploadService(&cfg.Upload),
+	}
+}
+
+// CreateUpload 创建分片上传会话，响应中的chunk_size为每个分片的大小
+func (h *UploadHandler) CreateUpload(ctx context.Context, c *app.RequestContext) {
+	var req requests.CreateUploadRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	session, err := h.uploadService.CreateUpload(middleware.GetUserID(c), &req)
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, session)
+}
+
+// GetUpload 查询上传进度
+func (h *UploadHandler) GetUpload(ctx context.Context, c *app.RequestContext) {
+	session, err := h.uploadService.GetUpload(middleware.GetUserID(c), c.Param("id"))
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, session)
+}
+
+// UploadChunk 上传分片，请求体为分片原始内容
+func (h *UploadHandler) UploadChunk(ctx context.Context, c *app.RequestContext) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": services.ErrUploadChunkIndex.Error(),
+		})
+		return
+	}
+
+	offset, err := strconv.ParseInt(string(c.GetHeader(uploadOffsetHeader)), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": fmt.Sprintf("缺少或无效的%s请求头", uploadOffsetHeader),
+		})
+		return
+	}
+
+	checksum := string(c.GetHeader(uploadChecksumHeader))
+	if checksum == "" {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": fmt.Sprintf("缺少%s请求头", uploadChecksumHeader),
+		})
+		return
+	}
+
+	session, err := h.uploadService.UploadChunk(middleware.GetUserID(c), c.Param("id"), index, offset, checksum, c.Request.Body())
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, session)
+}
+
+// CompleteUpload 合并分片，返回生成的附件
+func (h *UploadHandler) CompleteUpload(ctx context.Context, c *app.RequestContext) {
+	attachment, err := h.uploadService.CompleteUpload(middleware.GetUserID(c), c.Param("id"))
+	if err != nil {
+		if status := uploadErrorStatus(err); status != http.StatusInternalServerError {
+			c.JSON(status, utils.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.H{
+			"error": fmt.Sprintf("文件上传失败: %v", err),
+		})
+		return
+	}
+
+	response.Success(ctx, c, attachment)
+}
+
+// AbortUpload 取消上传
+func (h *UploadHandler) AbortUpload(ctx context.Context, c *app.RequestContext) {
+	if err := h.uploadService.AbortUpload(middleware.GetUserID(c), c.Param("id")); err != nil {
+		c.JSON(uploadErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, utils.H{
+		"message": "上传已取消",
+	})
+}
+
+// uploadErrorStatus 将上传错误转换为HTTP状态码
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUploadSessionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrUploadCompleted), errors.Is(err, services.ErrUploadIncomplete):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrFileTypeUnsupported), errors.Is(err, services.ErrUploadChunkIndex),
+		errors.Is(err, services.ErrUploadChunkOffset), errors.Is(err, services.ErrUploadChunkSize),
+		errors.Is(err, services.ErrUploadChunkChecksum), errors.Is(err, services.ErrUploadChecksum):
+		return http.StatusBadRequest
+	default:
+		return fileErrorStatus(err)
+	}
+}
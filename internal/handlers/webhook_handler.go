@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gochat/internal/config"
+	"gochat/internal/middleware"
+	"gochat/internal/models/entities"
+	"gochat/internal/models/requests"
+	"gochat/internal/services"
+	"gochat/pkg/response"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// WebhookHandler 聊天室Webhook处理器
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandler 创建Webhook处理器实例
+func NewWebhookHandler(cfg *config.Config) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: services.NewWebhookService(&cfg.Webhook),
+	}
+}
+
+// CreateWebhook 创建Webhook（管理员及以上），响应中的secret只返回一次
+func (h *WebhookHandler) CreateWebhook(ctx context.Context, c *app.RequestContext) {
+	roomID, ok := parseUintParam(c, "id", "无效的聊天室ID")
+	if !ok {
+		return
+	}
+
+	var req requests.WebhookRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(middleware.GetUserID(c), roomID, &req)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, webhook)
+}
+
+// GetWebhooks 获取聊天室的Webhook列表
+func (h *WebhookHandler) GetWebhooks(ctx context.Context, c *app.RequestContext) {
+	roomID, ok := parseUintParam(c, "id", "无效的聊天室ID")
+	if !ok {
+		return
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(middleware.GetUserID(c), roomID)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, webhooks)
+}
+
+// UpdateWebhook 更新Webhook
+func (h *WebhookHandler) UpdateWebhook(ctx context.Context, c *app.RequestContext) {
+	roomID, ok := parseUintParam(c, "id", "无效的聊天室ID")
+	if !ok {
+		return
+	}
+	webhookID, ok := parseUintParam(c, "webhookId", "无效的Webhook ID")
+	if !ok {
+		return
+	}
+
+	var req requests.WebhookRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(middleware.GetUserID(c), roomID, webhookID, &req)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, webhook)
+}
+
+// DeleteWebhook 删除Webhook
+func (h *WebhookHandler) DeleteWebhook(ctx context.Context, c *app.RequestContext) {
+	roomID, ok := parseUintParam(c, "id", "无效的聊天室ID")
+	if !ok {
+		return
+	}
+	webhookID, ok := parseUintParam(c, "webhookId", "无效的Webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(middleware.GetUserID(c), roomID, webhookID); err != nil {
+		c.JSON(webhookErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, "Webhook已删除")
+}
+
+// PingWebhook 向Webhook投递一次测试事件
+func (h *WebhookHandler) PingWebhook(ctx context.Context, c *app.RequestContext) {
+	roomID, ok := parseUintParam(c, "id", "无效的聊天室ID")
+	if !ok {
+		return
+	}
+	webhookID, ok := parseUintParam(c, "webhookId", "无效的Webhook ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.PingWebhook(middleware.GetUserID(c), roomID, webhookID)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, delivery)
+}
+
+// GetDeliveries 获取Webhook投递记录（?status=pending|succeeded|dead&limit=50&offset=0）
+func (h *WebhookHandler) GetDeliveries(ctx context.Context, c *app.RequestContext) {
+	roomID, ok := parseUintParam(c, "id", "无效的聊天室ID")
+	if !ok {
+		return
+	}
+	webhookID, ok := parseUintParam(c, "webhookId", "无效的Webhook ID")
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", entities.WebhookDeliveryPending, entities.WebhookDeliverySucceeded, entities.WebhookDeliveryDead:
+	default:
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": "无效的投递状态",
+		})
+		return
+	}
+
+	// 分页参数
+	limitStr := c.DefaultQuery("limit", "50")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, _ := strconv.Atoi(limitStr)
+	offset, _ := strconv.Atoi(offsetStr)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(middleware.GetUserID(c), roomID, webhookID, status, limit, offset)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, deliveries)
+}
+
+// Redeliver 重新投递一条记录（通常用于死信）
+func (h *WebhookHandler) Redeliver(ctx context.Context, c *app.RequestContext) {
+	roomID, ok := parseUintParam(c, "id", "无效的聊天室ID")
+	if !ok {
+		return
+	}
+	webhookID, ok := parseUintParam(c, "webhookId", "无效的Webhook ID")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseUintParam(c, "deliveryId", "无效的投递记录ID")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.Redeliver(middleware.GetUserID(c), roomID, webhookID, deliveryID); err != nil {
+		c.JSON(webhookErrorStatus(err), utils.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response.Success(ctx, c, "已重新加入投递队列")
+}
+
+// parseUintParam 解析路径中的ID参数，失败时直接返回400
+func parseUintParam(c *app.RequestContext, name, errMsg string) (uint, bool) {
+	value, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{
+			"error": errMsg,
+		})
+		return 0, false
+	}
+	return uint(value), true
+}
+
+// webhookErrorStatus 将Webhook错误转换为HTTP状态码
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrWebhookNotFound),
+		errors.Is(err, services.ErrWebhookDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrRoomNoAuthority):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrWebhookURLInvalid), errors.Is(err, services.ErrWebhookURLPrivate),
+		errors.Is(err, services.ErrWebhookEventInvalid), errors.Is(err, services.ErrDirectRoomManage):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrWebhookDisabled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -3,11 +3,11 @@ package handlers
 import (
 	"context"
 	"gochat/pkg/logger"
-	"gochat/pkg/utils"
 	"net/http"
 	"strconv"
 
 	"gochat/internal/config"
+	"gochat/internal/services"
 	ws "gochat/internal/websocket"
 
 	"github.com/cloudwego/hertz/pkg/app"
@@ -62,8 +62,8 @@ func (h *WebSocketHandler) HandleWebSocket(ctx context.Context, c *app.RequestCo
 		return
 	}
 
-	// 验证token并获取用户信息
-	claims, err := utils.ParseToken(token, cfg.JWT.Secret)
+	// 验证token并获取用户信息（包括是否已被撤销）
+	claims, err := services.NewTokenService(&cfg.JWT).ParseAndValidate(token)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "无效的token",
@@ -79,6 +79,7 @@ func (h *WebSocketHandler) HandleWebSocket(ctx context.Context, c *app.RequestCo
 
 		// 创建客户端
 		client := ws.NewClient(claims.UserID, claims.Username, conn, h.hub)
+		client.SessionID = claims.SessionID
 		logger.Info("WebSocket客户端已创建:", claims.Username)
 
 		// 注册客户端到Hub
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"fmt"
+	"gochat/pkg/logger"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LogMailer 将邮件输出到日志，不实际发送
+type LogMailer struct{}
+
+// NewLogMailer 创建日志邮件发送器
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send 将邮件内容写入日志
+func (m *LogMailer) Send(msg *Message) error {
+	logger.Info("Mail to:", msg.To, "Subject:", msg.Subject, "Body:", msg.Body)
+	return nil
+}
+
+// FileMailer 将邮件追加写入本地文件，便于开发时查看
+type FileMailer struct {
+	path  string
+	from  string
+	mutex sync.Mutex
+}
+
+// NewFileMailer 创建文件邮件发送器
+func NewFileMailer(path, from string) *FileMailer {
+	return &FileMailer{
+		path: path,
+		from: from,
+	}
+}
+
+// Send 将邮件追加写入文件
+func (m *FileMailer) Send(msg *Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "Date: %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC1123Z), m.from, msg.To, msg.Subject, msg.Body)
+	return err
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"fmt"
+	"gochat/internal/config"
+)
+
+// 邮件发送方式
+const (
+	DriverLog  = "log"  // 输出到日志（开发环境）
+	DriverFile = "file" // 写入文件（开发和测试环境）
+	DriverSMTP = "smtp" // 通过SMTP服务器发送
+)
+
+// Message 邮件内容
+type Message struct {
+	To      string
+	Subject string
+	Body    string // 纯文本正文
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	// Send 发送邮件
+	Send(msg *Message) error
+}
+
+// New 根据配置创建邮件发送器
+func New(cfg *config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "", DriverLog:
+		return NewLogMailer(), nil
+	case DriverFile:
+		return NewFileMailer(cfg.FilePath, cfg.From), nil
+	case DriverSMTP:
+		return NewSMTPMailer(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported mail driver: %s", cfg.Driver)
+	}
+}
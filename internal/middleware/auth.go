@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"context"
+	"gochat/internal/config"
+	"gochat/internal/models/entities"
+	"gochat/internal/services"
 	"gochat/pkg/response"
 	"gochat/pkg/utils"
 	"strings"
@@ -10,7 +13,11 @@ import (
 )
 
 // AuthMiddleware JWT认证中间件
-func AuthMiddleware(jwtSecret string) app.HandlerFunc {
+// 传入apiKeyScopes时同时接受机器人的API Key，API Key必须拥有全部指定的权限范围；
+// 未传入时只接受用户JWT
+func AuthMiddleware(cfg *config.JWTConfig, apiKeyScopes ...string) app.HandlerFunc {
+	tokenService := services.NewTokenService(cfg)
+	apiKeyService := services.NewAPIKeyService()
 	return func(ctx context.Context, c *app.RequestContext) {
 		// 从请求头获取token
 		authHeader := string(c.GetHeader("Authorization"))
@@ -35,8 +42,39 @@ func AuthMiddleware(jwtSecret string) app.HandlerFunc {
 			return
 		}
 
-		// 验证token
-		claims, err := utils.ParseToken(tokenString, jwtSecret)
+		// 机器人API Key
+		if strings.HasPrefix(tokenString, services.APIKeyPrefix) {
+			if len(apiKeyScopes) == 0 {
+				response.Unauthorized(ctx, c, "该接口不支持API Key")
+				c.Abort()
+				return
+			}
+
+			key, bot, err := apiKeyService.Authenticate(tokenString)
+			if err != nil {
+				response.Unauthorized(ctx, c, "无效的API Key")
+				c.Abort()
+				return
+			}
+			for _, scope := range apiKeyScopes {
+				if !key.HasScope(scope) {
+					response.Forbidden(ctx, c, "API Key缺少权限: "+scope)
+					c.Abort()
+					return
+				}
+			}
+
+			c.Set("user_id", bot.ID)
+			c.Set("username", bot.Username)
+			c.Set("role", bot.Role)
+			c.Set("api_key", key)
+
+			c.Next(ctx)
+			return
+		}
+
+		// 验证token（包括是否已被撤销）
+		claims, err := tokenService.ParseAndValidate(tokenString)
 		if err != nil {
 			response.Unauthorized(ctx, c, "无效的token")
 			c.Abort()
@@ -46,6 +84,8 @@ func AuthMiddleware(jwtSecret string) app.HandlerFunc {
 		// 将用户信息存储到上下文中
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 		c.Set("token", tokenString)
 
 		c.Next(ctx)
@@ -60,6 +100,22 @@ func GetUserID(c *app.RequestContext) uint {
 	return 0
 }
 
+// GetClaims 从上下文获取token声明
+func GetClaims(c *app.RequestContext) *utils.JWTClaims {
+	if claims, exists := c.Get("claims"); exists {
+		return claims.(*utils.JWTClaims)
+	}
+	return nil
+}
+
+// GetAPIKey 从上下文获取机器人API Key，使用用户JWT认证时返回nil
+func GetAPIKey(c *app.RequestContext) *entities.APIKey {
+	if key, exists := c.Get("api_key"); exists {
+		return key.(*entities.APIKey)
+	}
+	return nil
+}
+
 // GetUsername 从上下文获取用户名
 func GetUsername(c *app.RequestContext) string {
 	if username, exists := c.Get("username"); exists {
@@ -69,7 +125,8 @@ func GetUsername(c *app.RequestContext) string {
 }
 
 // AuthMiddlewareWithQuery JWT认证中间件（支持查询参数）
-func AuthMiddlewareWithQuery(jwtSecret string) app.HandlerFunc {
+func AuthMiddlewareWithQuery(cfg *config.JWTConfig) app.HandlerFunc {
+	tokenService := services.NewTokenService(cfg)
 	return func(ctx context.Context, c *app.RequestContext) {
 		var tokenString string
 
@@ -88,8 +145,8 @@ func AuthMiddlewareWithQuery(jwtSecret string) app.HandlerFunc {
 			return
 		}
 
-		// 验证token
-		claims, err := utils.ParseToken(tokenString, jwtSecret)
+		// 验证token（包括是否已被撤销）
+		claims, err := tokenService.ParseAndValidate(tokenString)
 		if err != nil {
 			response.Unauthorized(ctx, c, "无效的token")
 			c.Abort()
@@ -99,6 +156,8 @@ func AuthMiddlewareWithQuery(jwtSecret string) app.HandlerFunc {
 		// 将用户信息存储到上下文中
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 		c.Set("token", tokenString)
 
 		c.Next(ctx)
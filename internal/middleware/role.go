@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"gochat/internal/models/entities"
+	"gochat/pkg/response"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// RequireRole 全局角色校验中间件，需在认证中间件之后使用
+// 角色等级不低于minRole的用户才能访问
+func RequireRole(minRole string) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		if entities.UserRoleLevel(GetRole(c)) < entities.UserRoleLevel(minRole) {
+			response.Forbidden(ctx, c, "权限不足")
+			c.Abort()
+			return
+		}
+
+		c.Next(ctx)
+	}
+}
+
+// GetRole 从上下文获取全局角色，旧token中没有角色时视为普通用户
+func GetRole(c *app.RequestContext) string {
+	if role, exists := c.Get("role"); exists {
+		if roleStr, ok := role.(string); ok && roleStr != "" {
+			return roleStr
+		}
+	}
+	return entities.UserRoleUser
+}
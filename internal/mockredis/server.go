@@ -0,0 +1,358 @@
+// Package mockredis 模拟Redis发布订阅服务，用于在开发和测试环境中验证多节点部署的redis广播总线
+//
+// 只实现广播总线用到的命令：PING、AUTH、SELECT、PUBLISH、SUBSCRIBE、UNSUBSCRIBE、QUIT，使用RESP2协议。
+// 设置密码时要求客户端先通过AUTH认证；数据库编号被忽略，所有连接共享同一组频道。
+package mockredis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	maxBulkLen  = 512 << 20 // 与Redis默认的proto-max-bulk-len一致
+	maxArrayLen = 1 << 20
+)
+
+var errProtocol = errors.New("protocol error")
+
+// Server 模拟的Redis服务
+type Server struct {
+	password string
+
+	mutex    sync.RWMutex
+	channels map[string]map[*conn]bool // 频道的订阅连接
+}
+
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+
+	writeMutex sync.Mutex
+	writer     *bufio.Writer
+
+	authed   bool
+	channels map[string]bool // 已订阅的频道，只在连接自己的协程中访问
+}
+
+// NewServer 创建模拟Redis服务，password为空时不要求认证
+func NewServer(password string) *Server {
+	return &Server{
+		password: password,
+		channels: make(map[string]map[*conn]bool),
+	}
+}
+
+// Serve 在listener上接受连接，直到listener关闭
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serve(netConn)
+	}
+}
+
+// serve 逐条读取并执行客户端命令，直到连接关闭
+func (s *Server) serve(netConn net.Conn) {
+	c := &conn{
+		netConn:  netConn,
+		reader:   bufio.NewReader(netConn),
+		writer:   bufio.NewWriter(netConn),
+		authed:   s.password == "",
+		channels: make(map[string]bool),
+	}
+	defer func() {
+		s.unsubscribeAll(c)
+		netConn.Close()
+	}()
+
+	for {
+		args, err := readCommand(c.reader)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.write(errorReply("ERR Protocol error"))
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if !s.execute(c, strings.ToUpper(args[0]), args[1:]) {
+			return
+		}
+	}
+}
+
+// execute 执行一条命令，返回false时关闭连接
+func (s *Server) execute(c *conn, name string, args []string) bool {
+	if name == "QUIT" {
+		c.write(simpleReply("OK"))
+		return false
+	}
+	if name == "AUTH" {
+		s.auth(c, args)
+		return true
+	}
+	if !c.authed {
+		c.write(errorReply("NOAUTH Authentication required."))
+		return true
+	}
+
+	// 订阅状态下只允许订阅相关命令和PING
+	if len(c.channels) > 0 {
+		switch name {
+		case "SUBSCRIBE", "UNSUBSCRIBE", "PING":
+		default:
+			c.write(errorReply(fmt.Sprintf("ERR Can't execute '%s': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context", strings.ToLower(name))))
+			return true
+		}
+	}
+
+	switch name {
+	case "PING":
+		s.ping(c, args)
+	case "SELECT":
+		if len(args) != 1 {
+			c.write(wrongArgs(name))
+		} else if _, err := strconv.Atoi(args[0]); err != nil {
+			c.write(errorReply("ERR value is not an integer or out of range"))
+		} else {
+			c.write(simpleReply("OK"))
+		}
+	case "PUBLISH":
+		if len(args) != 2 {
+			c.write(wrongArgs(name))
+			return true
+		}
+		c.write(integerReply(s.publish(args[0], args[1])))
+	case "SUBSCRIBE":
+		if len(args) == 0 {
+			c.write(wrongArgs(name))
+			return true
+		}
+		s.subscribe(c, args)
+	case "UNSUBSCRIBE":
+		s.unsubscribe(c, args)
+	default:
+		// HELLO、CLIENT等未实现的命令返回错误，go-redis会降级为RESP2并忽略客户端信息设置
+		c.write(errorReply(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(name))))
+	}
+	return true
+}
+
+func (s *Server) auth(c *conn, args []string) {
+	password := ""
+	switch len(args) {
+	case 1:
+		password = args[0]
+	case 2:
+		password = args[1] // AUTH username password
+	default:
+		c.write(wrongArgs("AUTH"))
+		return
+	}
+
+	if s.password == "" {
+		c.write(errorReply("ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?"))
+		return
+	}
+	if password != s.password {
+		c.write(errorReply("WRONGPASS invalid username-password pair or user is disabled."))
+		return
+	}
+	c.authed = true
+	c.write(simpleReply("OK"))
+}
+
+func (s *Server) ping(c *conn, args []string) {
+	if len(args) > 1 {
+		c.write(wrongArgs("PING"))
+		return
+	}
+	message := ""
+	if len(args) == 1 {
+		message = args[0]
+	}
+
+	// 订阅状态下PING以推送消息的格式回复
+	if len(c.channels) > 0 {
+		c.write(arrayReply(bulkReply("pong"), bulkReply(message)))
+		return
+	}
+	if len(args) == 1 {
+		c.write(bulkReply(message))
+		return
+	}
+	c.write(simpleReply("PONG"))
+}
+
+// publish 向频道的所有订阅者推送消息，返回收到消息的订阅者数量
+func (s *Server) publish(channel, payload string) int {
+	s.mutex.RLock()
+	subscribers := make([]*conn, 0, len(s.channels[channel]))
+	for subscriber := range s.channels[channel] {
+		subscribers = append(subscribers, subscriber)
+	}
+	s.mutex.RUnlock()
+
+	message := arrayReply(bulkReply("message"), bulkReply(channel), bulkReply(payload))
+	for _, subscriber := range subscribers {
+		subscriber.write(message)
+	}
+	return len(subscribers)
+}
+
+func (s *Server) subscribe(c *conn, channels []string) {
+	for _, channel := range channels {
+		if !c.channels[channel] {
+			c.channels[channel] = true
+			s.mutex.Lock()
+			if s.channels[channel] == nil {
+				s.channels[channel] = make(map[*conn]bool)
+			}
+			s.channels[channel][c] = true
+			s.mutex.Unlock()
+		}
+		c.write(arrayReply(bulkReply("subscribe"), bulkReply(channel), integerReply(len(c.channels))))
+	}
+}
+
+// unsubscribe 取消订阅，未指定频道时取消全部订阅
+func (s *Server) unsubscribe(c *conn, channels []string) {
+	if len(channels) == 0 {
+		for channel := range c.channels {
+			channels = append(channels, channel)
+		}
+		if len(channels) == 0 {
+			c.write(arrayReply(bulkReply("unsubscribe"), nullReply(), integerReply(0)))
+			return
+		}
+	}
+
+	for _, channel := range channels {
+		s.removeSubscriber(c, channel)
+		c.write(arrayReply(bulkReply("unsubscribe"), bulkReply(channel), integerReply(len(c.channels))))
+	}
+}
+
+func (s *Server) unsubscribeAll(c *conn) {
+	for channel := range c.channels {
+		s.removeSubscriber(c, channel)
+	}
+}
+
+func (s *Server) removeSubscriber(c *conn, channel string) {
+	delete(c.channels, channel)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.channels[channel], c)
+	if len(s.channels[channel]) == 0 {
+		delete(s.channels, channel)
+	}
+}
+
+// write 写入一条回复，订阅推送和命令回复可能来自不同协程
+func (c *conn) write(reply []byte) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if _, err := c.writer.Write(reply); err != nil {
+		return
+	}
+	if err := c.writer.Flush(); err != nil {
+		log.Printf("write to %s failed: %v", c.netConn.RemoteAddr(), err)
+	}
+}
+
+// readCommand 读取一条命令，支持RESP数组和内联命令（便于用telnet或redis-cli调试）
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, nil
+	}
+	if line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count > maxArrayLen {
+		return nil, errProtocol
+	}
+	args := make([]string, 0, max(count, 0))
+	for i := 0; i < count; i++ {
+		header, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if header == "" || header[0] != '$' {
+			return nil, errProtocol
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 || size > maxBulkLen {
+			return nil, errProtocol
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		if string(data[size:]) != "\r\n" {
+			return nil, errProtocol
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func simpleReply(s string) []byte {
+	return []byte("+" + s + "\r\n")
+}
+
+func errorReply(s string) []byte {
+	return []byte("-" + s + "\r\n")
+}
+
+func wrongArgs(name string) []byte {
+	return errorReply(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+func integerReply(n int) []byte {
+	return []byte(":" + strconv.Itoa(n) + "\r\n")
+}
+
+func bulkReply(s string) []byte {
+	return []byte("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func nullReply() []byte {
+	return []byte("$-1\r\n")
+}
+
+func arrayReply(items ...[]byte) []byte {
+	reply := []byte("*" + strconv.Itoa(len(items)) + "\r\n")
+	for _, item := range items {
+		reply = append(reply, item...)
+	}
+	return reply
+}
@@ -1,6 +1,8 @@
 package entities
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,6 +15,15 @@ type User struct {
 	Email        string         `gorm:"uniqueIndex;size:100;not null" json:"email"`
 	PasswordHash string         `gorm:"size:255;not null" json:"-"`
 	AvatarURL    string         `gorm:"size:255" json:"avatar_url"`
+	Role         string         `gorm:"size:20;default:'user';index" json:"role"` // user, admin, superadmin
+	TokenVersion int            `gorm:"default:0" json:"-"`                       // token版本，递增后已签发的token全部失效
+	FailedLogins int            `gorm:"default:0" json:"-"`                       // 连续登录失败次数
+	LockedUntil  *time.Time     `json:"-"`                                        // 登录锁定截止时间
+	TOTPSecret   string         `gorm:"size:64" json:"-"`                         // 两步验证密钥（未验证前为待启用状态）
+	TOTPEnabled  bool           `gorm:"default:false" json:"-"`                   // 是否已启用两步验证
+	TOTPCounter  int64          `gorm:"default:0" json:"-"`                       // 最近一次使用的验证码时间步，防止重放
+	IsBot        bool           `gorm:"default:false;index" json:"is_bot"`        // 是否为机器人账号（只能通过API Key调用）
+	BotOwnerID   *uint          `json:"bot_owner_id,omitempty"`                   // 创建机器人的管理员
 	IsOnline     bool           `gorm:"default:false" json:"is_online"`
 	LastSeen     *time.Time     `json:"last_seen"`
 	CreatedAt    time.Time      `json:"created_at"`
@@ -30,12 +41,33 @@ func (User) TableName() string {
 	return "users"
 }
 
+// 全局用户角色
+const (
+	UserRoleUser       = "user"       // 普通用户
+	UserRoleAdmin      = "admin"      // 系统管理员
+	UserRoleSuperAdmin = "superadmin" // 超级管理员
+)
+
+// UserRoleLevel 返回全局角色的权限等级，未知角色视为普通用户
+func UserRoleLevel(role string) int {
+	switch role {
+	case UserRoleSuperAdmin:
+		return 3
+	case UserRoleAdmin:
+		return 2
+	default:
+		return 1
+	}
+}
+
 // Room 聊天室模型
 type Room struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
 	Name        string         `gorm:"size:100;not null" json:"name"`
 	Description string         `gorm:"type:text" json:"description"`
 	IsPrivate   bool           `gorm:"default:false" json:"is_private"`
+	Kind        string         `gorm:"size:20;default:'group';index" json:"kind"` // group, direct
+	DirectKey   *string        `gorm:"size:64;uniqueIndex" json:"-"`              // 私聊唯一标识（仅私聊房间）
 	CreatedBy   uint           `gorm:"not null" json:"created_by"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
@@ -52,19 +84,28 @@ func (Room) TableName() string {
 	return "rooms"
 }
 
+// 聊天室类型
+const (
+	RoomKindGroup  = "group"  // 群聊
+	RoomKindDirect = "direct" // 一对一私聊
+)
+
 // Attachment 附件模型
 type Attachment struct {
-	ID        uint      `gorm:"primarykey" json:"id"`
-	MessageID *uint     `gorm:"index" json:"message_id"`             // 允许NULL，用于临时附件
-	FileName  string    `gorm:"size:255;not null" json:"file_name"`  // 原始文件名
-	FilePath  string    `gorm:"size:500;not null" json:"file_path"`  // 存储路径
-	FileSize  int64     `gorm:"not null" json:"file_size"`           // 文件大小（字节）
-	FileType  string    `gorm:"size:100;not null" json:"file_type"`  // MIME类型
-	Category  string    `gorm:"size:20;not null" json:"category"`    // image, file, video
-	Width     int       `gorm:"default:0" json:"width,omitempty"`    // 图片/视频宽度
-	Height    int       `gorm:"default:0" json:"height,omitempty"`   // 图片/视频高度
-	Duration  int       `gorm:"default:0" json:"duration,omitempty"` // 视频时长（秒）
-	CreatedAt time.Time `json:"created_at"`
+	ID         uint      `gorm:"primarykey" json:"id"`
+	MessageID  *uint     `gorm:"index" json:"message_id"`             // 允许NULL，用于临时附件
+	UploaderID uint      `gorm:"index" json:"uploader_id"`            // 上传者，早期上传的附件为0
+	RoomID     *uint     `gorm:"index" json:"room_id"`                // 所属聊天室，关联到消息时记录
+	FileName   string    `gorm:"size:255;not null" json:"file_name"`  // 原始文件名
+	FilePath   string    `gorm:"size:500;not null" json:"file_path"`  // 存储key（早期附件为本地路径）
+	FileSize   int64     `gorm:"not null" json:"file_size"`           // 文件大小（字节）
+	FileType   string    `gorm:"size:100;not null" json:"file_type"`  // MIME类型
+	Category   string    `gorm:"size:20;not null" json:"category"`    // image, file, video
+	Width      int       `gorm:"default:0" json:"width,omitempty"`    // 图片/视频宽度
+	Height     int       `gorm:"default:0" json:"height,omitempty"`   // 图片/视频高度
+	Duration   int       `gorm:"default:0" json:"duration,omitempty"` // 视频时长（秒）
+	Thumbnails string    `gorm:"size:50" json:"-"`                    // 已生成的缩略图尺寸（最长边像素），空格分隔
+	CreatedAt  time.Time `json:"created_at"`
 
 	// 关联关系
 	Message *Message `gorm:"foreignKey:MessageID" json:"message,omitempty"`
@@ -75,19 +116,78 @@ func (Attachment) TableName() string {
 	return "attachments"
 }
 
+// HasThumbnail 是否已生成指定尺寸的缩略图
+func (a *Attachment) HasThumbnail(size int) bool {
+	for _, s := range strings.Fields(a.Thumbnails) {
+		if s == strconv.Itoa(size) {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadSession 分片上传会话，所有分片上传后合并为附件
+type UploadSession struct {
+	ID           string    `gorm:"primarykey;size:32" json:"id"`
+	UploaderID   uint      `gorm:"not null;index" json:"uploader_id"`
+	MessageID    *uint     `json:"message_id"`                         // 合并后直接关联的消息，为空时生成临时附件
+	FileName     string    `gorm:"size:255;not null" json:"file_name"` // 原始文件名
+	FileSize     int64     `gorm:"not null" json:"file_size"`          // 文件总大小（字节）
+	ChunkSize    int64     `gorm:"not null" json:"chunk_size"`         // 分片大小（字节），最后一个分片可以更小
+	Checksum     string    `gorm:"size:64" json:"checksum"`            // 整个文件的SHA-256（十六进制），为空时合并后不校验
+	AttachmentID *uint     `json:"attachment_id"`                      // 合并完成后生成的附件
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UploadSession) TableName() string {
+	return "upload_sessions"
+}
+
+// TotalChunks 分片总数
+func (s *UploadSession) TotalChunks() int {
+	return int((s.FileSize + s.ChunkSize - 1) / s.ChunkSize)
+}
+
+// UploadChunk 已上传的分片
+type UploadChunk struct {
+	SessionID string    `gorm:"primarykey;size:32" json:"session_id"`
+	Index     int       `gorm:"primarykey;autoIncrement:false" json:"index"`
+	Size      int64     `gorm:"not null" json:"size"`
+	Checksum  string    `gorm:"size:64;not null" json:"checksum"` // 分片的SHA-256（十六进制）
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UploadChunk) TableName() string {
+	return "upload_chunks"
+}
+
 // Message 消息模型
 type Message struct {
-	ID          uint      `gorm:"primarykey" json:"id"`
-	RoomID      uint      `gorm:"not null;index" json:"room_id"`
-	UserID      uint      `gorm:"not null;index" json:"user_id"`
-	Content     string    `gorm:"type:text" json:"content"`                   // 文本内容，多媒体消息可为空
-	MessageType string    `gorm:"size:20;default:'text'" json:"message_type"` // text, image, file, video
-	CreatedAt   time.Time `json:"created_at"`
+	ID           uint           `gorm:"primarykey" json:"id"`
+	RoomID       uint           `gorm:"not null;index" json:"room_id"`
+	UserID       uint           `gorm:"not null;index;uniqueIndex:idx_user_client_msg" json:"user_id"`
+	ClientMsgID  *string        `gorm:"size:64;uniqueIndex:idx_user_client_msg" json:"client_msg_id,omitempty"` // 客户端消息ID，用于按用户去重
+	Content      string         `gorm:"type:text" json:"content"`                                               // 文本内容，多媒体消息可为空
+	MessageType  string         `gorm:"size:20;default:'text'" json:"message_type"`                             // text, image, file, video
+	ReplyToID    *uint          `gorm:"index" json:"reply_to_id,omitempty"`                                     // 回复/引用的消息ID
+	ThreadRootID *uint          `gorm:"index" json:"thread_root_id,omitempty"`                                  // 所属话题的根消息ID
+	ReplyCount   int            `gorm:"default:0" json:"reply_count"`                                           // 话题回复数（仅根消息）
+	LastReplyAt  *time.Time     `json:"last_reply_at,omitempty"`                                                // 话题最后回复时间（仅根消息）
+	CreatedAt    time.Time      `json:"created_at"`
+	EditedAt     *time.Time     `json:"edited_at,omitempty"` // 最后编辑时间，未编辑为NULL
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
 	// 关联关系
 	Room        Room         `gorm:"foreignKey:RoomID" json:"room,omitempty"`
 	User        User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Attachments []Attachment `gorm:"foreignKey:MessageID" json:"attachments,omitempty"`
+
+	// 聚合数据（不存储）
+	Reactions []ReactionSummary `gorm:"-" json:"reactions,omitempty"`
 }
 
 // TableName 指定表名
@@ -95,6 +195,30 @@ func (Message) TableName() string {
 	return "messages"
 }
 
+// 聊天室成员角色
+const (
+	RoomRoleOwner     = "owner"     // 群主
+	RoomRoleAdmin     = "admin"     // 管理员
+	RoomRoleModerator = "moderator" // 协管员
+	RoomRoleMember    = "member"    // 普通成员
+)
+
+// RoomRoleLevel 返回聊天室角色的权限等级，等级越高权限越大，非成员为0
+func RoomRoleLevel(role string) int {
+	switch role {
+	case RoomRoleOwner:
+		return 4
+	case RoomRoleAdmin:
+		return 3
+	case RoomRoleModerator:
+		return 2
+	case RoomRoleMember:
+		return 1
+	default:
+		return 0
+	}
+}
+
 // RoomMember 聊天室成员模型
 type RoomMember struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -125,3 +249,430 @@ func (rm *RoomMember) BeforeCreate(tx *gorm.DB) error {
 	rm.JoinedAt = time.Now()
 	return nil
 }
+
+// RoomReadState 用户在聊天室中的已读状态
+type RoomReadState struct {
+	ID                uint      `gorm:"primarykey" json:"id"`
+	RoomID            uint      `gorm:"not null;uniqueIndex:idx_room_user_read" json:"room_id"`
+	UserID            uint      `gorm:"not null;uniqueIndex:idx_room_user_read;index" json:"user_id"`
+	LastReadMessageID uint      `gorm:"not null;default:0" json:"last_read_message_id"` // 最后已读消息ID
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RoomReadState) TableName() string {
+	return "room_read_state"
+}
+
+// MessageReaction 消息表情回应
+type MessageReaction struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	MessageID uint      `gorm:"not null;uniqueIndex:idx_message_user_emoji" json:"message_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_message_user_emoji;index" json:"user_id"`
+	Emoji     string    `gorm:"size:32;not null;uniqueIndex:idx_message_user_emoji" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (MessageReaction) TableName() string {
+	return "message_reactions"
+}
+
+// ReactionSummary 消息表情回应聚合
+type ReactionSummary struct {
+	Emoji   string `json:"emoji"`    // 表情
+	Count   int    `json:"count"`    // 回应人数
+	UserIDs []uint `json:"user_ids"` // 回应的用户
+}
+
+// 聊天室处罚类型
+const (
+	SanctionBan  = "ban"  // 封禁
+	SanctionMute = "mute" // 禁言
+)
+
+// RoomSanction 聊天室处罚记录（封禁/禁言）
+type RoomSanction struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	RoomID    uint       `gorm:"not null;uniqueIndex:idx_room_user_sanction" json:"room_id"`
+	UserID    uint       `gorm:"not null;uniqueIndex:idx_room_user_sanction;index" json:"user_id"`
+	Type      string     `gorm:"size:20;not null;uniqueIndex:idx_room_user_sanction" json:"type"` // ban, mute
+	Reason    string     `gorm:"size:255" json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"` // 过期时间，NULL表示永久
+	CreatedBy uint       `gorm:"not null" json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// 关联关系
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName 指定表名
+func (RoomSanction) TableName() string {
+	return "room_sanctions"
+}
+
+// IsActive 处罚是否仍然生效
+func (s *RoomSanction) IsActive() bool {
+	return s.ExpiresAt == nil || s.ExpiresAt.After(time.Now())
+}
+
+// RoomInvite 聊天室邀请链接
+type RoomInvite struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	RoomID    uint       `gorm:"not null;index" json:"room_id"`
+	Token     string     `gorm:"size:64;not null;uniqueIndex" json:"token"`
+	CreatedBy uint       `gorm:"not null" json:"created_by"`
+	MaxUses   int        `gorm:"default:0" json:"max_uses"` // 最大使用次数，0表示不限
+	UseCount  int        `gorm:"default:0" json:"use_count"`
+	ExpiresAt *time.Time `json:"expires_at"` // 过期时间，NULL表示永不过期
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// 关联关系
+	Room Room `gorm:"foreignKey:RoomID" json:"room,omitempty"`
+}
+
+// TableName 指定表名
+func (RoomInvite) TableName() string {
+	return "room_invites"
+}
+
+// IsUsable 邀请是否仍可使用
+func (i *RoomInvite) IsUsable() bool {
+	if i.RevokedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && !i.ExpiresAt.After(time.Now()) {
+		return false
+	}
+	return i.MaxUses == 0 || i.UseCount < i.MaxUses
+}
+
+// 加入申请状态
+const (
+	JoinRequestPending  = "pending"  // 待审批
+	JoinRequestApproved = "approved" // 已同意
+	JoinRequestDenied   = "denied"   // 已拒绝
+)
+
+// RoomJoinRequest 聊天室加入申请
+type RoomJoinRequest struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	RoomID     uint       `gorm:"not null;index:idx_room_join_request" json:"room_id"`
+	UserID     uint       `gorm:"not null;index:idx_room_join_request" json:"user_id"`
+	Message    string     `gorm:"size:255" json:"message"`
+	Status     string     `gorm:"size:20;not null;default:'pending';index" json:"status"` // pending, approved, denied
+	ReviewedBy *uint      `json:"reviewed_by"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// 关联关系
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName 指定表名
+func (RoomJoinRequest) TableName() string {
+	return "room_join_requests"
+}
+
+// RefreshToken 刷新令牌（只保存摘要）
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	SessionID uint       `gorm:"index" json:"session_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// PasswordResetToken 重置密码令牌（只保存摘要，使用一次后失效）
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// RecoveryCode 两步验证恢复码（只保存摘要，每个只能使用一次）
+type RecoveryCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// LoginChallenge 两步验证登录挑战，密码验证通过后签发，换取正式令牌
+type LoginChallenge struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Attempts  int       `gorm:"default:0" json:"attempts"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (LoginChallenge) TableName() string {
+	return "login_challenges"
+}
+
+// UserIdentity 关联到本地用户的外部身份（OIDC身份提供方 + sub）
+type UserIdentity struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Provider    string     `gorm:"size:50;not null;uniqueIndex:idx_provider_subject" json:"provider"`
+	Subject     string     `gorm:"size:255;not null;uniqueIndex:idx_provider_subject" json:"subject"`
+	Email       string     `gorm:"size:100" json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// OIDCState OIDC授权请求状态，回调时校验state并取回PKCE校验码和nonce
+type OIDCState struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	StateHash    string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	BindingHash  string    `gorm:"size:64;not null" json:"-"` // 发起登录的浏览器Cookie中绑定值的摘要
+	Provider     string    `gorm:"size:50;not null" json:"provider"`
+	CodeVerifier string    `gorm:"size:128;not null" json:"-"`
+	Nonce        string    `gorm:"size:64;not null" json:"-"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (OIDCState) TableName() string {
+	return "oidc_states"
+}
+
+// UserSession 登录会话，每次登录创建一条记录，刷新令牌时延续
+type UserSession struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	DeviceName string     `gorm:"size:100" json:"device_name"`
+	UserAgent  string     `gorm:"size:255" json:"user_agent"`
+	IPAddress  string     `gorm:"size:64" json:"ip_address"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"` // 随刷新令牌延长
+	LastUsedAt time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// IsActive 会话是否仍然有效
+func (s *UserSession) IsActive() bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(time.Now())
+}
+
+// API Key权限范围
+const (
+	APIKeyScopeMessagesRead  = "messages:read"  // 读取聊天室消息
+	APIKeyScopeMessagesWrite = "messages:write" // 向聊天室发送消息
+)
+
+// APIKeyScopes 所有可授予的API Key权限范围
+var APIKeyScopes = []string{APIKeyScopeMessagesRead, APIKeyScopeMessagesWrite}
+
+// APIKey 机器人账号的长期API Key，只保存摘要，限定可访问的聊天室和权限范围
+type APIKey struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"` // 所属机器人账号
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"` // 明文前缀，便于识别
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"size:255;not null" json:"-"` // 空格分隔的权限范围
+	ExpiresAt  *time.Time `json:"expires_at"`                 // 为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedBy  uint       `gorm:"not null" json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	RoomIDs []uint `gorm:"-" json:"room_ids"` // 允许访问的聊天室，由api_key_rooms加载
+}
+
+// TableName 指定表名
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// IsActive API Key是否仍然有效
+func (k *APIKey) IsActive() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(time.Now()))
+}
+
+// ScopeList 返回权限范围列表
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// HasScope 是否拥有指定权限范围
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsRoom 是否允许访问指定聊天室
+func (k *APIKey) AllowsRoom(roomID uint) bool {
+	for _, id := range k.RoomIDs {
+		if id == roomID {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyRoom API Key与可访问聊天室的关联
+type APIKeyRoom struct {
+	APIKeyID uint `gorm:"primaryKey" json:"api_key_id"`
+	RoomID   uint `gorm:"primaryKey;index" json:"room_id"`
+}
+
+// TableName 指定表名
+func (APIKeyRoom) TableName() string {
+	return "api_key_rooms"
+}
+
+// Webhook事件类型
+const (
+	WebhookEventMessageCreated = "message.created" // 新消息
+	WebhookEventMessageEdited  = "message.edited"  // 消息被编辑
+	WebhookEventMessageDeleted = "message.deleted" // 消息被删除
+	WebhookEventMemberJoined   = "member.joined"   // 成员加入
+	WebhookEventMemberLeft     = "member.left"     // 成员离开（包括被踢出和封禁）
+	WebhookEventPing           = "ping"            // 测试投递，不可订阅
+)
+
+// WebhookEvents 可订阅的Webhook事件
+var WebhookEvents = []string{
+	WebhookEventMessageCreated,
+	WebhookEventMessageEdited,
+	WebhookEventMessageDeleted,
+	WebhookEventMemberJoined,
+	WebhookEventMemberLeft,
+}
+
+// Webhook 聊天室的外发Webhook，事件发生时向URL投递签名的JSON
+type Webhook struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	RoomID    uint      `gorm:"not null;index" json:"room_id"`
+	URL       string    `gorm:"size:500;not null" json:"url"`
+	Secret    string    `gorm:"size:64;not null" json:"-"`  // HMAC签名密钥
+	Events    string    `gorm:"size:255;not null" json:"-"` // 空格分隔的订阅事件
+	IsActive  bool      `gorm:"default:true" json:"is_active"`
+	CreatedBy uint      `gorm:"not null" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// EventList 返回订阅的事件列表
+func (w *Webhook) EventList() []string {
+	return strings.Fields(w.Events)
+}
+
+// Subscribes 是否订阅了指定事件
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook投递状态
+const (
+	WebhookDeliveryPending   = "pending"   // 等待投递或等待重试
+	WebhookDeliverySucceeded = "succeeded" // 投递成功
+	WebhookDeliveryDead      = "dead"      // 重试耗尽，保留为死信
+)
+
+// WebhookDelivery Webhook投递记录，同时作为重试队列和死信记录
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	WebhookID      uint       `gorm:"not null;index" json:"webhook_id"`
+	RoomID         uint       `gorm:"not null" json:"room_id"`
+	Event          string     `gorm:"size:50;not null" json:"event"`
+	Payload        string     `gorm:"type:text;not null" json:"-"`
+	Status         string     `gorm:"size:20;not null;index:idx_webhook_delivery_due,priority:1" json:"status"`
+	Attempts       int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_webhook_delivery_due,priority:2" json:"next_attempt_at"`
+	LockedUntil    *time.Time `json:"-"` // 投递中的租约，防止多个节点重复投递
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `gorm:"size:500" json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// IncomingWebhook 聊天室的传入Webhook，外部系统向 /hooks/:token 发送Slack格式的消息，以集成机器人身份发到聊天室
+type IncomingWebhook struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	RoomID      uint       `gorm:"not null;index" json:"room_id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"` // 发送消息的集成机器人账号
+	Name        string     `gorm:"size:100" json:"name"`
+	TokenHash   string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	TokenPrefix string     `gorm:"size:16;not null" json:"token_prefix"` // 明文前缀，便于识别
+	CreatedBy   uint       `gorm:"not null" json:"created_by"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (IncomingWebhook) TableName() string {
+	return "incoming_webhooks"
+}
+
+// RevokedToken 已撤销的访问令牌（jti黑名单），过期后可清理
+type RevokedToken struct {
+	JTI       string    `gorm:"primarykey;size:64" json:"jti"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
@@ -2,13 +2,67 @@ package requests
 
 // RegisterRequest 注册请求结构
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username   string `json:"username" binding:"required,min=3,max=50"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required,min=6"`
+	DeviceName string `json:"device_name"` // 设备名称（可选）
 }
 
 // LoginRequest 登录请求结构
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name"` // 设备名称（可选），显示在登录设备列表中
+}
+
+// RefreshTokenRequest 刷新令牌请求结构
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest 退出登录请求结构
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"` // 是否退出所有设备
+}
+
+// ChangePasswordRequest 修改密码请求结构
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+	DeviceName  string `json:"device_name"` // 当前设备名称（可选），用于重新签发的登录会话
+}
+
+// ForgotPasswordRequest 申请重置密码请求结构
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest 重置密码请求结构
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// TwoFactorLoginRequest 两步验证登录请求结构
+type TwoFactorLoginRequest struct {
+	PendingToken string `json:"pending_token" binding:"required"`
+	Code         string `json:"code" binding:"required"` // TOTP验证码或恢复码
+	DeviceName   string `json:"device_name"`             // 设备名称（可选）
+}
+
+// TwoFactorEnrollRequest 生成两步验证密钥请求结构
+type TwoFactorEnrollRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// TwoFactorVerifyRequest 启用两步验证请求结构
+type TwoFactorVerifyRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorDisableRequest 关闭两步验证请求结构
+type TwoFactorDisableRequest struct {
 	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP验证码或恢复码
 }
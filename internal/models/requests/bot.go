@@ -0,0 +1,15 @@
+package requests
+
+// CreateBotRequest 创建机器人账号请求
+type CreateBotRequest struct {
+	Username  string `json:"username" binding:"required,min=3,max=50" vd:"len($)>=3 && len($)<=50; msg:'用户名长度应在3-50字符之间'"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// CreateAPIKeyRequest 创建API Key请求
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name" binding:"required" vd:"len($)>0 && len($)<=100; msg:'名称长度应在1-100字符之间'"`
+	Scopes    []string `json:"scopes" binding:"required" vd:"len($)>0; msg:'至少需要一个权限范围'"`
+	RoomIDs   []uint   `json:"room_ids" binding:"required" vd:"len($)>0; msg:'至少需要一个聊天室'"`
+	ExpiresIn int64    `json:"expires_in" vd:"$>=0; msg:'有效期不能为负数'"` // 有效期（秒），0表示永不过期
+}
@@ -0,0 +1,9 @@
+package requests
+
+// CreateUploadRequest 创建分片上传会话请求
+type CreateUploadRequest struct {
+	FileName  string `json:"file_name" binding:"required" vd:"len($)>0 && len($)<=255; msg:'文件名长度应在1-255字符之间'"`
+	FileSize  int64  `json:"file_size" binding:"required" vd:"$>0; msg:'文件大小必须大于0'"`
+	Checksum  string `json:"checksum" vd:"len($)==0 || regexp('^[0-9a-fA-F]{64}$'); msg:'校验和应为64位十六进制SHA-256'"` // 整个文件的SHA-256，可选
+	MessageID uint   `json:"message_id"`                                                                        // 合并后直接关联的消息，可选
+}
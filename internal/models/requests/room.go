@@ -17,3 +17,50 @@ type UpdateRoomRequest struct {
 type JoinRoomRequest struct {
 	RoomID uint `json:"room_id" binding:"required" vd:"$>0; msg:'聊天室ID必须大于0'"`
 }
+
+// SanctionRequest 封禁/禁言请求
+type SanctionRequest struct {
+	Reason   string `json:"reason" vd:"len($)<=255; msg:'原因不能超过255字符'"`
+	Duration int64  `json:"duration" vd:"$>=0 && $<=315360000; msg:'时长必须在0到315360000秒（10年）之间'"` // 时长（秒），0表示永久
+}
+
+// UpdateMemberRoleRequest 调整成员角色请求
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" binding:"required" vd:"$=='admin' || $=='moderator' || $=='member'; msg:'角色必须是admin、moderator或member'"`
+}
+
+// TransferRoomRequest 转让群主请求
+type TransferRoomRequest struct {
+	UserID uint `json:"user_id" binding:"required" vd:"$>0; msg:'用户ID必须大于0'"`
+}
+
+// CreateInviteRequest 创建邀请链接请求
+type CreateInviteRequest struct {
+	MaxUses   int   `json:"max_uses" vd:"$>=0; msg:'最大使用次数不能为负数'"` // 0表示不限次数
+	ExpiresIn int64 `json:"expires_in" vd:"$>=0; msg:'有效期不能为负数'"`  // 有效期（秒），0表示永不过期
+}
+
+// JoinRequestRequest 申请加入聊天室请求
+type JoinRequestRequest struct {
+	Message string `json:"message" vd:"len($)<=255; msg:'申请留言不能超过255字符'"`
+}
+
+// PostMessageRequest 通过REST接口发送消息请求
+type PostMessageRequest struct {
+	Content   string `json:"content" binding:"required" vd:"len($)>0; msg:'消息内容不能为空'"`
+	ReplyToID uint   `json:"reply_to_id"` // 回复/引用的消息ID
+}
+
+// WebhookRequest 创建/更新Webhook请求
+type WebhookRequest struct {
+	URL      string   `json:"url" binding:"required" vd:"len($)>0 && len($)<=500; msg:'URL长度应在1-500字符之间'"`
+	Events   []string `json:"events" binding:"required" vd:"len($)>0; msg:'至少需要订阅一个事件'"`
+	IsActive *bool    `json:"is_active"` // 为空时默认启用（创建）或保持不变（更新）
+}
+
+// CreateIncomingWebhookRequest 创建传入Webhook请求
+type CreateIncomingWebhookRequest struct {
+	Name      string `json:"name" vd:"len($)<=100; msg:'名称不能超过100字符'"`
+	Username  string `json:"username" binding:"required" vd:"len($)>=3 && len($)<=50; msg:'集成机器人用户名长度应在3-50字符之间'"`
+	AvatarURL string `json:"avatar_url"`
+}
@@ -0,0 +1,77 @@
+package requests
+
+import "encoding/json"
+
+// SlackMessage Slack incoming-webhook格式的消息
+// username、icon_emoji、icon_url、channel等字段被忽略，消息始终以集成机器人身份发到Webhook所属的聊天室
+type SlackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []SlackAttachment `json:"attachments"`
+	Blocks      []SlackBlock      `json:"blocks"`
+}
+
+// SlackAttachment Slack旧版消息附件
+type SlackAttachment struct {
+	Fallback   string       `json:"fallback"`
+	Pretext    string       `json:"pretext"`
+	AuthorName string       `json:"author_name"`
+	AuthorLink string       `json:"author_link"`
+	Title      string       `json:"title"`
+	TitleLink  string       `json:"title_link"`
+	Text       string       `json:"text"`
+	Fields     []SlackField `json:"fields"`
+	ImageURL   string       `json:"image_url"`
+	Footer     string       `json:"footer"`
+	Blocks     []SlackBlock `json:"blocks"`
+}
+
+// SlackField 附件中的字段
+type SlackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// SlackBlock Block Kit布局块（支持section、header、context、divider、image、actions）
+type SlackBlock struct {
+	Type     string         `json:"type"`
+	Text     *SlackText     `json:"text"`
+	Fields   []SlackText    `json:"fields"`
+	Elements []SlackElement `json:"elements"`
+	ImageURL string         `json:"image_url"`
+	AltText  string         `json:"alt_text"`
+	Title    *SlackText     `json:"title"`
+}
+
+// SlackText Block Kit文本对象
+type SlackText struct {
+	Type string `json:"type"` // plain_text, mrkdwn
+	Text string `json:"text"`
+}
+
+// SlackElement context和actions块中的元素
+type SlackElement struct {
+	Type     string        `json:"type"`
+	Text     SlackFlexText `json:"text"` // context中为字符串，按钮中为文本对象
+	AltText  string        `json:"alt_text"`
+	ImageURL string        `json:"image_url"`
+	URL      string        `json:"url"`
+}
+
+// SlackFlexText 兼容字符串和文本对象两种写法的文本
+type SlackFlexText string
+
+// UnmarshalJSON 解析字符串或 {"type": "...", "text": "..."} 文本对象
+func (t *SlackFlexText) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*t = SlackFlexText(text)
+		return nil
+	}
+
+	var object SlackText
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	*t = SlackFlexText(object.Text)
+	return nil
+}
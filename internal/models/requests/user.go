@@ -4,3 +4,8 @@ package requests
 type UpdateProfileRequest struct {
 	AvatarURL string `json:"avatar_url"`
 }
+
+// UpdateUserRoleRequest 调整用户全局角色请求
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required" vd:"$=='user' || $=='admin' || $=='superadmin'; msg:'角色必须是user、admin或superadmin'"`
+}
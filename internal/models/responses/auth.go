@@ -4,8 +4,12 @@ import "time"
 
 // AuthResponse 认证响应结构
 type AuthResponse struct {
-	User  *UserInfo `json:"user"`
-	Token string    `json:"token"`
+	User             *UserInfo `json:"user"`
+	Token            string    `json:"token"`              // 访问令牌
+	ExpiresAt        time.Time `json:"expires_at"`         // 访问令牌过期时间
+	RefreshToken     string    `json:"refresh_token"`      // 刷新令牌
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"` // 刷新令牌过期时间
+	SessionID        uint      `json:"session_id"`         // 登录会话ID
 }
 
 // UserInfo 用户信息结构
@@ -14,6 +18,7 @@ type UserInfo struct {
 	Username  string `json:"username"`
 	Email     string `json:"email"`
 	AvatarURL string `json:"avatar_url"`
+	Role      string `json:"role,omitempty"` // 全局角色（仅当前用户信息返回）
 }
 
 // UserProfile 用户资料响应
@@ -26,3 +31,53 @@ type UserProfile struct {
 	LastSeen  *time.Time `json:"last_seen"`
 	CreatedAt time.Time  `json:"created_at"`
 }
+
+// AdminUserInfo 管理后台用户信息
+type AdminUserInfo struct {
+	ID        uint       `json:"id"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	Role      string     `json:"role"`
+	IsOnline  bool       `json:"is_online"`
+	LastSeen  *time.Time `json:"last_seen"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at"` // 禁用时间，为空表示正常
+}
+
+// TwoFactorChallenge 需要两步验证时的登录响应，使用pending_token和验证码换取正式令牌
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	PendingToken      string    `json:"pending_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// TwoFactorEnrollment 两步验证注册信息（恢复码只显示一次）
+type TwoFactorEnrollment struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"uri"` // otpauth URI，可生成二维码供验证器应用扫描
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatus 两步验证状态
+type TwoFactorStatus struct {
+	Enabled           bool  `json:"enabled"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+// SessionInfo 登录会话信息
+type SessionInfo struct {
+	ID         uint      `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // 是否为当前请求使用的会话
+}
+
+// AdminUserList 管理后台用户列表
+type AdminUserList struct {
+	Users []*AdminUserInfo `json:"users"`
+	Total int64            `json:"total"`
+}
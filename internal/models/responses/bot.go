@@ -0,0 +1,32 @@
+package responses
+
+import "time"
+
+// BotInfo 机器人账号信息
+type BotInfo struct {
+	ID         uint       `json:"id"`
+	Username   string     `json:"username"`
+	AvatarURL  string     `json:"avatar_url"`
+	BotOwnerID *uint      `json:"bot_owner_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DeletedAt  *time.Time `json:"deleted_at"` // 禁用时间，为空表示正常
+}
+
+// APIKeyInfo API Key信息（不包含明文）
+type APIKeyInfo struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	RoomIDs    []uint     `json:"room_ids"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyCreated 新创建的API Key，明文只返回一次
+type APIKeyCreated struct {
+	APIKeyInfo
+	Key string `json:"key"`
+}
@@ -0,0 +1,16 @@
+package responses
+
+import "time"
+
+// UploadSessionInfo 分片上传会话及进度
+type UploadSessionInfo struct {
+	ID             string    `json:"id"`
+	FileName       string    `json:"file_name"`
+	FileSize       int64     `json:"file_size"`
+	ChunkSize      int64     `json:"chunk_size"`
+	TotalChunks    int       `json:"total_chunks"`
+	ReceivedChunks []int     `json:"received_chunks"` // 已上传的分片序号
+	ReceivedBytes  int64     `json:"received_bytes"`
+	AttachmentID   *uint     `json:"attachment_id"` // 合并完成后生成的附件
+	ExpiresAt      time.Time `json:"expires_at"`
+}
@@ -0,0 +1,22 @@
+package responses
+
+import "gochat/internal/models/entities"
+
+// RoomListItem 聊天室列表项
+type RoomListItem struct {
+	*entities.Room
+	UnreadCount int64 `json:"unread_count"` // 未读消息数量
+}
+
+// ThreadResponse 话题消息响应
+type ThreadResponse struct {
+	Root    *entities.Message   `json:"root"`    // 话题根消息
+	Replies []*entities.Message `json:"replies"` // 回复列表
+}
+
+// DirectRoomItem 私聊列表项
+type DirectRoomItem struct {
+	*entities.Room
+	Peer        *UserInfo `json:"peer"`         // 私聊对象
+	UnreadCount int64     `json:"unread_count"` // 未读消息数量
+}
@@ -0,0 +1,65 @@
+package responses
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookInfo Webhook信息（不包含签名密钥）
+type WebhookInfo struct {
+	ID        uint      `json:"id"`
+	RoomID    uint      `json:"room_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	IsActive  bool      `json:"is_active"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookCreated 新创建的Webhook，签名密钥只返回一次
+type WebhookCreated struct {
+	WebhookInfo
+	Secret string `json:"secret"`
+}
+
+// WebhookDeliveryInfo Webhook投递记录
+type WebhookDeliveryInfo struct {
+	ID             uint            `json:"id"`
+	WebhookID      uint            `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"` // pending, succeeded, dead
+	Attempts       int             `json:"attempts"`
+	LastStatusCode int             `json:"last_status_code"`
+	LastError      string          `json:"last_error"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"` // 仅pending状态
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+// WebhookDeliveryList Webhook投递记录分页列表
+type WebhookDeliveryList struct {
+	Deliveries []*WebhookDeliveryInfo `json:"deliveries"`
+	Total      int64                  `json:"total"`
+}
+
+// IncomingWebhookInfo 传入Webhook信息（不包含令牌）
+type IncomingWebhookInfo struct {
+	ID          uint       `json:"id"`
+	RoomID      uint       `json:"room_id"`
+	Name        string     `json:"name"`
+	UserID      uint       `json:"user_id"`  // 集成机器人ID
+	Username    string     `json:"username"` // 集成机器人用户名，消息以此身份发送
+	TokenPrefix string     `json:"token_prefix"`
+	CreatedBy   uint       `json:"created_by"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// IncomingWebhookCreated 新创建的传入Webhook，令牌和URL只返回一次
+type IncomingWebhookCreated struct {
+	IncomingWebhookInfo
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"gochat/internal/config"
 	"gochat/internal/handlers"
+	"gochat/internal/mailer"
 	"gochat/internal/middleware"
+	"gochat/internal/models/entities"
 	ws "gochat/internal/websocket"
 	"io/ioutil"
 	"path/filepath"
@@ -26,12 +28,28 @@ func SetupRouter(addr string, wsHub *ws.Hub) *server.Hertz {
 		panic("Failed to load config: " + err.Error())
 	}
 
+	// 初始化邮件发送器
+	mail, err := mailer.New(&cfg.Mail)
+	if err != nil {
+		panic("Failed to create mailer: " + err.Error())
+	}
+
 	// 初始化处理器
-	authHandler := handlers.NewAuthHandler(&cfg.JWT)
+	authHandler := handlers.NewAuthHandler(cfg, mail, wsHub)
+	twoFactorHandler := handlers.NewTwoFactorHandler(cfg)
+	oidcHandler := handlers.NewOIDCHandler(cfg, mail)
 	userHandler := handlers.NewUserHandler()
-	roomHandler := handlers.NewRoomHandler()
+	roomHandler := handlers.NewRoomHandler(wsHub)
 	wsHandler := handlers.NewWebSocketHandler(wsHub)
-	fileHandler := handlers.NewFileHandler()
+	fileHandler := handlers.NewFileHandler(cfg)
+	uploadHandler := handlers.NewUploadHandler(cfg)
+	directHandler := handlers.NewDirectHandler(wsHub)
+	moderationHandler := handlers.NewModerationHandler(wsHub)
+	inviteHandler := handlers.NewInviteHandler(wsHub)
+	adminHandler := handlers.NewAdminHandler(wsHub)
+	botHandler := handlers.NewBotHandler()
+	webhookHandler := handlers.NewWebhookHandler(cfg)
+	incomingWebhookHandler := handlers.NewIncomingWebhookHandler(wsHub)
 
 	// API 路由组
 	api := h.Group("/api")
@@ -39,47 +57,124 @@ func SetupRouter(addr string, wsHub *ws.Hub) *server.Hertz {
 	// 公开路由
 	api.POST("/auth/register", authHandler.Register)
 	api.POST("/auth/login", authHandler.Login)
+	api.POST("/auth/2fa/login", authHandler.LoginTwoFactor)
+	api.GET("/auth/oidc/:provider/login", oidcHandler.Login)
+	api.GET("/auth/oidc/:provider/callback", oidcHandler.Callback)
+	api.POST("/auth/refresh", authHandler.Refresh)
+	api.POST("/auth/password/forgot", authHandler.ForgotPassword)
+	api.POST("/auth/password/reset", authHandler.ResetPassword)
+
+	// 传入Webhook（Slack兼容，URL中的令牌即凭证）
+	h.POST("/hooks/:token", incomingWebhookHandler.PostHook)
 
 	// WebSocket 连接路由（需要token验证）
 	h.GET("/ws", wsHandler.HandleWebSocket)
 
 	// 需要认证的路由
 	protected := api.Group("/")
-	protected.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+	protected.Use(middleware.AuthMiddleware(&cfg.JWT))
 
 	// 认证相关路由
 	protected.GET("/auth/userinfo", authHandler.GetUserInfo)
+	protected.POST("/auth/logout", authHandler.Logout)
+	protected.POST("/auth/password", authHandler.ChangePassword)
+	protected.GET("/auth/2fa", twoFactorHandler.GetStatus)
+	protected.POST("/auth/2fa/enroll", twoFactorHandler.Enroll)
+	protected.POST("/auth/2fa/verify", twoFactorHandler.Verify)
+	protected.POST("/auth/2fa/disable", twoFactorHandler.Disable)
+	protected.POST("/auth/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+	protected.GET("/auth/sessions", authHandler.GetSessions)
+	protected.DELETE("/auth/sessions/:id", authHandler.DeleteSession)
 
 	// 用户相关路由
 	protected.GET("/users/:id", userHandler.GetProfile)
 	protected.PUT("/users/:id", userHandler.UpdateProfile)
 	protected.GET("/users/online", userHandler.GetOnlineUsers)
-	protected.GET("/users/delete/:id", userHandler.DeleteUser)
 
 	// 聊天室相关路由
 	protected.POST("/rooms", roomHandler.CreateRoom)
 	protected.GET("/rooms", roomHandler.GetRooms)
 	protected.GET("/rooms/:id", roomHandler.GetRoom)
+	protected.PUT("/rooms/:id", roomHandler.UpdateRoom)
+	protected.DELETE("/rooms/:id", roomHandler.DeleteRoom)
+	protected.POST("/rooms/:id/transfer", roomHandler.TransferRoom)
 	protected.GET("/rooms/search", roomHandler.GetRoomsByBlurName)
 	protected.POST("/rooms/:id/join", roomHandler.JoinRoom)
 	protected.POST("/rooms/:id/leave", roomHandler.LeaveRoom)
 	protected.GET("/rooms/:id/members", roomHandler.GetRoomMembers)
-	protected.GET("/rooms/:id/messages", roomHandler.GetRoomMessages)
-
-	// WebSocket 管理路由
-	protected.GET("/ws/stats", wsHandler.GetStats)
-	protected.POST("/ws/broadcast/:roomId", wsHandler.BroadcastToRoom)
+	protected.GET("/rooms/:id/messages/:msgId/thread", roomHandler.GetThreadMessages)
+
+	// 聊天室消息路由（同时接受机器人API Key）
+	api.GET("/rooms/:id/messages", middleware.AuthMiddleware(&cfg.JWT, entities.APIKeyScopeMessagesRead), roomHandler.GetRoomMessages)
+	api.POST("/rooms/:id/messages", middleware.AuthMiddleware(&cfg.JWT, entities.APIKeyScopeMessagesWrite), roomHandler.PostMessage)
+
+	// 聊天室管理路由
+	protected.GET("/rooms/:id/sanctions", moderationHandler.GetSanctions)
+	protected.POST("/rooms/:id/members/:userId/kick", moderationHandler.KickMember)
+	protected.POST("/rooms/:id/members/:userId/ban", moderationHandler.BanMember)
+	protected.DELETE("/rooms/:id/members/:userId/ban", moderationHandler.UnbanMember)
+	protected.POST("/rooms/:id/members/:userId/mute", moderationHandler.MuteMember)
+	protected.DELETE("/rooms/:id/members/:userId/mute", moderationHandler.UnmuteMember)
+	protected.PUT("/rooms/:id/members/:userId/role", moderationHandler.UpdateMemberRole)
+
+	// 邀请与加入申请路由
+	protected.POST("/rooms/:id/invites", inviteHandler.CreateInvite)
+	protected.GET("/rooms/:id/invites", inviteHandler.GetInvites)
+	protected.DELETE("/rooms/:id/invites/:inviteId", inviteHandler.RevokeInvite)
+	protected.POST("/invites/:token/accept", inviteHandler.AcceptInvite)
+	protected.POST("/rooms/:id/join-requests", inviteHandler.CreateJoinRequest)
+	protected.GET("/rooms/:id/join-requests", inviteHandler.GetJoinRequests)
+	protected.POST("/rooms/:id/join-requests/:requestId/approve", inviteHandler.ApproveJoinRequest)
+	protected.POST("/rooms/:id/join-requests/:requestId/deny", inviteHandler.DenyJoinRequest)
+
+	// Webhook路由
+	protected.GET("/rooms/:id/webhooks", webhookHandler.GetWebhooks)
+	protected.POST("/rooms/:id/webhooks", webhookHandler.CreateWebhook)
+	protected.PUT("/rooms/:id/webhooks/:webhookId", webhookHandler.UpdateWebhook)
+	protected.DELETE("/rooms/:id/webhooks/:webhookId", webhookHandler.DeleteWebhook)
+	protected.POST("/rooms/:id/webhooks/:webhookId/ping", webhookHandler.PingWebhook)
+	protected.GET("/rooms/:id/webhooks/:webhookId/deliveries", webhookHandler.GetDeliveries)
+	protected.POST("/rooms/:id/webhooks/:webhookId/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+	protected.GET("/rooms/:id/incoming-webhooks", incomingWebhookHandler.GetIncomingWebhooks)
+	protected.POST("/rooms/:id/incoming-webhooks", incomingWebhookHandler.CreateIncomingWebhook)
+	protected.DELETE("/rooms/:id/incoming-webhooks/:hookId", incomingWebhookHandler.DeleteIncomingWebhook)
+
+	// 私聊相关路由
+	protected.GET("/dm", directHandler.GetDirectRooms)
+	protected.POST("/dm/:userId", directHandler.OpenDirectRoom)
+
+	// 管理后台路由（需要系统管理员角色）
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(&cfg.JWT), middleware.RequireRole(entities.UserRoleAdmin))
+	admin.GET("/ws/stats", wsHandler.GetStats)
+	admin.POST("/ws/broadcast/:roomId", wsHandler.BroadcastToRoom)
+	admin.GET("/users", adminHandler.ListUsers)
+	admin.GET("/users/disabled", adminHandler.GetDisabledUsers)
+	admin.POST("/users/:id/disable", adminHandler.DisableUser)
+	admin.POST("/users/:id/restore", adminHandler.RestoreUser)
+	admin.PUT("/users/:id/role", adminHandler.UpdateUserRole)
+	admin.GET("/bots", botHandler.ListBots)
+	admin.POST("/bots", botHandler.CreateBot)
+	admin.GET("/bots/:id/keys", botHandler.GetKeys)
+	admin.POST("/bots/:id/keys", botHandler.CreateKey)
+	admin.DELETE("/bots/:id/keys/:keyId", botHandler.RevokeKey)
 
 	// 文件相关路由
 	protected.POST("/files/upload", fileHandler.UploadFile)
+	protected.POST("/files/uploads", uploadHandler.CreateUpload)
+	protected.GET("/files/uploads/:id", uploadHandler.GetUpload)
+	protected.PUT("/files/uploads/:id/chunks/:index", uploadHandler.UploadChunk)
+	protected.POST("/files/uploads/:id/complete", uploadHandler.CompleteUpload)
+	protected.DELETE("/files/uploads/:id", uploadHandler.AbortUpload)
 	protected.GET("/files/:id", fileHandler.GetFileInfo)
 	protected.DELETE("/files/:id", fileHandler.DeleteFile)
 
 	// 文件下载和预览路由（支持查询参数认证）
 	fileAuth := h.Group("/api/files")
-	fileAuth.Use(middleware.AuthMiddlewareWithQuery(cfg.JWT.Secret))
+	fileAuth.Use(middleware.AuthMiddlewareWithQuery(&cfg.JWT))
 	fileAuth.GET("/:id/download", fileHandler.DownloadFile)
 	fileAuth.GET("/:id/preview", fileHandler.PreviewFile)
+	fileAuth.GET("/:id/thumbnail", fileHandler.GetThumbnail)
 
 	// 静态文件服务 - 手动处理
 	h.GET("/web/*filepath", func(ctx context.Context, c *app.RequestContext) {
@@ -107,8 +202,8 @@ func SetupRouter(addr string, wsHub *ws.Hub) *server.Hertz {
 		c.Data(200, "text/html; charset=utf-8", content)
 	})
 
-	// 上传文件静态访问
-	h.Static("/uploads", "./uploads")
+	// 上传文件访问（需认证，且只能访问有权限的附件）
+	h.GET("/uploads/*filepath", middleware.AuthMiddlewareWithQuery(&cfg.JWT), fileHandler.ServeUpload)
 
 	// 根路径重定向到web页面
 	h.GET("/", func(ctx context.Context, c *app.RequestContext) {
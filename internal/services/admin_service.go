@@ -0,0 +1,157 @@
+package services
+
+import (
+	"errors"
+	"gochat/internal/dal"
+	"gochat/internal/models/entities"
+	"gochat/internal/models/responses"
+
+	"gorm.io/gorm"
+)
+
+// 管理后台错误
+var (
+	ErrAdminNoAuthority = errors.New("无权限操作该用户")
+	ErrAdminSelf        = errors.New("不能对自己执行该操作")
+)
+
+// AdminService 管理后台服务
+type AdminService struct {
+	userDAL  *dal.UserDAL
+	tokenDAL *dal.TokenDAL
+}
+
+// NewAdminService 创建管理后台服务实例
+func NewAdminService() *AdminService {
+	return &AdminService{
+		userDAL:  dal.NewUserDAL(),
+		tokenDAL: dal.NewTokenDAL(),
+	}
+}
+
+// ListUsers 分页获取用户列表，includeDeleted为true时包括已禁用的用户
+func (s *AdminService) ListUsers(includeDeleted bool, limit, offset int) (*responses.AdminUserList, error) {
+	users, total, err := s.userDAL.List(includeDeleted, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &responses.AdminUserList{
+		Users: make([]*responses.AdminUserInfo, 0, len(users)),
+		Total: total,
+	}
+	for i := range users {
+		list.Users = append(list.Users, newAdminUserInfo(&users[i]))
+	}
+	return list, nil
+}
+
+// GetDisabledUsers 获取已禁用的用户列表
+func (s *AdminService) GetDisabledUsers() ([]*responses.AdminUserInfo, error) {
+	users, err := s.userDAL.GetDeletedUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*responses.AdminUserInfo, 0, len(users))
+	for i := range users {
+		items = append(items, newAdminUserInfo(&users[i]))
+	}
+	return items, nil
+}
+
+// DisableUser 禁用用户（软删除），只能操作角色低于自己的用户
+// 同时撤销该用户已签发的所有令牌
+func (s *AdminService) DisableUser(operatorID uint, operatorRole string, userID uint) error {
+	if _, err := s.checkAuthority(operatorID, operatorRole, userID); err != nil {
+		return err
+	}
+	if err := s.userDAL.IncrementTokenVersion(userID); err != nil {
+		return err
+	}
+	if err := s.tokenDAL.RevokeUserRefreshTokens(userID); err != nil {
+		return err
+	}
+	return s.userDAL.SoftDeleteByID(userID)
+}
+
+// RestoreUser 恢复已禁用的用户，只能操作角色低于自己的用户
+func (s *AdminService) RestoreUser(operatorID uint, operatorRole string, userID uint) error {
+	if _, err := s.checkAuthority(operatorID, operatorRole, userID); err != nil {
+		return err
+	}
+	return s.userDAL.RestoreByID(userID)
+}
+
+// SetUserRole 调整用户全局角色，只能授予低于自己的角色（超级管理员可以授予任意角色）
+// 角色变化时撤销该用户已签发的所有令牌，需重新登录；返回值changed表示角色是否发生变化
+func (s *AdminService) SetUserRole(operatorID uint, operatorRole string, userID uint, role string) (*responses.AdminUserInfo, bool, error) {
+	user, err := s.checkAuthority(operatorID, operatorRole, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	operatorLevel := entities.UserRoleLevel(operatorRole)
+	if operatorRole != entities.UserRoleSuperAdmin && entities.UserRoleLevel(role) >= operatorLevel {
+		return nil, false, ErrAdminNoAuthority
+	}
+
+	if user.Role == role {
+		return newAdminUserInfo(user), false, nil
+	}
+
+	// 令牌中携带角色，撤销已签发的令牌使新角色立即生效
+	if err := s.userDAL.IncrementTokenVersion(userID); err != nil {
+		return nil, false, err
+	}
+	if err := s.tokenDAL.RevokeUserRefreshTokens(userID); err != nil {
+		return nil, false, err
+	}
+	if err := s.userDAL.UpdateRole(userID, role); err != nil {
+		return nil, false, err
+	}
+
+	user.Role = role
+	return newAdminUserInfo(user), true, nil
+}
+
+// checkAuthority 检查操作者是否可以管理目标用户，返回目标用户（包括已禁用的）
+func (s *AdminService) checkAuthority(operatorID uint, operatorRole string, userID uint) (*entities.User, error) {
+	if operatorID == userID {
+		return nil, ErrAdminSelf
+	}
+
+	user, err := s.userDAL.GetByIDWithDeleted(userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if entities.UserRoleLevel(operatorRole) <= entities.UserRoleLevel(user.Role) {
+		return nil, ErrAdminNoAuthority
+	}
+	return user, nil
+}
+
+// newAdminUserInfo 转换为管理后台用户信息
+func newAdminUserInfo(user *entities.User) *responses.AdminUserInfo {
+	info := &responses.AdminUserInfo{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      user.Role,
+		IsOnline:  user.IsOnline,
+		LastSeen:  user.LastSeen,
+		CreatedAt: user.CreatedAt,
+	}
+	if info.Role == "" {
+		info.Role = entities.UserRoleUser
+	}
+	if user.DeletedAt.Valid {
+		deletedAt := user.DeletedAt.Time
+		info.DeletedAt = &deletedAt
+	}
+	return info
+}
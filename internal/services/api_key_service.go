@@ -0,0 +1,352 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gochat/internal/dal"
+	"gochat/internal/models/entities"
+	"gochat/internal/models/responses"
+	"gochat/pkg/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// API Key参数
+const (
+	APIKeyPrefix          = "gck_"         // API Key明文前缀，认证中间件据此区分API Key和JWT
+	apiKeyBytes           = 24             // API Key随机部分字节数
+	apiKeyDisplayPrefix   = 12             // 保存的明文前缀长度，便于在列表中识别
+	apiKeyTouchInterval   = time.Minute    // 最后使用时间的更新间隔
+	botEmailDomain        = "bots.invalid" // 机器人账号的占位邮箱域名
+	botPasswordTokenBytes = 32             // 机器人账号随机密码字节数（不用于登录）
+)
+
+// 机器人与API Key错误
+var (
+	ErrBotNotFound       = errors.New("机器人不存在")
+	ErrBotUsernameExists = errors.New("用户名已存在")
+	ErrAPIKeyNotFound    = errors.New("API Key不存在")
+	ErrAPIKeyInvalid     = errors.New("无效的API Key")
+	ErrAPIKeyScope       = errors.New("无效的权限范围")
+	ErrAPIKeyRoom        = errors.New("API Key只能授权给已存在的非私聊聊天室")
+)
+
+// APIKeyService 机器人账号与API Key服务
+type APIKeyService struct {
+	userDAL     *dal.UserDAL
+	apiKeyDAL   *dal.APIKeyDAL
+	roomService *RoomService
+
+	touchMu   sync.Mutex
+	lastTouch map[uint]time.Time
+}
+
+// NewAPIKeyService 创建API Key服务实例
+func NewAPIKeyService() *APIKeyService {
+	return &APIKeyService{
+		userDAL:     dal.NewUserDAL(),
+		apiKeyDAL:   dal.NewAPIKeyDAL(),
+		roomService: NewRoomService(),
+		lastTouch:   make(map[uint]time.Time),
+	}
+}
+
+// CreateBot 创建机器人账号，机器人不能登录，只能通过API Key调用接口
+func (s *APIKeyService) CreateBot(operatorID uint, username, avatarURL string) (*responses.BotInfo, error) {
+	bot, err := s.newBot(operatorID, username, avatarURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userDAL.Create(bot); err != nil {
+		return nil, err
+	}
+
+	return newBotInfo(bot), nil
+}
+
+// newBot 检查用户名并构建机器人账号（未保存）
+func (s *APIKeyService) newBot(operatorID uint, username, avatarURL string) (*entities.User, error) {
+	exists, err := s.userDAL.CheckUsernameExists(username)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrBotUsernameExists
+	}
+
+	// 随机密码不会告知任何人，机器人无法通过密码登录
+	password, err := utils.GenerateRandomToken(botPasswordTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.User{
+		Username:     username,
+		Email:        fmt.Sprintf("%s@%s", strings.ToLower(username), botEmailDomain),
+		PasswordHash: hashedPassword,
+		AvatarURL:    avatarURL,
+		Role:         entities.UserRoleUser,
+		IsBot:        true,
+		BotOwnerID:   &operatorID,
+	}, nil
+}
+
+// ListBots 获取所有机器人账号
+func (s *APIKeyService) ListBots() ([]*responses.BotInfo, error) {
+	bots, err := s.userDAL.ListBots()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*responses.BotInfo, 0, len(bots))
+	for i := range bots {
+		items = append(items, newBotInfo(&bots[i]))
+	}
+	return items, nil
+}
+
+// CreateKey 为机器人创建API Key，机器人会自动加入授权的聊天室
+// 明文只在创建时返回一次，数据库中只保存摘要
+func (s *APIKeyService) CreateKey(operatorID, botID uint, name string, scopes []string, roomIDs []uint, expiresIn int64) (*responses.APIKeyCreated, error) {
+	if _, err := s.getBot(botID); err != nil {
+		return nil, err
+	}
+
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	roomIDs = uniqueIDs(roomIDs)
+	if len(roomIDs) == 0 {
+		return nil, ErrAPIKeyRoom
+	}
+	for _, roomID := range roomIDs {
+		room, err := s.roomService.GetRoomByID(roomID)
+		if err != nil || room.Kind == entities.RoomKindDirect {
+			return nil, ErrAPIKeyRoom
+		}
+		banned, err := s.roomService.IsBanned(roomID, botID)
+		if err != nil {
+			return nil, err
+		}
+		if banned {
+			return nil, ErrRoomBanned
+		}
+	}
+
+	random, err := utils.GenerateRandomToken(apiKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	rawKey := APIKeyPrefix + random
+
+	key := &entities.APIKey{
+		UserID:    botID,
+		Name:      name,
+		Prefix:    rawKey[:apiKeyDisplayPrefix],
+		KeyHash:   utils.HashToken(rawKey),
+		Scopes:    strings.Join(scopes, " "),
+		CreatedBy: operatorID,
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(expiresIn) * time.Second)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.apiKeyDAL.CreateWithRooms(key, roomIDs); err != nil {
+		return nil, err
+	}
+
+	// 机器人需要是聊天室成员，消息才能像普通成员一样广播和持久化
+	for _, roomID := range roomIDs {
+		if err := s.roomService.ensureMember(roomID, botID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &responses.APIKeyCreated{
+		APIKeyInfo: *newAPIKeyInfo(key),
+		Key:        rawKey,
+	}, nil
+}
+
+// ListKeys 获取机器人的API Key列表
+func (s *APIKeyService) ListKeys(botID uint) ([]*responses.APIKeyInfo, error) {
+	if _, err := s.getBot(botID); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.apiKeyDAL.GetByUser(botID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*responses.APIKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, newAPIKeyInfo(key))
+	}
+	return items, nil
+}
+
+// RevokeKey 撤销机器人的API Key，立即生效
+func (s *APIKeyService) RevokeKey(botID, keyID uint) error {
+	revoked, err := s.apiKeyDAL.Revoke(botID, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate 校验API Key，返回API Key和所属的机器人账号
+// 已撤销、已过期或机器人已被禁用时返回ErrAPIKeyInvalid
+func (s *APIKeyService) Authenticate(rawKey string) (*entities.APIKey, *entities.User, error) {
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	key, err := s.apiKeyDAL.GetByHash(utils.HashToken(rawKey))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, ErrAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+	if !key.IsActive() {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	bot, err := s.userDAL.GetByID(key.UserID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, ErrAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+	if !bot.IsBot {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	s.touch(key.ID)
+
+	return key, bot, nil
+}
+
+// touch 按间隔更新API Key最后使用时间，避免每次请求都写库
+func (s *APIKeyService) touch(keyID uint) {
+	now := time.Now()
+
+	s.touchMu.Lock()
+	if last, ok := s.lastTouch[keyID]; ok && now.Sub(last) < apiKeyTouchInterval {
+		s.touchMu.Unlock()
+		return
+	}
+	s.lastTouch[keyID] = now
+	s.touchMu.Unlock()
+
+	_ = s.apiKeyDAL.Touch(keyID)
+}
+
+// getBot 获取机器人账号，普通用户视为不存在
+func (s *APIKeyService) getBot(botID uint) (*entities.User, error) {
+	bot, err := s.userDAL.GetByID(botID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrBotNotFound
+		}
+		return nil, err
+	}
+	if !bot.IsBot {
+		return nil, ErrBotNotFound
+	}
+	return bot, nil
+}
+
+// normalizeScopes 校验并去重权限范围
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !isValidScope(scope) {
+			return nil, ErrAPIKeyScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, ErrAPIKeyScope
+	}
+	return result, nil
+}
+
+// isValidScope 判断是否为可授予的权限范围
+func isValidScope(scope string) bool {
+	for _, s := range entities.APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueIDs 去除重复和为0的ID，保持原有顺序
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+// newBotInfo 构建机器人账号信息
+func newBotInfo(bot *entities.User) *responses.BotInfo {
+	info := &responses.BotInfo{
+		ID:         bot.ID,
+		Username:   bot.Username,
+		AvatarURL:  bot.AvatarURL,
+		BotOwnerID: bot.BotOwnerID,
+		CreatedAt:  bot.CreatedAt,
+	}
+	if bot.DeletedAt.Valid {
+		deletedAt := bot.DeletedAt.Time
+		info.DeletedAt = &deletedAt
+	}
+	return info
+}
+
+// newAPIKeyInfo 构建API Key信息
+func newAPIKeyInfo(key *entities.APIKey) *responses.APIKeyInfo {
+	roomIDs := key.RoomIDs
+	if roomIDs == nil {
+		roomIDs = []uint{}
+	}
+	return &responses.APIKeyInfo{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		RoomIDs:    roomIDs,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
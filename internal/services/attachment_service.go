@@ -1,40 +1,70 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"gochat/internal/dal"
 	"gochat/internal/models/entities"
+	"gochat/internal/storage"
 	"gochat/pkg/logger"
+	"gochat/pkg/utils"
 	"io"
+	"mime"
 	"mime/multipart"
-	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// 附件错误
+var (
+	ErrAttachmentNotFound        = errors.New("附件不存在")
+	ErrAttachmentForbidden       = errors.New("无权访问该附件")
+	ErrAttachmentDeleteForbidden = errors.New("只有上传者或聊天室管理员可以删除附件")
+	ErrAttachmentFileNotFound    = errors.New("文件不存在")
+	ErrThumbnailSize             = errors.New("无效的缩略图尺寸")
+	ErrThumbnailUnsupported      = errors.New("该文件类型没有缩略图")
+)
+
+// ThumbnailSizes 图片上传时生成的缩略图尺寸（最长边像素），从小到大
+var ThumbnailSizes = []int{256, 1024}
+
+// legacyUploadPrefix 早期附件的FilePath保存的是 ./uploads 下的本地路径，去掉该前缀即为存储key
+const legacyUploadPrefix = "uploads/"
+
+// 存储后端后台操作的超时参数
+const (
+	storageBaseTimeout       = 30 * time.Second
+	storageMinBytesPerSecond = 256 << 10 // 按256KB/s的最低速率为大文件追加时长
 )
 
 // AttachmentService 附件服务
 type AttachmentService struct {
 	attachmentDAL *dal.AttachmentDAL
-	uploadDir     string
+	messageDAL    *dal.MessageDAL
+	roomService   *RoomService
+	store         storage.BlobStore
 }
 
-// NewAttachmentService 创建附件服务实例
+// NewAttachmentService 创建附件服务实例，文件保存在 storage.Store 配置的存储后端
 func NewAttachmentService() *AttachmentService {
-	uploadDir := "./uploads"
-	// 确保上传目录存在
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		logger.Error("Failed to create upload directory:", err)
-	}
-
 	return &AttachmentService{
 		attachmentDAL: dal.NewAttachmentDAL(),
-		uploadDir:     uploadDir,
+		messageDAL:    dal.NewMessageDAL(),
+		roomService:   NewRoomService(),
+		store:         storage.Store,
 	}
 }
 
-// UploadFile 上传文件
-func (s *AttachmentService) UploadFile(fileHeader *multipart.FileHeader, messageID uint) (*entities.Attachment, error) {
+// UploadFile 上传文件，messageID不为0时直接关联到上传者自己的消息
+func (s *AttachmentService) UploadFile(fileHeader *multipart.FileHeader, uploaderID, messageID uint) (*entities.Attachment, error) {
 	// 打开上传的文件
 	file, err := fileHeader.Open()
 	if err != nil {
@@ -42,44 +72,44 @@ func (s *AttachmentService) UploadFile(fileHeader *multipart.FileHeader, message
 	}
 	defer file.Close()
 
-	// 生成唯一的文件名
-	ext := filepath.Ext(fileHeader.Filename)
-	fileName := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), strings.ReplaceAll(fileHeader.Filename, ext, ""), ext)
-
-	// 根据文件类型创建子目录
-	category := s.getCategoryFromFileName(fileHeader.Filename)
-	subDir := filepath.Join(s.uploadDir, category)
-	if err := os.MkdirAll(subDir, 0755); err != nil {
-		return nil, fmt.Errorf("无法创建子目录: %v", err)
-	}
-
-	// 完整的文件路径
-	filePath := filepath.Join(subDir, fileName)
+	return s.saveFile(file, fileHeader.Filename, fileHeader.Size, uploaderID, messageID)
+}
 
-	// 创建目标文件
-	dst, err := os.Create(filePath)
+// saveFile 将文件写入存储后端并创建附件记录，普通上传和分片上传合并后共用
+func (s *AttachmentService) saveFile(file io.ReadSeeker, originalName string, size int64, uploaderID, messageID uint) (*entities.Attachment, error) {
+	// 只能为自己发送的消息上传附件，附件随消息归属到对应聊天室
+	roomID, err := s.messageRoomID(messageID, uploaderID)
 	if err != nil {
-		return nil, fmt.Errorf("无法创建目标文件: %v", err)
+		return nil, err
 	}
-	defer dst.Close()
 
-	// 复制文件内容
-	size, err := io.Copy(dst, file)
-	if err != nil {
-		return nil, fmt.Errorf("文件复制失败: %v", err)
-	}
+	// 生成唯一的文件名
+	baseName := filepath.Base(originalName)
+	ext := filepath.Ext(baseName)
+	fileName := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), strings.ReplaceAll(baseName, ext, ""), ext)
+
+	// 按文件类型分目录存放
+	category := s.getCategoryFromFileName(originalName)
+	key := path.Join(category, fileName)
 
 	// 获取文件MIME类型
-	fileType := s.getFileType(fileHeader.Filename)
+	fileType := s.getFileType(originalName)
+
+	// 写入存储后端
+	if err := s.putBlob(key, file, size, fileType); err != nil {
+		return nil, fmt.Errorf("文件保存失败: %v", err)
+	}
 
 	// 创建附件记录
 	attachment := &entities.Attachment{
-		MessageID: nil, // 先创建为临时附件，稍后会关联到实际消息
-		FileName:  fileHeader.Filename,
-		FilePath:  filePath,
-		FileSize:  size,
-		FileType:  fileType,
-		Category:  category,
+		MessageID:  nil, // 先创建为临时附件，稍后会关联到实际消息
+		UploaderID: uploaderID,
+		RoomID:     roomID,
+		FileName:   originalName,
+		FilePath:   key,
+		FileSize:   size,
+		FileType:   fileType,
+		Category:   category,
 	}
 
 	// 如果提供了有效的 messageID，则直接关联
@@ -87,16 +117,88 @@ func (s *AttachmentService) UploadFile(fileHeader *multipart.FileHeader, message
 		attachment.MessageID = &messageID
 	}
 
-	// 如果是图片或视频，可以在这里获取宽高信息
-	// 这里先简化处理，后续可以添加图片处理库
+	// 图片读取宽高并生成缩略图，无法识别的图片仍按普通文件保存
 	if category == "image" {
-		// TODO: 获取图片宽高
+		s.processImage(file, attachment)
 	} else if category == "video" {
 		// TODO: 获取视频宽高和时长
 	}
 
-	// 保存到数据库
-	return s.attachmentDAL.Create(attachment)
+	// 保存到数据库，失败时清理已写入的文件
+	saved, err := s.attachmentDAL.Create(attachment)
+	if err != nil {
+		s.deleteBlobs(attachment)
+		return nil, err
+	}
+	return saved, nil
+}
+
+// messageRoomID 检查消息属于上传者并返回消息所在聊天室，messageID为0时返回nil
+func (s *AttachmentService) messageRoomID(messageID, uploaderID uint) (*uint, error) {
+	if messageID == 0 {
+		return nil, nil
+	}
+
+	message, err := s.messageDAL.GetByID(messageID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+	if message.UserID != uploaderID {
+		return nil, ErrAttachmentForbidden
+	}
+	return &message.RoomID, nil
+}
+
+// processImage 读取图片宽高，并为超过缩略图尺寸的图片生成缩略图
+// 从大到小依次缩放，较小的缩略图基于上一级结果生成
+func (s *AttachmentService) processImage(file io.ReadSeeker, attachment *entities.Attachment) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		logger.Error("Failed to rewind uploaded image:", err)
+		return
+	}
+
+	config, _, err := utils.DecodeImageConfig(file)
+	if err != nil {
+		logger.Warn("Unrecognized image:", attachment.FileName, "error:", err)
+		return
+	}
+	attachment.Width = config.Width
+	attachment.Height = config.Height
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		logger.Error("Failed to rewind uploaded image:", err)
+		return
+	}
+	img, _, err := utils.DecodeImage(file)
+	if err != nil {
+		logger.Warn("Failed to decode image for thumbnails:", attachment.FileName, "error:", err)
+		return
+	}
+
+	var generated []string
+	for i := len(ThumbnailSizes) - 1; i >= 0; i-- {
+		size := ThumbnailSizes[i]
+		if attachment.Width <= size && attachment.Height <= size {
+			continue
+		}
+
+		img = utils.ResizeToFit(img, size)
+		blob := thumbnailBlob(attachment, size)
+		data, err := utils.EncodeThumbnail(img, blob.ContentType == "image/jpeg")
+		if err != nil {
+			logger.Error("Failed to encode thumbnail:", err)
+			continue
+		}
+		if err := s.putBlob(blob.Key, bytes.NewReader(data), int64(len(data)), blob.ContentType); err != nil {
+			logger.Error("Failed to save thumbnail:", blob.Key, "error:", err)
+			continue
+		}
+		generated = append([]string{strconv.Itoa(size)}, generated...)
+	}
+	attachment.Thumbnails = strings.Join(generated, " ")
 }
 
 // GetAttachment 获取附件
@@ -104,63 +206,305 @@ func (s *AttachmentService) GetAttachment(attachmentID uint) (*entities.Attachme
 	return s.attachmentDAL.GetByID(attachmentID)
 }
 
+// GetAttachmentForUser 获取用户有权访问的附件
+// 已关联消息的附件要求用户是消息所在聊天室的成员，临时附件只有上传者可以访问
+func (s *AttachmentService) GetAttachmentForUser(attachmentID, userID uint) (*entities.Attachment, error) {
+	attachment, err := s.attachmentDAL.GetByID(attachmentID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	if err := s.checkReadAccess(attachment, userID); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// GetAttachmentByPathForUser 根据存储key获取用户有权访问的附件（用于 /uploads 路径访问）
+func (s *AttachmentService) GetAttachmentByPathForUser(relPath string, userID uint) (*entities.Attachment, error) {
+	key := strings.TrimPrefix(path.Clean("/"+relPath), "/")
+
+	attachment, err := s.attachmentDAL.GetByFilePath(key)
+	if err == gorm.ErrRecordNotFound {
+		attachment, err = s.attachmentDAL.GetByFilePath(legacyUploadPrefix + key)
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	if err := s.checkReadAccess(attachment, userID); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// AttachmentBlob 附件在存储后端中的一个对象（原文件或缩略图）
+type AttachmentBlob struct {
+	Key         string
+	ContentType string
+	FileName    string
+}
+
+// OriginalBlob 获取附件原文件
+func (s *AttachmentService) OriginalBlob(attachment *entities.Attachment) *AttachmentBlob {
+	return &AttachmentBlob{
+		Key:         storageKey(attachment),
+		ContentType: attachment.FileType,
+		FileName:    attachment.FileName,
+	}
+}
+
+// ThumbnailBlob 获取图片附件指定尺寸的缩略图
+// 图片本身不超过该尺寸或早期上传没有缩略图时返回原图
+func (s *AttachmentService) ThumbnailBlob(attachment *entities.Attachment, size int) (*AttachmentBlob, error) {
+	if attachment.Category != "image" {
+		return nil, ErrThumbnailUnsupported
+	}
+	if !isThumbnailSize(size) {
+		return nil, ErrThumbnailSize
+	}
+	if !attachment.HasThumbnail(size) {
+		return s.OriginalBlob(attachment), nil
+	}
+	return thumbnailBlob(attachment, size), nil
+}
+
+// StatBlob 获取对象元信息（大小、修改时间和ETag）
+func (s *AttachmentService) StatBlob(ctx context.Context, blob *AttachmentBlob) (*storage.BlobInfo, error) {
+	info, err := s.store.Stat(ctx, blob.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrAttachmentFileNotFound
+		}
+		return nil, err
+	}
+	return info, nil
+}
+
+// OpenBlob 从存储后端读取对象，调用方负责关闭返回的Reader
+func (s *AttachmentService) OpenBlob(ctx context.Context, blob *AttachmentBlob) (io.ReadCloser, *storage.BlobInfo, error) {
+	reader, info, err := s.store.Get(ctx, blob.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, ErrAttachmentFileNotFound
+		}
+		return nil, nil, err
+	}
+	return reader, info, nil
+}
+
+// OpenBlobRange 从存储后端读取对象从offset开始的length字节，调用方负责关闭返回的Reader
+func (s *AttachmentService) OpenBlobRange(ctx context.Context, blob *AttachmentBlob, offset, length int64) (io.ReadCloser, *storage.BlobInfo, error) {
+	reader, info, err := s.store.GetRange(ctx, blob.Key, offset, length)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, ErrAttachmentFileNotFound
+		}
+		return nil, nil, err
+	}
+	return reader, info, nil
+}
+
+// PresignBlob 生成对象的预签名下载地址，存储后端不支持预签名时返回空字符串
+// download为true时响应以附件形式下载，否则内联展示
+func (s *AttachmentService) PresignBlob(ctx context.Context, blob *AttachmentBlob, download bool) (string, error) {
+	opts := &storage.PresignOptions{
+		ContentType: blob.ContentType,
+	}
+	if download {
+		opts.ContentDisposition = ContentDisposition(blob.FileName)
+	}
+
+	url, err := s.store.PresignGet(ctx, blob.Key, opts)
+	if errors.Is(err, storage.ErrPresignNotSupported) {
+		return "", nil
+	}
+	return url, err
+}
+
 // GetMessageAttachments 获取消息的所有附件
 func (s *AttachmentService) GetMessageAttachments(messageID uint) ([]*entities.Attachment, error) {
 	return s.attachmentDAL.GetByMessageID(messageID)
 }
 
-// DeleteAttachment 删除附件
-func (s *AttachmentService) DeleteAttachment(attachmentID uint) error {
+// DeleteAttachment 删除附件，只有上传者或附件所在聊天室的管理员可以删除
+func (s *AttachmentService) DeleteAttachment(attachmentID, operatorID uint) error {
 	// 获取附件信息
 	attachment, err := s.attachmentDAL.GetByID(attachmentID)
 	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrAttachmentNotFound
+		}
 		return err
 	}
 
-	// 删除物理文件
-	if err := os.Remove(attachment.FilePath); err != nil {
-		logger.Error("Failed to delete file:", attachment.FilePath, "error:", err)
-		// 即使物理文件删除失败，也继续删除数据库记录
+	if attachment.UploaderID == 0 || attachment.UploaderID != operatorID {
+		roomID, err := s.attachmentRoomID(attachment)
+		if err != nil {
+			return err
+		}
+		if roomID == 0 {
+			return ErrAttachmentDeleteForbidden
+		}
+		isAdmin, err := s.roomService.IsRoomAdmin(operatorID, roomID)
+		if err != nil {
+			return err
+		}
+		if !isAdmin {
+			return ErrAttachmentDeleteForbidden
+		}
 	}
 
+	// 删除存储的文件和缩略图，即使删除失败也继续删除数据库记录
+	s.deleteBlobs(attachment)
+
 	// 删除数据库记录
 	return s.attachmentDAL.Delete(attachmentID)
 }
 
-// UpdateAttachmentMessageID 更新附件的消息ID
-func (s *AttachmentService) UpdateAttachmentMessageID(attachmentID, messageID uint) error {
-	return s.attachmentDAL.UpdateMessageID(attachmentID, &messageID)
+// AttachToMessage 将上传者的临时附件关联到消息，返回关联后的附件
+// 附件不存在、不属于该用户或已关联其他消息时返回nil
+func (s *AttachmentService) AttachToMessage(attachmentID, uploaderID, messageID, roomID uint) (*entities.Attachment, error) {
+	attached, err := s.attachmentDAL.AttachToMessage(attachmentID, uploaderID, messageID, roomID)
+	if err != nil || !attached {
+		return nil, err
+	}
+	return s.attachmentDAL.GetByID(attachmentID)
 }
 
-// ValidateFile 验证文件
-func (s *AttachmentService) ValidateFile(fileHeader *multipart.FileHeader) error {
-	// 文件大小限制（50MB）
-	const maxFileSize = 50 * 1024 * 1024
-	if fileHeader.Size > maxFileSize {
-		return fmt.Errorf("文件大小超过限制，最大允许50MB")
+// checkReadAccess 检查用户是否可以查看或下载附件
+func (s *AttachmentService) checkReadAccess(attachment *entities.Attachment, userID uint) error {
+	roomID, err := s.attachmentRoomID(attachment)
+	if err != nil {
+		return err
+	}
+	if roomID == 0 {
+		// 尚未发送的临时附件
+		if attachment.UploaderID != 0 && attachment.UploaderID == userID {
+			return nil
+		}
+		return ErrAttachmentForbidden
 	}
 
-	// 允许的文件类型
-	allowedTypes := map[string]bool{
-		// 图片类型
-		".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".bmp": true, ".webp": true,
-		// 视频类型
-		".mp4": true, ".avi": true, ".mov": true, ".wmv": true, ".flv": true, ".webm": true,
-		// 文档类型
-		".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".ppt": true, ".pptx": true,
-		".txt": true, ".md": true, ".csv": true,
-		// 压缩文件
-		".zip": true, ".rar": true, ".7z": true, ".tar": true, ".gz": true,
-		// 其他常见类型
-		".json": true, ".xml": true, ".log": true,
+	role, err := s.roomService.GetMemberRole(roomID, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrAttachmentForbidden
+		}
+		return err
+	}
+	if role == "" {
+		return ErrAttachmentForbidden
 	}
+	return nil
+}
 
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if !allowedTypes[ext] {
-		return fmt.Errorf("不支持的文件类型: %s", ext)
+// attachmentRoomID 获取附件所属聊天室，临时附件返回0
+// 早期附件没有记录聊天室，通过关联的消息查找
+func (s *AttachmentService) attachmentRoomID(attachment *entities.Attachment) (uint, error) {
+	if attachment.RoomID != nil {
+		return *attachment.RoomID, nil
+	}
+	if attachment.MessageID == nil {
+		return 0, nil
 	}
 
-	return nil
+	message, err := s.messageDAL.GetByID(*attachment.MessageID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, ErrAttachmentForbidden
+		}
+		return 0, err
+	}
+	return message.RoomID, nil
+}
+
+// ContentDisposition 构建下载附件的Content-Disposition头
+// 文件名由用户提供：去掉控制字符后按RFC 2045转义引号，非ASCII文件名使用RFC 2231的filename*参数
+func ContentDisposition(fileName string) string {
+	fileName = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, fileName)
+
+	if disposition := mime.FormatMediaType("attachment", map[string]string{"filename": fileName}); disposition != "" {
+		return disposition
+	}
+	return "attachment"
+}
+
+// deleteBlobs 删除附件的原文件和缩略图，失败时只记录日志
+func (s *AttachmentService) deleteBlobs(attachment *entities.Attachment) {
+	keys := []string{storageKey(attachment)}
+	for _, size := range ThumbnailSizes {
+		if attachment.HasThumbnail(size) {
+			keys = append(keys, thumbnailBlob(attachment, size).Key)
+		}
+	}
+
+	for _, key := range keys {
+		if err := s.deleteBlob(key); err != nil {
+			logger.Error("Failed to delete file:", key, "error:", err)
+		}
+	}
+}
+
+// putBlob 写入存储后端，超时时长随大小增加，存储后端无响应时不会一直挂起
+func (s *AttachmentService) putBlob(key string, r io.Reader, size int64, contentType string) error {
+	ctx, cancel := storageContext(size)
+	defer cancel()
+	return s.store.Put(ctx, key, r, size, contentType)
+}
+
+// deleteBlob 删除存储后端中的对象
+func (s *AttachmentService) deleteBlob(key string) error {
+	ctx, cancel := storageContext(0)
+	defer cancel()
+	return s.store.Delete(ctx, key)
+}
+
+// storageContext 存储后端写入、删除等后台操作的超时：基础时长加上按最低传输速率传输size字节所需的时长
+func storageContext(size int64) (context.Context, context.CancelFunc) {
+	timeout := storageBaseTimeout + time.Duration(size/storageMinBytesPerSecond)*time.Second
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// thumbnailBlob 缩略图与原图存放在同一目录，如 image/123_a.png 的256缩略图为 image/123_a_thumb256.png
+// JPEG原图生成JPEG缩略图，其他格式生成PNG以保留透明度
+func thumbnailBlob(attachment *entities.Attachment, size int) *AttachmentBlob {
+	key := storageKey(attachment)
+	ext, contentType := ".png", "image/png"
+	if attachment.FileType == "image/jpeg" {
+		ext, contentType = ".jpg", "image/jpeg"
+	}
+
+	base := strings.TrimSuffix(attachment.FileName, path.Ext(attachment.FileName))
+	return &AttachmentBlob{
+		Key:         fmt.Sprintf("%s_thumb%d%s", strings.TrimSuffix(key, path.Ext(key)), size, ext),
+		ContentType: contentType,
+		FileName:    fmt.Sprintf("%s_%d%s", base, size, ext),
+	}
+}
+
+// isThumbnailSize 是否为支持的缩略图尺寸
+func isThumbnailSize(size int) bool {
+	for _, s := range ThumbnailSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// storageKey 获取附件在存储后端中的key，兼容早期保存的本地路径
+func storageKey(attachment *entities.Attachment) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(attachment.FilePath)), legacyUploadPrefix)
 }
 
 // getCategoryFromFileName 根据文件名获取文件分类
@@ -2,36 +2,74 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"gochat/internal/config"
 	"gochat/internal/dal"
+	"gochat/internal/mailer"
 	"gochat/internal/models/entities"
 	"gochat/internal/models/requests"
 	"gochat/internal/models/responses"
+	"gochat/pkg/logger"
 	"gochat/pkg/utils"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// resetTokenBytes 重置密码令牌的随机字节数
+const resetTokenBytes = 32
+
+// 两步验证登录挑战参数
+const (
+	challengeTokenBytes = 32
+	challengeTTL        = 5 * time.Minute // 挑战有效期
+	challengeMaxAttempt = 5               // 每个挑战允许的验证码错误次数
+)
+
+// 认证错误，登录失败统一返回ErrInvalidCredentials，避免泄露用户是否存在
+var (
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	ErrAccountLocked      = errors.New("登录失败次数过多，请稍后再试") // 仅在已验证身份后返回（两步验证、修改密码等）
+	ErrWrongPassword      = errors.New("原密码错误")
+	ErrResetTokenInvalid  = errors.New("重置链接无效或已过期")
+	ErrChallengeInvalid   = errors.New("登录验证已失效，请重新登录")
+)
+
+// dummyPasswordHash 用户不存在时用于比对的密码哈希，使响应时间与密码错误时一致
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
 // AuthService 认证服务结构
 type AuthService struct {
-	userDAL     *dal.UserDAL
-	jwtSecret   string
-	expireHours time.Duration
+	userDAL          *dal.UserDAL
+	passwordResetDAL *dal.PasswordResetDAL
+	twoFactorDAL     *dal.TwoFactorDAL
+	tokenService     *TokenService
+	twoFactor        *TwoFactorService
+	mailer           mailer.Mailer
+	authCfg          *config.AuthConfig
 }
 
 // NewAuthService 创建认证服务实例
-func NewAuthService(cfg *config.JWTConfig) *AuthService {
+func NewAuthService(jwtCfg *config.JWTConfig, authCfg *config.AuthConfig, mail mailer.Mailer) *AuthService {
 	return &AuthService{
-		userDAL:     dal.NewUserDAL(),
-		jwtSecret:   cfg.Secret,
-		expireHours: cfg.ExpireHours,
+		userDAL:          dal.NewUserDAL(),
+		passwordResetDAL: dal.NewPasswordResetDAL(),
+		twoFactorDAL:     dal.NewTwoFactorDAL(),
+		tokenService:     NewTokenService(jwtCfg),
+		twoFactor:        NewTwoFactorService(authCfg),
+		mailer:           mail,
+		authCfg:          authCfg,
 	}
 }
 
-// Register 用户注册
-func (s *AuthService) Register(req *requests.RegisterRequest) (*responses.AuthResponse, error) {
+// Register 用户注册，注册成功后直接创建登录会话
+func (s *AuthService) Register(req *requests.RegisterRequest, meta *SessionMeta) (*responses.AuthResponse, error) {
 	// 检查用户名是否已存在
 	exists, err := s.userDAL.CheckUsernameExists(req.Username)
 	if err != nil {
@@ -61,31 +99,21 @@ func (s *AuthService) Register(req *requests.RegisterRequest) (*responses.AuthRe
 		Username:     req.Username,
 		Email:        req.Email,
 		PasswordHash: hashedPassword,
+		Role:         entities.UserRoleUser,
 	}
 
 	if err := s.userDAL.Create(user); err != nil {
 		return nil, errors.New("用户创建失败")
 	}
 
-	// 生成JWT token
-	token, err := utils.GenerateToken(user.ID, user.Username, s.jwtSecret, s.expireHours)
-	if err != nil {
-		return nil, errors.New("token生成失败")
-	}
-
-	return &responses.AuthResponse{
-		User: &responses.UserInfo{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			AvatarURL: user.AvatarURL,
-		},
-		Token: token,
-	}, nil
+	// 签发访问令牌和刷新令牌
+	return s.tokenService.IssueTokens(user, meta)
 }
 
-// Login 用户登录
-func (s *AuthService) Login(req *requests.LoginRequest) (*responses.AuthResponse, error) {
+// Login 用户登录，每次登录创建新的登录会话
+// 用户不存在、密码错误和账号锁定返回相同的错误；连续失败达到上限后按次数递增锁定时长
+// 启用两步验证的用户密码验证通过后返回登录挑战，需调用LoginTwoFactor完成登录
+func (s *AuthService) Login(req *requests.LoginRequest, meta *SessionMeta) (*responses.AuthResponse, *responses.TwoFactorChallenge, error) {
 	var user *entities.User
 	var err error
 
@@ -98,36 +126,329 @@ func (s *AuthService) Login(req *requests.LoginRequest) (*responses.AuthResponse
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, errors.New("用户不存在")
+			utils.CheckPassword(req.Password, getDummyPasswordHash())
+			return nil, nil, ErrInvalidCredentials
 		}
-		return nil, errors.New("系统错误")
+		return nil, nil, errors.New("系统错误")
+	}
+
+	// 锁定中的账号同样比对密码并返回相同的错误，避免通过锁定状态判断账号是否存在
+	if isLocked(user) {
+		utils.CheckPassword(req.Password, user.PasswordHash)
+		return nil, nil, ErrInvalidCredentials
 	}
 
 	// 验证密码
 	if !utils.CheckPassword(req.Password, user.PasswordHash) {
-		return nil, errors.New("密码错误")
+		if err := recordLoginFailure(s.userDAL, s.authCfg, user.ID); err != nil {
+			logger.Error("Failed to record login failure:", err)
+		}
+		return nil, nil, ErrInvalidCredentials
 	}
 
-	// 生成JWT token
-	token, err := utils.GenerateToken(user.ID, user.Username, s.jwtSecret, s.expireHours)
+	return s.beginSession(user, meta)
+}
+
+// LoginVerified 用户身份已由外部身份提供方验证后登录，与密码登录同样检查锁定状态和两步验证
+func (s *AuthService) LoginVerified(user *entities.User, meta *SessionMeta) (*responses.AuthResponse, *responses.TwoFactorChallenge, error) {
+	if isLocked(user) {
+		return nil, nil, ErrAccountLocked
+	}
+	return s.beginSession(user, meta)
+}
+
+// beginSession 已通过第一步验证的用户：启用两步验证时返回登录挑战，否则直接完成登录
+func (s *AuthService) beginSession(user *entities.User, meta *SessionMeta) (*responses.AuthResponse, *responses.TwoFactorChallenge, error) {
+	if user.TOTPEnabled {
+		challenge, err := s.createChallenge(user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, challenge, nil
+	}
+
+	resp, err := s.completeLogin(user, meta)
+	return resp, nil, err
+}
+
+// LoginTwoFactor 使用登录挑战和TOTP验证码（或恢复码）完成登录
+// 验证码错误计入登录失败次数，同一挑战错误次数过多后失效
+func (s *AuthService) LoginTwoFactor(req *requests.TwoFactorLoginRequest, meta *SessionMeta) (*responses.AuthResponse, error) {
+	challenge, err := s.twoFactorDAL.GetChallenge(utils.HashToken(req.PendingToken))
 	if err != nil {
-		return nil, errors.New("token生成失败")
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrChallengeInvalid
+		}
+		return nil, errors.New("系统错误")
+	}
+	if !challenge.ExpiresAt.After(time.Now()) || challenge.Attempts >= challengeMaxAttempt {
+		return nil, ErrChallengeInvalid
 	}
 
-	return &responses.AuthResponse{
-		User: &responses.UserInfo{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			AvatarURL: user.AvatarURL,
-		},
-		Token: token,
+	user, err := s.userDAL.GetByID(challenge.UserID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrChallengeInvalid
+		}
+		return nil, errors.New("系统错误")
+	}
+	if isLocked(user) {
+		return nil, ErrAccountLocked
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrChallengeInvalid
+	}
+
+	ok, err := s.twoFactor.CheckCode(user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.twoFactorDAL.IncrementChallengeAttempts(challenge.ID); err != nil {
+			logger.Error("Failed to record two-factor failure:", err)
+		}
+		if err := recordLoginFailure(s.userDAL, s.authCfg, user.ID); err != nil {
+			logger.Error("Failed to record login failure:", err)
+		}
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	// 条件删除保证同一挑战只能完成一次
+	deleted, err := s.twoFactorDAL.DeleteChallenge(challenge.ID)
+	if err != nil {
+		return nil, errors.New("系统错误")
+	}
+	if !deleted {
+		return nil, ErrChallengeInvalid
+	}
+
+	return s.completeLogin(user, meta)
+}
+
+// completeLogin 清除登录失败记录并签发访问令牌和刷新令牌
+func (s *AuthService) completeLogin(user *entities.User, meta *SessionMeta) (*responses.AuthResponse, error) {
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := s.userDAL.ResetFailedLogins(user.ID); err != nil {
+			logger.Error("Failed to reset login failures:", err)
+		}
+	}
+	return s.tokenService.IssueTokens(user, meta)
+}
+
+// createChallenge 为已通过密码验证的用户创建两步验证登录挑战
+func (s *AuthService) createChallenge(userID uint) (*responses.TwoFactorChallenge, error) {
+	token, err := utils.GenerateRandomToken(challengeTokenBytes)
+	if err != nil {
+		return nil, errors.New("系统错误")
+	}
+
+	expiresAt := time.Now().Add(challengeTTL)
+	if err := s.twoFactorDAL.CreateChallenge(&entities.LoginChallenge{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return nil, errors.New("系统错误")
+	}
+
+	return &responses.TwoFactorChallenge{
+		TwoFactorRequired: true,
+		PendingToken:      token,
+		ExpiresAt:         expiresAt,
 	}, nil
 }
 
+// isLocked 用户是否处于登录锁定中
+func isLocked(user *entities.User) bool {
+	return user.LockedUntil != nil && user.LockedUntil.After(time.Now())
+}
+
+// recordLoginFailure 记录一次登录失败，达到上限后锁定，之后每次失败锁定时长翻倍
+// 两步验证管理等需要再次验证密码的操作共用同一计数
+func recordLoginFailure(userDAL *dal.UserDAL, cfg *config.AuthConfig, userID uint) error {
+	failures, err := userDAL.IncrementFailedLogins(userID)
+	if err != nil {
+		return err
+	}
+	if failures < cfg.MaxLoginAttempts {
+		return nil
+	}
+
+	lockout := time.Duration(cfg.LockoutMinutes) * time.Minute
+	maxLockout := time.Duration(cfg.MaxLockoutMinutes) * time.Minute
+	for i := cfg.MaxLoginAttempts; i < failures && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > maxLockout {
+		lockout = maxLockout
+	}
+	return userDAL.LockUntil(userID, time.Now().Add(lockout))
+}
+
+// ChangePassword 修改密码，成功后注销所有登录会话并为当前设备签发新的令牌
+// 原密码错误计入登录失败次数，账号锁定期间不能修改密码
+func (s *AuthService) ChangePassword(userID uint, req *requests.ChangePasswordRequest, meta *SessionMeta) (*responses.AuthResponse, error) {
+	user, err := s.userDAL.GetByID(userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, errors.New("系统错误")
+	}
+
+	if isLocked(user) {
+		return nil, ErrAccountLocked
+	}
+
+	// 原密码错误同样计入登录失败次数，防止借已登录的会话暴力猜测密码
+	if !utils.CheckPassword(req.OldPassword, user.PasswordHash) {
+		if err := recordLoginFailure(s.userDAL, s.authCfg, user.ID); err != nil {
+			logger.Error("Failed to record login failure:", err)
+		}
+		return nil, ErrWrongPassword
+	}
+
+	if err := s.setPassword(user.ID, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	// token版本已变化，重新读取用户后签发
+	user, err = s.userDAL.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("系统错误")
+	}
+	return s.tokenService.IssueTokens(user, meta)
+}
+
+// ForgotPassword 发送重置密码邮件，邮箱不存在时同样返回成功，避免泄露用户是否存在
+func (s *AuthService) ForgotPassword(email string) error {
+	user, err := s.userDAL.GetByEmail(email)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return errors.New("系统错误")
+	}
+
+	token, err := utils.GenerateRandomToken(resetTokenBytes)
+	if err != nil {
+		return errors.New("系统错误")
+	}
+
+	// 新的重置链接生效后，之前发送的链接全部失效
+	if err := s.passwordResetDAL.InvalidateByUser(user.ID); err != nil {
+		return errors.New("系统错误")
+	}
+	expireMinutes := s.authCfg.ResetExpireMinutes
+	if err := s.passwordResetDAL.Create(&entities.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(time.Duration(expireMinutes) * time.Minute),
+	}); err != nil {
+		return errors.New("系统错误")
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "GoChat 重置密码",
+		Body: fmt.Sprintf("%s，你好：\n\n我们收到了重置你的 GoChat 账号密码的请求。请在 %d 分钟内打开以下链接设置新密码：\n\n%s\n\n如果这不是你本人的操作，请忽略这封邮件。\n",
+			user.Username, expireMinutes, s.resetLink(token)),
+	}
+	// 异步发送，避免响应时间泄露邮箱是否存在
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			logger.Error("Failed to send password reset mail:", err)
+		}
+	}()
+	return nil
+}
+
+// ResetPassword 使用重置令牌设置新密码，返回被重置的用户ID
+// 重置成功后注销该用户的所有登录会话并解除登录锁定
+func (s *AuthService) ResetPassword(token, newPassword string) (uint, error) {
+	stored, err := s.passwordResetDAL.GetByHash(utils.HashToken(token))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, ErrResetTokenInvalid
+		}
+		return 0, errors.New("系统错误")
+	}
+	if stored.UsedAt != nil || !stored.ExpiresAt.After(time.Now()) {
+		return 0, ErrResetTokenInvalid
+	}
+
+	// 条件更新保证令牌只能使用一次
+	consumed, err := s.passwordResetDAL.Consume(stored.ID)
+	if err != nil {
+		return 0, errors.New("系统错误")
+	}
+	if !consumed {
+		return 0, ErrResetTokenInvalid
+	}
+
+	if err := s.setPassword(stored.UserID, newPassword); err != nil {
+		return 0, err
+	}
+	if err := s.userDAL.ResetFailedLogins(stored.UserID); err != nil {
+		return 0, errors.New("系统错误")
+	}
+	return stored.UserID, nil
+}
+
+// setPassword 更新密码并撤销用户已签发的所有令牌
+func (s *AuthService) setPassword(userID uint, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+	if err := s.userDAL.UpdatePassword(userID, hashedPassword); err != nil {
+		return errors.New("密码更新失败")
+	}
+	if err := s.tokenService.RevokeAllTokens(userID); err != nil {
+		return errors.New("系统错误")
+	}
+	return nil
+}
+
+// resetLink 构造重置密码链接
+func (s *AuthService) resetLink(token string) string {
+	link, err := url.Parse(s.authCfg.ResetURL)
+	if err != nil {
+		return s.authCfg.ResetURL + "?token=" + url.QueryEscape(token)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// getDummyPasswordHash 获取用于等时比对的密码哈希
+func getDummyPasswordHash() string {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = utils.HashPassword("gochat-dummy-password")
+	})
+	return dummyPasswordHash
+}
+
+// RefreshToken 使用刷新令牌换取新的令牌对
+func (s *AuthService) RefreshToken(refreshToken string) (*responses.AuthResponse, error) {
+	return s.tokenService.Refresh(refreshToken)
+}
+
+// Logout 注销当前访问令牌及对应的刷新令牌，all为true时注销用户的所有令牌
+func (s *AuthService) Logout(claims *utils.JWTClaims, refreshToken string, all bool) error {
+	if all {
+		if err := s.tokenService.RevokeAccessToken(claims); err != nil {
+			return err
+		}
+		return s.tokenService.RevokeAllTokens(claims.UserID)
+	}
+	return s.tokenService.Logout(claims, refreshToken)
+}
+
 // GetUserByToken 通过token获取用户信息
 func (s *AuthService) GetUserByToken(tokenString string) (*responses.UserInfo, error) {
-	claims, err := utils.ParseToken(tokenString, s.jwtSecret)
+	claims, err := s.tokenService.ParseAndValidate(tokenString)
 	if err != nil {
 		return nil, errors.New("无效的token")
 	}
@@ -145,5 +466,6 @@ func (s *AuthService) GetUserByToken(tokenString string) (*responses.UserInfo, e
 		Username:  user.Username,
 		Email:     user.Email,
 		AvatarURL: user.AvatarURL,
+		Role:      user.Role,
 	}, nil
 }
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"gochat/internal/dal"
+	"gochat/internal/models/entities"
+	"gochat/internal/models/requests"
+	"gochat/internal/models/responses"
+	"gochat/pkg/utils"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 传入Webhook参数
+const (
+	IncomingWebhookPathPrefix      = "/hooks/" // 传入Webhook URL路径前缀
+	incomingWebhookTokenBytes      = 24        // 令牌随机字节数
+	incomingWebhookDisplayPrefix   = 8         // 保存的令牌明文前缀长度
+	incomingWebhookMaxContentRunes = 40000     // 转换后消息的最大长度，与Slack的上限一致
+	incomingWebhookMaxContentBytes = 65535     // 转换后消息的最大字节数，不超过消息内容字段（MySQL TEXT）的容量
+)
+
+// 传入Webhook错误
+var (
+	ErrIncomingWebhookNotFound = errors.New("传入Webhook不存在")
+	ErrIncomingWebhookNoText   = errors.New("消息内容为空")
+	ErrIncomingWebhookTooLong  = errors.New("消息内容过长")
+)
+
+// IncomingWebhookService 传入Webhook服务，将Slack格式的消息以集成机器人身份发到聊天室
+type IncomingWebhookService struct {
+	hookDAL        *dal.IncomingWebhookDAL
+	userDAL        *dal.UserDAL
+	roomService    *RoomService
+	messageService *MessageService
+	apiKeyService  *APIKeyService
+}
+
+// NewIncomingWebhookService 创建传入Webhook服务实例
+func NewIncomingWebhookService() *IncomingWebhookService {
+	return &IncomingWebhookService{
+		hookDAL:        dal.NewIncomingWebhookDAL(),
+		userDAL:        dal.NewUserDAL(),
+		roomService:    NewRoomService(),
+		messageService: NewMessageService(),
+		apiKeyService:  NewAPIKeyService(),
+	}
+}
+
+// CreateIncomingWebhook 为聊天室创建传入Webhook（管理员及以上）
+// 同时创建一个集成机器人账号并加入聊天室，令牌只在创建时返回一次
+func (s *IncomingWebhookService) CreateIncomingWebhook(operatorID, roomID uint, req *requests.CreateIncomingWebhookRequest) (*responses.IncomingWebhookCreated, error) {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	bot, err := s.apiKeyService.newBot(operatorID, req.Username, req.AvatarURL)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateRandomToken(incomingWebhookTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &entities.IncomingWebhook{
+		RoomID:      roomID,
+		Name:        req.Name,
+		TokenHash:   utils.HashToken(token),
+		TokenPrefix: token[:incomingWebhookDisplayPrefix],
+		CreatedBy:   operatorID,
+	}
+	// 机器人、成员关系和Webhook一起创建，避免失败时留下孤立的机器人账号
+	if err := s.hookDAL.CreateWithBot(bot, hook); err != nil {
+		return nil, err
+	}
+	emitMemberEvent(entities.WebhookEventMemberJoined, roomID, bot.ID, "")
+
+	return &responses.IncomingWebhookCreated{
+		IncomingWebhookInfo: *newIncomingWebhookInfo(hook, bot.Username),
+		Token:               token,
+		URL:                 IncomingWebhookPathPrefix + token,
+	}, nil
+}
+
+// ListIncomingWebhooks 获取聊天室的传入Webhook列表
+func (s *IncomingWebhookService) ListIncomingWebhooks(operatorID, roomID uint) ([]*responses.IncomingWebhookInfo, error) {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	hooks, err := s.hookDAL.GetByRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*responses.IncomingWebhookInfo, 0, len(hooks))
+	for _, hook := range hooks {
+		var username string
+		if bot, err := s.userDAL.GetByIDWithDeleted(hook.UserID); err == nil {
+			username = bot.Username
+		}
+		items = append(items, newIncomingWebhookInfo(hook, username))
+	}
+	return items, nil
+}
+
+// DeleteIncomingWebhook 删除传入Webhook，URL立即失效；集成机器人账号保留，历史消息不受影响
+func (s *IncomingWebhookService) DeleteIncomingWebhook(operatorID, roomID, hookID uint) error {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return err
+	}
+
+	hook, err := s.hookDAL.GetByID(hookID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrIncomingWebhookNotFound
+		}
+		return err
+	}
+	if hook.RoomID != roomID {
+		return ErrIncomingWebhookNotFound
+	}
+	return s.hookDAL.Delete(hookID)
+}
+
+// Post 将Slack格式的消息转换为文本，以集成机器人身份发到Webhook所属的聊天室
+// 返回带发送者信息的消息，由调用方负责广播
+func (s *IncomingWebhookService) Post(token string, msg *requests.SlackMessage) (*entities.Message, error) {
+	hook, err := s.hookDAL.GetByTokenHash(utils.HashToken(token))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		return nil, err
+	}
+
+	// 机器人被禁用后Webhook随之失效
+	if _, err := s.userDAL.GetByID(hook.UserID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		return nil, err
+	}
+
+	content := RenderSlackMessage(msg)
+	if content == "" {
+		return nil, ErrIncomingWebhookNoText
+	}
+	if len(content) > incomingWebhookMaxContentBytes || utf8.RuneCountInString(content) > incomingWebhookMaxContentRunes {
+		return nil, ErrIncomingWebhookTooLong
+	}
+
+	message, err := s.messageService.PostMessage(hook.UserID, hook.RoomID, content, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.hookDAL.Touch(hook.ID)
+	return message, nil
+}
+
+// newIncomingWebhookInfo 构建传入Webhook信息
+func newIncomingWebhookInfo(hook *entities.IncomingWebhook, username string) *responses.IncomingWebhookInfo {
+	return &responses.IncomingWebhookInfo{
+		ID:          hook.ID,
+		RoomID:      hook.RoomID,
+		Name:        hook.Name,
+		UserID:      hook.UserID,
+		Username:    username,
+		TokenPrefix: hook.TokenPrefix,
+		CreatedBy:   hook.CreatedBy,
+		LastUsedAt:  hook.LastUsedAt,
+		CreatedAt:   hook.CreatedAt,
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"gochat/internal/dal"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// inviteTokenBytes 邀请令牌的随机字节数
+const inviteTokenBytes = 16
+
+// 邀请与加入申请错误
+var (
+	ErrInviteInvalid        = errors.New("邀请链接无效或已过期")
+	ErrInviteNotFound       = errors.New("邀请不存在")
+	ErrAlreadyMember        = errors.New("你已经是聊天室成员")
+	ErrJoinRequestNotFound  = errors.New("加入申请不存在或已处理")
+	ErrJoinRequestExists    = errors.New("已提交过加入申请，请等待审批")
+	ErrJoinRequestNotNeeded = errors.New("公开聊天室可以直接加入")
+)
+
+// InviteService 聊天室邀请与加入申请服务
+type InviteService struct {
+	roomService    *RoomService
+	roomDAL        *dal.RoomDAL
+	roomMemberDAL  *dal.RoomMemberDAL
+	inviteDAL      *dal.RoomInviteDAL
+	joinRequestDAL *dal.RoomJoinRequestDAL
+}
+
+// NewInviteService 创建邀请服务实例
+func NewInviteService() *InviteService {
+	return &InviteService{
+		roomService:    NewRoomService(),
+		roomDAL:        dal.NewRoomDAL(),
+		roomMemberDAL:  dal.NewRoomMemberDAL(),
+		inviteDAL:      dal.NewRoomInviteDAL(),
+		joinRequestDAL: dal.NewRoomJoinRequestDAL(),
+	}
+}
+
+// CreateInvite 创建邀请链接（管理员及以上），maxUses为0表示不限次数，ttl为0表示永不过期
+func (s *InviteService) CreateInvite(operatorID, roomID uint, maxUses int, ttl time.Duration) (*entities.RoomInvite, error) {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &entities.RoomInvite{
+		RoomID:    roomID,
+		Token:     token,
+		CreatedBy: operatorID,
+		MaxUses:   maxUses,
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := s.inviteDAL.Create(invite); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// GetActiveInvites 获取聊天室中仍可使用的邀请（管理员及以上）
+func (s *InviteService) GetActiveInvites(operatorID, roomID uint) ([]*entities.RoomInvite, error) {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.inviteDAL.GetActiveByRoom(roomID)
+}
+
+// RevokeInvite 撤销邀请（管理员及以上）
+func (s *InviteService) RevokeInvite(operatorID, roomID, inviteID uint) error {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return err
+	}
+
+	revoked, err := s.inviteDAL.Revoke(roomID, inviteID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInviteNotFound
+	}
+	return nil
+}
+
+// AcceptInvite 通过邀请链接加入聊天室，已是成员时直接返回聊天室且不消耗次数
+func (s *InviteService) AcceptInvite(userID uint, token string) (*entities.Room, error) {
+	invite, err := s.inviteDAL.GetByToken(token)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrInviteInvalid
+		}
+		return nil, err
+	}
+	if !invite.IsUsable() || invite.Room.ID == 0 {
+		return nil, ErrInviteInvalid
+	}
+
+	banned, err := s.roomService.IsBanned(invite.RoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if banned {
+		return nil, ErrRoomBanned
+	}
+
+	isMember, err := s.roomMemberDAL.IsMember(invite.RoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isMember {
+		return &invite.Room, nil
+	}
+
+	consumed, err := s.inviteDAL.Consume(invite.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInviteInvalid
+	}
+
+	if err := s.roomService.ensureMember(invite.RoomID, userID); err != nil {
+		return nil, err
+	}
+	return &invite.Room, nil
+}
+
+// CreateJoinRequest 申请加入私有聊天室
+func (s *InviteService) CreateJoinRequest(userID, roomID uint, message string) (*entities.RoomJoinRequest, error) {
+	room, err := s.roomDAL.GetByID(roomID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRoomNotFound
+		}
+		return nil, err
+	}
+	if room.Kind == entities.RoomKindDirect {
+		return nil, ErrDirectRoomJoin
+	}
+	if !room.IsPrivate {
+		return nil, ErrJoinRequestNotNeeded
+	}
+
+	banned, err := s.roomService.IsBanned(roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if banned {
+		return nil, ErrRoomBanned
+	}
+
+	isMember, err := s.roomMemberDAL.IsMember(roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isMember {
+		return nil, ErrAlreadyMember
+	}
+
+	if _, err := s.joinRequestDAL.GetPending(roomID, userID); err == nil {
+		return nil, ErrJoinRequestExists
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	request := &entities.RoomJoinRequest{
+		RoomID:  roomID,
+		UserID:  userID,
+		Message: message,
+		Status:  entities.JoinRequestPending,
+	}
+	if err := s.joinRequestDAL.Create(request); err != nil {
+		return nil, err
+	}
+
+	// 重新查询以携带申请人信息
+	return s.joinRequestDAL.GetByID(request.ID)
+}
+
+// GetPendingJoinRequests 获取聊天室待审批的加入申请（管理员及以上）
+func (s *InviteService) GetPendingJoinRequests(operatorID, roomID uint) ([]*entities.RoomJoinRequest, error) {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.joinRequestDAL.GetPendingByRoom(roomID)
+}
+
+// ReviewJoinRequest 审批加入申请（管理员及以上），同意时申请人成为成员
+func (s *InviteService) ReviewJoinRequest(operatorID, roomID, requestID uint, approve bool) (*entities.RoomJoinRequest, error) {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	request, err := s.joinRequestDAL.GetByID(requestID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrJoinRequestNotFound
+		}
+		return nil, err
+	}
+	if request.RoomID != roomID || request.Status != entities.JoinRequestPending {
+		return nil, ErrJoinRequestNotFound
+	}
+
+	status := entities.JoinRequestDenied
+	if approve {
+		// 申请期间被封禁的用户不能通过审批
+		banned, err := s.roomService.IsBanned(roomID, request.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if banned {
+			return nil, ErrRoomBanned
+		}
+		status = entities.JoinRequestApproved
+	}
+
+	reviewed, err := s.joinRequestDAL.Review(requestID, status, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if !reviewed {
+		return nil, ErrJoinRequestNotFound
+	}
+
+	if approve {
+		if err := s.roomService.ensureMember(roomID, request.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	request.Status = status
+	request.ReviewedBy = &operatorID
+	request.ReviewedAt = &now
+	return request, nil
+}
+
+// GetRoomAdminIDs 获取聊天室管理员及以上角色的用户ID（用于推送加入申请）
+func (s *InviteService) GetRoomAdminIDs(roomID uint) ([]uint, error) {
+	userIDs, err := s.roomMemberDAL.GetUserIDsByRoles(roomID, []string{entities.RoomRoleOwner, entities.RoomRoleAdmin})
+	if err != nil {
+		return nil, err
+	}
+
+	// 早期创建的聊天室中创建者可能没有成员记录
+	room, err := s.roomDAL.GetByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs {
+		if userID == room.CreatedBy {
+			return userIDs, nil
+		}
+	}
+	isOwner, err := s.roomService.HasRoomRole(room.CreatedBy, roomID, entities.RoomRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if isOwner {
+		userIDs = append(userIDs, room.CreatedBy)
+	}
+	return userIDs, nil
+}
+
+// generateInviteToken 生成随机邀请令牌
+func generateInviteToken() (string, error) {
+	buf := make([]byte, inviteTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -1,25 +1,183 @@
 package services
 
 import (
+	"errors"
 	"gochat/internal/dal"
 	"gochat/internal/models/entities"
+
+	"gorm.io/gorm"
+)
+
+// 消息操作错误
+var (
+	ErrMessageNotFound    = errors.New("消息不存在")
+	ErrMessageNoAuthority = errors.New("无权限操作该消息")
+	ErrReplyTargetInvalid = errors.New("回复的消息不存在")
+	ErrNotRoomMember      = errors.New("您不在此聊天室中")
+	ErrMemberMuted        = errors.New("你已被禁言")
 )
 
 // MessageService 消息服务
 type MessageService struct {
-	messageDAL *dal.MessageDAL
+	messageDAL   *dal.MessageDAL
+	readStateDAL *dal.ReadStateDAL
+	reactionDAL  *dal.ReactionDAL
+	roomService  *RoomService
 }
 
 // NewMessageService 创建消息服务实例
 func NewMessageService() *MessageService {
 	return &MessageService{
-		messageDAL: dal.NewMessageDAL(),
+		messageDAL:   dal.NewMessageDAL(),
+		readStateDAL: dal.NewReadStateDAL(),
+		reactionDAL:  dal.NewReactionDAL(),
+		roomService:  NewRoomService(),
 	}
 }
 
 // CreateMessage 创建消息
 func (s *MessageService) CreateMessage(message *entities.Message) (*entities.Message, error) {
-	return s.messageDAL.Create(message)
+	return s.createMessage(message)
+}
+
+// PostMessage 以成员身份发送文本消息（REST接口使用），校验成员身份和禁言状态后持久化
+func (s *MessageService) PostMessage(userID, roomID uint, content string, replyToID uint) (*entities.Message, error) {
+	role, err := s.roomService.GetMemberRole(roomID, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRoomNotFound
+		}
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrNotRoomMember
+	}
+
+	muted, err := s.roomService.IsMuted(roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if muted {
+		return nil, ErrMemberMuted
+	}
+
+	message := &entities.Message{
+		RoomID:      roomID,
+		UserID:      userID,
+		Content:     content,
+		MessageType: "text",
+	}
+	if replyToID > 0 {
+		message.ReplyToID = &replyToID
+	}
+
+	savedMessage, err := s.CreateMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重新加载以带上发送者信息，用于广播
+	return s.messageDAL.GetByID(savedMessage.ID)
+}
+
+// CreateMessageWithClientID 创建消息，携带客户端消息ID时按用户去重
+// 返回值created为false表示该消息此前已存储，返回的是已有记录
+func (s *MessageService) CreateMessageWithClientID(message *entities.Message, clientMsgID string) (*entities.Message, bool, error) {
+	if clientMsgID == "" {
+		savedMessage, err := s.createMessage(message)
+		return savedMessage, err == nil, err
+	}
+
+	existing, err := s.messageDAL.GetByClientMsgID(message.UserID, clientMsgID)
+	if err == nil {
+		return existing, false, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, false, err
+	}
+
+	message.ClientMsgID = &clientMsgID
+	savedMessage, err := s.createMessage(message)
+	if err != nil {
+		// 并发重试可能触发唯一索引冲突，此时返回已存储的消息
+		if existing, findErr := s.messageDAL.GetByClientMsgID(message.UserID, clientMsgID); findErr == nil {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+
+	return savedMessage, true, nil
+}
+
+// createMessage 创建消息，回复消息会关联到话题并更新话题统计
+func (s *MessageService) createMessage(message *entities.Message) (*entities.Message, error) {
+	if message.ReplyToID != nil {
+		if *message.ReplyToID == 0 {
+			message.ReplyToID = nil
+		} else {
+			rootID, err := s.resolveThreadRoot(message.RoomID, *message.ReplyToID)
+			if err != nil {
+				return nil, err
+			}
+			message.ThreadRootID = &rootID
+		}
+	}
+
+	savedMessage, err := s.messageDAL.Create(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if savedMessage.ThreadRootID != nil {
+		if err := s.messageDAL.IncrementReplyCount(*savedMessage.ThreadRootID, savedMessage.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	emitMessageEvent(entities.WebhookEventMessageCreated, savedMessage)
+
+	return savedMessage, nil
+}
+
+// resolveThreadRoot 根据被回复的消息确定话题根消息
+func (s *MessageService) resolveThreadRoot(roomID, replyToID uint) (uint, error) {
+	parent, err := s.messageDAL.GetByID(replyToID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, ErrReplyTargetInvalid
+		}
+		return 0, err
+	}
+
+	// 只能回复同一聊天室中的消息
+	if parent.RoomID != roomID {
+		return 0, ErrReplyTargetInvalid
+	}
+
+	if parent.ThreadRootID != nil {
+		return *parent.ThreadRootID, nil
+	}
+	return parent.ID, nil
+}
+
+// GetThread 获取话题根消息及其回复
+func (s *MessageService) GetThread(roomID, rootID uint, limit, offset int) (*entities.Message, []*entities.Message, error) {
+	root, err := s.messageDAL.GetByID(rootID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, ErrMessageNotFound
+		}
+		return nil, nil, err
+	}
+	if root.RoomID != roomID {
+		return nil, nil, ErrMessageNotFound
+	}
+
+	replies, err := s.messageDAL.GetThreadReplies(rootID, limit, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	return root, replies, nil
 }
 
 // GetMessagesByRoom 获取聊天室消息
@@ -27,6 +185,11 @@ func (s *MessageService) GetMessagesByRoom(roomID uint, limit, offset int) ([]*e
 	return s.messageDAL.GetByRoomID(roomID, limit, offset)
 }
 
+// GetMessagesAfter 获取聊天室中指定消息之后的消息
+func (s *MessageService) GetMessagesAfter(roomID, afterID uint, limit int) ([]*entities.Message, error) {
+	return s.messageDAL.GetAfterID(roomID, afterID, limit)
+}
+
 // GetMessageByID 根据ID获取消息
 func (s *MessageService) GetMessageByID(messageID uint) (*entities.Message, error) {
 	return s.messageDAL.GetByID(messageID)
@@ -42,7 +205,123 @@ func (s *MessageService) DeleteMessage(messageID uint) error {
 	return s.messageDAL.Delete(messageID)
 }
 
+// EditMessage 编辑消息（仅消息作者或聊天室协管员及以上角色）
+func (s *MessageService) EditMessage(operatorID, messageID uint, content string) (*entities.Message, error) {
+	if _, err := s.checkMessageAuthority(operatorID, messageID); err != nil {
+		return nil, err
+	}
+
+	message, err := s.messageDAL.Update(messageID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	emitMessageEvent(entities.WebhookEventMessageEdited, message)
+
+	return message, nil
+}
+
+// RemoveMessage 删除消息（仅消息作者或聊天室协管员及以上角色），返回被删除的消息
+func (s *MessageService) RemoveMessage(operatorID, messageID uint) (*entities.Message, error) {
+	message, err := s.checkMessageAuthority(operatorID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.messageDAL.Delete(messageID); err != nil {
+		return nil, err
+	}
+
+	// 删除回复时同步话题回复数
+	if message.ThreadRootID != nil {
+		if err := s.messageDAL.DecrementReplyCount(*message.ThreadRootID); err != nil {
+			return nil, err
+		}
+	}
+
+	emitMessageEvent(entities.WebhookEventMessageDeleted, message)
+
+	return message, nil
+}
+
+// checkMessageAuthority 检查用户是否有权限修改消息
+func (s *MessageService) checkMessageAuthority(operatorID, messageID uint) (*entities.Message, error) {
+	message, err := s.messageDAL.GetByID(messageID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	// 消息作者可以直接操作
+	if message.UserID == operatorID {
+		return message, nil
+	}
+
+	// 协管员及以上角色可以操作他人消息
+	canModerate, err := s.roomService.HasRoomRole(operatorID, message.RoomID, entities.RoomRoleModerator)
+	if err != nil {
+		return nil, err
+	}
+	if !canModerate {
+		return nil, ErrMessageNoAuthority
+	}
+
+	return message, nil
+}
+
 // GetUnreadCount 获取未读消息数量
 func (s *MessageService) GetUnreadCount(userID, roomID uint) (int64, error) {
 	return s.messageDAL.GetUnreadCount(userID, roomID)
 }
+
+// MarkRead 将用户在聊天室中的已读位置推进到指定消息
+// 返回最新的已读消息ID，以及已读位置是否发生变化
+func (s *MessageService) MarkRead(userID, roomID, messageID uint) (uint, bool, error) {
+	exists, err := s.messageDAL.ExistsInRoom(messageID, roomID)
+	if err != nil {
+		return 0, false, err
+	}
+	if !exists {
+		return 0, false, ErrMessageNotFound
+	}
+
+	advanced, err := s.readStateDAL.Advance(roomID, userID, messageID)
+	if err != nil {
+		return 0, false, err
+	}
+	if advanced {
+		return messageID, true, nil
+	}
+
+	// 已读位置没有变化，返回当前位置
+	state, err := s.readStateDAL.Get(roomID, userID)
+	if err != nil {
+		return 0, false, err
+	}
+	return state.LastReadMessageID, false, nil
+}
+
+// AddReaction 添加表情回应，返回回应是否发生变化
+func (s *MessageService) AddReaction(userID, messageID uint, emoji string) (bool, error) {
+	return s.reactionDAL.Create(&entities.MessageReaction{
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emoji,
+	})
+}
+
+// RemoveReaction 取消表情回应，返回回应是否发生变化
+func (s *MessageService) RemoveReaction(userID, messageID uint, emoji string) (bool, error) {
+	return s.reactionDAL.Delete(messageID, userID, emoji)
+}
+
+// GetReactions 获取消息的表情回应聚合
+func (s *MessageService) GetReactions(messageID uint) ([]entities.ReactionSummary, error) {
+	summaries, err := s.reactionDAL.GetSummaries([]uint{messageID})
+	if err != nil {
+		return nil, err
+	}
+	return summaries[messageID], nil
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"errors"
+	"gochat/internal/dal"
+	"gochat/internal/models/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 聊天室管理操作类型
+const (
+	ModerationKick   = "kick"   // 踢出
+	ModerationBan    = "ban"    // 封禁
+	ModerationUnban  = "unban"  // 解除封禁
+	ModerationMute   = "mute"   // 禁言
+	ModerationUnmute = "unmute" // 解除禁言
+	ModerationRole   = "role"   // 调整角色
+)
+
+// MaxSanctionSeconds 封禁、禁言时长上限（秒），10年；与requests.SanctionRequest的校验一致
+const MaxSanctionSeconds int64 = 10 * 365 * 24 * 60 * 60
+
+// 聊天室管理错误
+var (
+	ErrModerationNoAuthority = errors.New("无权限执行该操作")
+	ErrModerationSelf        = errors.New("不能对自己执行该操作")
+	ErrModerationNotMember   = errors.New("用户不在聊天室中")
+	ErrModerationInvalidRole = errors.New("无效的角色")
+	ErrModerationDirectRoom  = errors.New("私聊不支持该操作")
+)
+
+// ModerationResult 聊天室管理操作结果，用于通知聊天室成员
+type ModerationResult struct {
+	Action       string     `json:"action"`
+	RoomID       uint       `json:"room_id"`
+	OperatorID   uint       `json:"operator_id"`
+	OperatorName string     `json:"operator_name"`
+	TargetID     uint       `json:"target_id"`
+	TargetName   string     `json:"target_name"`
+	Reason       string     `json:"reason,omitempty"`
+	Role         string     `json:"role,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// ModerationService 聊天室管理服务
+type ModerationService struct {
+	roomService   *RoomService
+	roomMemberDAL *dal.RoomMemberDAL
+	sanctionDAL   *dal.SanctionDAL
+	userDAL       *dal.UserDAL
+}
+
+// NewModerationService 创建聊天室管理服务实例
+func NewModerationService() *ModerationService {
+	return &ModerationService{
+		roomService:   NewRoomService(),
+		roomMemberDAL: dal.NewRoomMemberDAL(),
+		sanctionDAL:   dal.NewSanctionDAL(),
+		userDAL:       dal.NewUserDAL(),
+	}
+}
+
+// KickMember 将成员踢出聊天室（协管员及以上，且角色高于对方）
+func (s *ModerationService) KickMember(operatorID, roomID, targetID uint) (*ModerationResult, error) {
+	targetRole, err := s.checkAuthority(operatorID, roomID, targetID, entities.RoomRoleModerator)
+	if err != nil {
+		return nil, err
+	}
+	if targetRole == "" {
+		return nil, ErrModerationNotMember
+	}
+
+	if err := s.roomMemberDAL.Delete(roomID, targetID); err != nil {
+		return nil, err
+	}
+
+	emitMemberEvent(entities.WebhookEventMemberLeft, roomID, targetID, MemberLeftReasonKick)
+
+	return s.newResult(ModerationKick, operatorID, roomID, targetID)
+}
+
+// BanMember 封禁用户（管理员及以上），duration为0表示永久封禁
+// 被封禁的成员会同时被移出聊天室
+func (s *ModerationService) BanMember(operatorID, roomID, targetID uint, reason string, duration time.Duration) (*ModerationResult, error) {
+	targetRole, err := s.checkAuthority(operatorID, roomID, targetID, entities.RoomRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	sanction := newSanction(roomID, targetID, operatorID, entities.SanctionBan, reason, duration)
+	if err := s.sanctionDAL.Upsert(sanction); err != nil {
+		return nil, err
+	}
+	if err := s.roomMemberDAL.Delete(roomID, targetID); err != nil {
+		return nil, err
+	}
+	if targetRole != "" {
+		emitMemberEvent(entities.WebhookEventMemberLeft, roomID, targetID, MemberLeftReasonBan)
+	}
+
+	result, err := s.newResult(ModerationBan, operatorID, roomID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	result.Reason = reason
+	result.ExpiresAt = sanction.ExpiresAt
+	return result, nil
+}
+
+// UnbanMember 解除封禁（管理员及以上）
+func (s *ModerationService) UnbanMember(operatorID, roomID, targetID uint) (*ModerationResult, error) {
+	if _, err := s.checkAuthority(operatorID, roomID, targetID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.sanctionDAL.Delete(roomID, targetID, entities.SanctionBan); err != nil {
+		return nil, err
+	}
+
+	return s.newResult(ModerationUnban, operatorID, roomID, targetID)
+}
+
+// MuteMember 禁言成员（协管员及以上），duration为0表示永久禁言
+func (s *ModerationService) MuteMember(operatorID, roomID, targetID uint, reason string, duration time.Duration) (*ModerationResult, error) {
+	targetRole, err := s.checkAuthority(operatorID, roomID, targetID, entities.RoomRoleModerator)
+	if err != nil {
+		return nil, err
+	}
+	if targetRole == "" {
+		return nil, ErrModerationNotMember
+	}
+
+	sanction := newSanction(roomID, targetID, operatorID, entities.SanctionMute, reason, duration)
+	if err := s.sanctionDAL.Upsert(sanction); err != nil {
+		return nil, err
+	}
+
+	result, err := s.newResult(ModerationMute, operatorID, roomID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	result.Reason = reason
+	result.ExpiresAt = sanction.ExpiresAt
+	return result, nil
+}
+
+// UnmuteMember 解除禁言（协管员及以上）
+func (s *ModerationService) UnmuteMember(operatorID, roomID, targetID uint) (*ModerationResult, error) {
+	if _, err := s.checkAuthority(operatorID, roomID, targetID, entities.RoomRoleModerator); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.sanctionDAL.Delete(roomID, targetID, entities.SanctionMute); err != nil {
+		return nil, err
+	}
+
+	return s.newResult(ModerationUnmute, operatorID, roomID, targetID)
+}
+
+// SetMemberRole 调整成员角色（管理员及以上，只能授予低于自己的角色）
+// 群主转让不通过该接口
+func (s *ModerationService) SetMemberRole(operatorID, roomID, targetID uint, role string) (*ModerationResult, error) {
+	level := entities.RoomRoleLevel(role)
+	if level == 0 || role == entities.RoomRoleOwner {
+		return nil, ErrModerationInvalidRole
+	}
+
+	targetRole, err := s.checkAuthority(operatorID, roomID, targetID, entities.RoomRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if targetRole == "" {
+		return nil, ErrModerationNotMember
+	}
+
+	operatorRole, err := s.roomService.GetMemberRole(roomID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if level >= entities.RoomRoleLevel(operatorRole) {
+		return nil, ErrModerationNoAuthority
+	}
+
+	if err := s.roomMemberDAL.UpdateRole(roomID, targetID, role); err != nil {
+		return nil, err
+	}
+
+	result, err := s.newResult(ModerationRole, operatorID, roomID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	result.Role = role
+	return result, nil
+}
+
+// GetActiveSanctions 获取聊天室中生效的处罚列表（协管员及以上）
+func (s *ModerationService) GetActiveSanctions(operatorID, roomID uint) ([]*entities.RoomSanction, error) {
+	allowed, err := s.roomService.HasRoomRole(operatorID, roomID, entities.RoomRoleModerator)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrModerationNoAuthority
+	}
+	return s.sanctionDAL.GetActiveByRoom(roomID)
+}
+
+// checkAuthority 检查操作者是否有权限管理目标用户，返回目标用户当前角色（非成员为空）
+func (s *ModerationService) checkAuthority(operatorID, roomID, targetID uint, minRole string) (string, error) {
+	if operatorID == targetID {
+		return "", ErrModerationSelf
+	}
+
+	room, err := s.roomService.GetRoomByID(roomID)
+	if err != nil {
+		return "", err
+	}
+	if room.Kind == entities.RoomKindDirect {
+		return "", ErrModerationDirectRoom
+	}
+
+	if _, err := s.userDAL.GetByID(targetID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+
+	operatorRole, err := s.roomService.GetMemberRole(roomID, operatorID)
+	if err != nil {
+		return "", err
+	}
+	targetRole, err := s.roomService.GetMemberRole(roomID, targetID)
+	if err != nil {
+		return "", err
+	}
+
+	operatorLevel := entities.RoomRoleLevel(operatorRole)
+	if operatorLevel < entities.RoomRoleLevel(minRole) {
+		return "", ErrModerationNoAuthority
+	}
+	// 只能管理角色低于自己的用户
+	if operatorLevel <= entities.RoomRoleLevel(targetRole) {
+		return "", ErrModerationNoAuthority
+	}
+
+	return targetRole, nil
+}
+
+// newResult 构造管理操作结果
+func (s *ModerationService) newResult(action string, operatorID, roomID, targetID uint) (*ModerationResult, error) {
+	operator, err := s.userDAL.GetByID(operatorID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.userDAL.GetByID(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ModerationResult{
+		Action:       action,
+		RoomID:       roomID,
+		OperatorID:   operatorID,
+		OperatorName: operator.Username,
+		TargetID:     targetID,
+		TargetName:   target.Username,
+	}, nil
+}
+
+// newSanction 构造处罚记录
+func newSanction(roomID, userID, operatorID uint, sanctionType, reason string, duration time.Duration) *entities.RoomSanction {
+	sanction := &entities.RoomSanction{
+		RoomID:    roomID,
+		UserID:    userID,
+		Type:      sanctionType,
+		Reason:    reason,
+		CreatedBy: operatorID,
+		CreatedAt: time.Now(),
+	}
+	if duration > 0 {
+		expiresAt := time.Now().Add(duration)
+		sanction.ExpiresAt = &expiresAt
+	}
+	return sanction
+}
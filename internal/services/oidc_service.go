@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gochat/internal/config"
+	"gochat/internal/dal"
+	"gochat/internal/mailer"
+	"gochat/internal/models/entities"
+	"gochat/internal/models/responses"
+	"gochat/internal/oidc"
+	"gochat/pkg/logger"
+	"gochat/pkg/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OIDCStateTTL 授权请求有效期，浏览器绑定Cookie的有效期与之一致
+const OIDCStateTTL = 10 * time.Minute
+
+// OIDC登录参数
+const (
+	oidcStateBytes     = 32
+	oidcBindingBytes   = 32 // 浏览器绑定值随机字节数
+	oidcNonceBytes     = 16
+	oidcVerifierBytes  = 32 // PKCE校验码随机字节数（十六进制编码后64个字符，满足43~128的长度要求）
+	oidcUsernameMaxLen = 50
+	oidcUsernameMinLen = 3
+	oidcUsernameTries  = 5 // 用户名冲突时追加随机后缀的尝试次数
+)
+
+// OIDC登录错误
+var (
+	ErrOIDCProviderNotFound = errors.New("未配置该身份提供方")
+	ErrOIDCStateInvalid     = errors.New("登录请求已失效，请重新登录")
+	ErrOIDCLoginFailed      = errors.New("身份提供方登录失败")
+	ErrOIDCEmailConflict    = errors.New("该邮箱已注册，请使用密码登录")
+	ErrOIDCAccountDisabled  = errors.New("账号已被禁用")
+)
+
+// OIDCService OpenID Connect登录服务（授权码 + PKCE），首次登录自动开通账号
+type OIDCService struct {
+	userDAL     *dal.UserDAL
+	identityDAL *dal.IdentityDAL
+	authService *AuthService
+	providers   map[string]*oidc.Provider
+	trustEmail  map[string]bool
+}
+
+// NewOIDCService 创建OIDC登录服务实例
+func NewOIDCService(cfg *config.Config, mail mailer.Mailer) *OIDCService {
+	s := &OIDCService{
+		userDAL:     dal.NewUserDAL(),
+		identityDAL: dal.NewIdentityDAL(),
+		authService: NewAuthService(&cfg.JWT, &cfg.Auth, mail),
+		providers:   make(map[string]*oidc.Provider, len(cfg.OIDC.Providers)),
+		trustEmail:  make(map[string]bool, len(cfg.OIDC.Providers)),
+	}
+	for name, providerCfg := range cfg.OIDC.Providers {
+		s.providers[name] = oidc.NewProvider(name, providerCfg)
+		s.trustEmail[name] = providerCfg.TrustEmail
+	}
+	return s
+}
+
+// BeginLogin 创建授权请求，返回跳转到身份提供方的地址和浏览器绑定值
+// 绑定值需保存在发起登录的浏览器中（Cookie），回调时一并提交，防止登录CSRF
+func (s *OIDCService) BeginLogin(ctx context.Context, providerName string) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrOIDCProviderNotFound
+	}
+
+	state, err := utils.GenerateRandomToken(oidcStateBytes)
+	if err != nil {
+		return "", "", errors.New("系统错误")
+	}
+	nonce, err := utils.GenerateRandomToken(oidcNonceBytes)
+	if err != nil {
+		return "", "", errors.New("系统错误")
+	}
+	verifier, err := utils.GenerateRandomToken(oidcVerifierBytes)
+	if err != nil {
+		return "", "", errors.New("系统错误")
+	}
+	binding, err := utils.GenerateRandomToken(oidcBindingBytes)
+	if err != nil {
+		return "", "", errors.New("系统错误")
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		logger.Error("OIDC authorization url failed:", providerName, err)
+		return "", "", ErrOIDCLoginFailed
+	}
+
+	if err := s.identityDAL.CreateState(&entities.OIDCState{
+		StateHash:    utils.HashToken(state),
+		BindingHash:  utils.HashToken(binding),
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(OIDCStateTTL),
+	}); err != nil {
+		return "", "", errors.New("系统错误")
+	}
+	return authURL, binding, nil
+}
+
+// CompleteLogin 处理身份提供方回调：校验state及浏览器绑定值、换取并校验ID Token，然后签发本地令牌
+// 与密码登录一样检查账号锁定，启用两步验证的用户返回登录挑战，需调用LoginTwoFactor完成登录
+func (s *OIDCService) CompleteLogin(ctx context.Context, providerName, state, code, binding string, meta *SessionMeta) (*responses.AuthResponse, *responses.TwoFactorChallenge, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, nil, ErrOIDCProviderNotFound
+	}
+	if state == "" || code == "" || binding == "" {
+		return nil, nil, ErrOIDCStateInvalid
+	}
+
+	stored, err := s.identityDAL.ConsumeState(utils.HashToken(state))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, ErrOIDCStateInvalid
+		}
+		return nil, nil, errors.New("系统错误")
+	}
+	if stored.Provider != providerName || !stored.ExpiresAt.After(time.Now()) {
+		return nil, nil, ErrOIDCStateInvalid
+	}
+	// 回调必须来自发起登录的浏览器，否则可能是攻击者诱导受害者登录攻击者的账号
+	if subtle.ConstantTimeCompare([]byte(stored.BindingHash), []byte(utils.HashToken(binding))) != 1 {
+		return nil, nil, ErrOIDCStateInvalid
+	}
+
+	token, err := provider.Exchange(ctx, code, stored.CodeVerifier)
+	if err != nil {
+		logger.Error("OIDC token exchange failed:", providerName, err)
+		return nil, nil, ErrOIDCLoginFailed
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, stored.Nonce)
+	if err != nil {
+		logger.Error("OIDC id_token verification failed:", providerName, err)
+		return nil, nil, ErrOIDCLoginFailed
+	}
+	if claims.Email == "" {
+		if err := provider.FetchUserInfo(ctx, token.AccessToken, claims); err != nil {
+			logger.Warn("OIDC userinfo request failed:", providerName, err)
+		}
+	}
+
+	user, err := s.resolveUser(providerName, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.authService.LoginVerified(user, meta)
+}
+
+// resolveUser 查找外部身份关联的用户；未关联时按配置关联同邮箱账号或自动开通新账号
+func (s *OIDCService) resolveUser(providerName string, claims *oidc.Claims) (*entities.User, error) {
+	identity, err := s.identityDAL.GetIdentity(providerName, claims.Subject)
+	if err == nil {
+		user, err := s.userDAL.GetByID(identity.UserID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrOIDCAccountDisabled
+			}
+			return nil, errors.New("系统错误")
+		}
+		if err := s.identityDAL.TouchIdentity(identity.ID, claims.Email); err != nil {
+			logger.Error("Failed to update identity:", err)
+		}
+		return user, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, errors.New("系统错误")
+	}
+
+	now := time.Now()
+	identity = &entities.UserIdentity{
+		Provider:    providerName,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+	}
+
+	email := claims.Email
+	if email != "" {
+		existing, err := s.userDAL.GetByEmail(email)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return nil, errors.New("系统错误")
+		}
+		if existing != nil {
+			// 只有身份提供方受信任且邮箱已验证时才关联已有账号，否则可能被冒用
+			if !s.trustEmail[providerName] || !claims.EmailVerified {
+				return nil, ErrOIDCEmailConflict
+			}
+			identity.UserID = existing.ID
+			if err := s.identityDAL.CreateIdentity(identity); err != nil {
+				return nil, errors.New("系统错误")
+			}
+			return existing, nil
+		}
+	} else {
+		email = placeholderEmail(providerName, claims.Subject)
+	}
+
+	return s.provisionUser(providerName, claims, email, identity)
+}
+
+// provisionUser 首次登录时自动开通账号，账号没有可用的本地密码
+func (s *OIDCService) provisionUser(providerName string, claims *oidc.Claims, email string, identity *entities.UserIdentity) (*entities.User, error) {
+	username, err := s.availableUsername(providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// 随机密码的哈希，用户需通过重置密码才能使用密码登录
+	randomPassword, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.New("系统错误")
+	}
+	passwordHash, err := utils.HashPassword(randomPassword)
+	if err != nil {
+		return nil, errors.New("系统错误")
+	}
+
+	user := &entities.User{
+		Username:     username,
+		Email:        email,
+		PasswordHash: passwordHash,
+		Role:         entities.UserRoleUser,
+	}
+	if err := s.identityDAL.CreateUserWithIdentity(user, identity); err != nil {
+		logger.Error("Failed to provision OIDC user:", err)
+		return nil, errors.New("用户创建失败")
+	}
+	logger.Info("OIDC user provisioned:", username, "provider:", providerName)
+	return user, nil
+}
+
+// availableUsername 根据身份信息生成未被占用的用户名
+func (s *OIDCService) availableUsername(providerName string, claims *oidc.Claims) (string, error) {
+	base := ""
+	for _, candidate := range []string{claims.PreferredUsername, emailLocalPart(claims.Email), claims.Name} {
+		if base = sanitizeUsername(candidate); base != "" {
+			break
+		}
+	}
+	if len(base) < oidcUsernameMinLen {
+		base = sanitizeUsername(providerName + "_" + base)
+	}
+
+	username := base
+	for i := 0; i < oidcUsernameTries; i++ {
+		exists, err := s.userDAL.CheckUsernameExists(username)
+		if err != nil {
+			return "", errors.New("系统错误")
+		}
+		if !exists {
+			return username, nil
+		}
+
+		suffix, err := utils.GenerateRandomToken(2)
+		if err != nil {
+			return "", errors.New("系统错误")
+		}
+		if len(base) > oidcUsernameMaxLen-len(suffix)-1 {
+			base = base[:oidcUsernameMaxLen-len(suffix)-1]
+		}
+		username = base + "_" + suffix
+	}
+	return "", errors.New("用户名已存在")
+}
+
+// sanitizeUsername 只保留字母、数字、下划线、点和连字符，最长oidcUsernameMaxLen个字符
+func sanitizeUsername(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if b.Len() >= oidcUsernameMaxLen {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// emailLocalPart 获取邮箱@之前的部分
+func emailLocalPart(email string) string {
+	if at := strings.IndexByte(email, '@'); at > 0 {
+		return email[:at]
+	}
+	return ""
+}
+
+// placeholderEmail 身份提供方未提供邮箱时生成不可投递的占位邮箱
+func placeholderEmail(providerName, subject string) string {
+	sum := sha256.Sum256([]byte(providerName + ":" + subject))
+	return fmt.Sprintf("%s@%s.oidc.invalid", hex.EncodeToString(sum[:8]), sanitizeUsername(providerName))
+}
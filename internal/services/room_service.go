@@ -1,14 +1,36 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"gochat/internal/dal"
 	"gochat/internal/models/entities"
+	"gochat/internal/models/responses"
+
+	"gorm.io/gorm"
+)
+
+// 聊天室操作错误
+var (
+	ErrDirectRoomSelf    = errors.New("不能与自己私聊")
+	ErrDirectRoomJoin    = errors.New("不能加入他人的私聊")
+	ErrUserNotFound      = errors.New("用户不存在")
+	ErrRoomBanned        = errors.New("你已被禁止加入该聊天室")
+	ErrRoomNotFound      = errors.New("聊天室不存在")
+	ErrRoomNoAuthority   = errors.New("无权限管理该聊天室")
+	ErrDirectRoomManage  = errors.New("私聊不支持该操作")
+	ErrTransferSelf      = errors.New("不能将群主转让给自己")
+	ErrTransferNotMember = errors.New("只能转让给聊天室成员")
+	ErrPrivateRoomJoin   = errors.New("私有聊天室需要通过邀请或申请加入")
 )
 
 // RoomService 聊天室服务
 type RoomService struct {
 	roomDAL       *dal.RoomDAL
 	roomMemberDAL *dal.RoomMemberDAL
+	messageDAL    *dal.MessageDAL
+	userDAL       *dal.UserDAL
+	sanctionDAL   *dal.SanctionDAL
 }
 
 // NewRoomService 创建聊天室服务实例
@@ -16,12 +38,18 @@ func NewRoomService() *RoomService {
 	return &RoomService{
 		roomDAL:       dal.NewRoomDAL(),
 		roomMemberDAL: dal.NewRoomMemberDAL(),
+		messageDAL:    dal.NewMessageDAL(),
+		userDAL:       dal.NewUserDAL(),
+		sanctionDAL:   dal.NewSanctionDAL(),
 	}
 }
 
-// CreateRoom 创建聊天室
+// CreateRoom 创建聊天室，创建者自动成为群主
 func (s *RoomService) CreateRoom(room *entities.Room) (*entities.Room, error) {
-	return s.roomDAL.Create(room)
+	members := []*entities.RoomMember{
+		{UserID: room.CreatedBy, Role: entities.RoomRoleOwner},
+	}
+	return s.roomDAL.CreateWithMembers(room, members)
 }
 
 // GetRoomByID 根据ID获取聊天室
@@ -39,8 +67,54 @@ func (s *RoomService) GetUserRooms(userID uint) ([]*entities.Room, error) {
 	return s.roomDAL.GetUserRooms(userID)
 }
 
+// GetUserRoomsWithUnread 获取用户加入的聊天室及每个聊天室的未读消息数量
+func (s *RoomService) GetUserRoomsWithUnread(userID uint) ([]*responses.RoomListItem, error) {
+	rooms, err := s.roomDAL.GetUserRooms(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roomIDs := make([]uint, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
+
+	unreadCounts, err := s.messageDAL.GetUnreadCounts(userID, roomIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*responses.RoomListItem, 0, len(rooms))
+	for _, room := range rooms {
+		items = append(items, &responses.RoomListItem{
+			Room:        room,
+			UnreadCount: unreadCounts[room.ID],
+		})
+	}
+	return items, nil
+}
+
 // JoinRoom 用户加入聊天室
 func (s *RoomService) JoinRoom(userID, roomID uint) error {
+	room, err := s.roomDAL.GetByID(roomID)
+	if err != nil {
+		return err
+	}
+
+	// 私聊只能通过私聊接口创建，不能直接加入
+	if room.Kind == entities.RoomKindDirect {
+		return ErrDirectRoomJoin
+	}
+
+	// 被封禁的用户不能加入
+	banned, err := s.IsBanned(roomID, userID)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return ErrRoomBanned
+	}
+
 	// 先检查用户是否已经在聊天室中
 	isMember, err := s.roomMemberDAL.IsMember(roomID, userID)
 	if err != nil {
@@ -52,18 +126,40 @@ func (s *RoomService) JoinRoom(userID, roomID uint) error {
 		return nil
 	}
 
+	// 私有聊天室只能通过邀请链接或加入申请加入
+	if room.IsPrivate {
+		return ErrPrivateRoomJoin
+	}
+
 	// 用户不在聊天室中，创建新的成员记录
 	member := &entities.RoomMember{
 		RoomID: roomID,
 		UserID: userID,
-		Role:   "member",
+		Role:   entities.RoomRoleMember,
+	}
+	if err := s.roomMemberDAL.Create(member); err != nil {
+		return err
 	}
-	return s.roomMemberDAL.Create(member)
+
+	emitMemberEvent(entities.WebhookEventMemberJoined, roomID, userID, "")
+	return nil
 }
 
 // LeaveRoom 用户离开聊天室
 func (s *RoomService) LeaveRoom(userID, roomID uint) error {
-	return s.roomMemberDAL.Delete(roomID, userID)
+	isMember, err := s.roomMemberDAL.IsMember(roomID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roomMemberDAL.Delete(roomID, userID); err != nil {
+		return err
+	}
+
+	if isMember {
+		emitMemberEvent(entities.WebhookEventMemberLeft, roomID, userID, MemberLeftReasonLeave)
+	}
+	return nil
 }
 
 // CanUserJoinRoom 检查用户是否可以加入聊天室
@@ -74,6 +170,12 @@ func (s *RoomService) CanUserJoinRoom(userID, roomID uint) (bool, error) {
 		return false, err
 	}
 
+	// 被封禁的用户不能加入
+	banned, err := s.IsBanned(roomID, userID)
+	if err != nil || banned {
+		return false, err
+	}
+
 	// 如果是私聊，需要验证权限
 	if room.IsPrivate {
 		return s.roomMemberDAL.IsMember(roomID, userID)
@@ -83,17 +185,307 @@ func (s *RoomService) CanUserJoinRoom(userID, roomID uint) (bool, error) {
 	return true, nil
 }
 
+// GetMemberRole 获取用户在聊天室中的角色，非成员返回空字符串
+// 早期创建的聊天室没有群主成员记录，此时创建者视为群主
+func (s *RoomService) GetMemberRole(roomID, userID uint) (string, error) {
+	member, err := s.roomMemberDAL.GetMember(roomID, userID)
+	if err == nil {
+		return member.Role, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return "", err
+	}
+
+	room, err := s.roomDAL.GetByID(roomID)
+	if err != nil {
+		return "", err
+	}
+	if room.CreatedBy != userID {
+		return "", nil
+	}
+
+	hasOwner, err := s.roomMemberDAL.HasOwnerRecord(roomID)
+	if err != nil {
+		return "", err
+	}
+	if hasOwner {
+		return "", nil
+	}
+	return entities.RoomRoleOwner, nil
+}
+
+// CheckReadAccess 检查用户是否可以读取聊天室消息，私有聊天室和私聊只有成员可以读取
+// requireMember为true时公开聊天室也要求是成员（机器人被踢出或封禁后不能继续读取）
+func (s *RoomService) CheckReadAccess(roomID, userID uint, requireMember bool) error {
+	room, err := s.roomDAL.GetByID(roomID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrRoomNotFound
+		}
+		return err
+	}
+	if !requireMember && !room.IsPrivate && room.Kind != entities.RoomKindDirect {
+		return nil
+	}
+
+	role, err := s.GetMemberRole(roomID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrNotRoomMember
+	}
+	return nil
+}
+
+// HasRoomRole 检查用户在聊天室中的角色是否不低于指定角色
+func (s *RoomService) HasRoomRole(userID, roomID uint, minRole string) (bool, error) {
+	role, err := s.GetMemberRole(roomID, userID)
+	if err != nil {
+		return false, err
+	}
+	return entities.RoomRoleLevel(role) >= entities.RoomRoleLevel(minRole), nil
+}
+
+// IsRoomAdmin 检查用户是否是聊天室管理员（群主或管理员）
+func (s *RoomService) IsRoomAdmin(userID, roomID uint) (bool, error) {
+	return s.HasRoomRole(userID, roomID, entities.RoomRoleAdmin)
+}
+
+// IsBanned 检查用户是否被禁止加入聊天室
+func (s *RoomService) IsBanned(roomID, userID uint) (bool, error) {
+	return s.hasActiveSanction(roomID, userID, entities.SanctionBan)
+}
+
+// IsMuted 检查用户是否在聊天室中被禁言
+func (s *RoomService) IsMuted(roomID, userID uint) (bool, error) {
+	return s.hasActiveSanction(roomID, userID, entities.SanctionMute)
+}
+
+// hasActiveSanction 检查用户在聊天室中是否有生效的处罚
+func (s *RoomService) hasActiveSanction(roomID, userID uint, sanctionType string) (bool, error) {
+	_, err := s.sanctionDAL.GetActive(roomID, userID, sanctionType)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // GetRoomMembers 获取聊天室成员
 func (s *RoomService) GetRoomMembers(roomID uint) ([]*entities.RoomMember, error) {
 	return s.roomMemberDAL.GetByRoomID(roomID)
 }
 
-// UpdateRoom 更新聊天室信息
-func (s *RoomService) UpdateRoom(roomID uint, name, description string) (*entities.Room, error) {
+// UpdateRoom 更新聊天室信息（管理员及以上）
+func (s *RoomService) UpdateRoom(operatorID, roomID uint, name, description string) (*entities.Room, error) {
+	if _, err := s.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
 	return s.roomDAL.Update(roomID, name, description)
 }
 
-// DeleteRoom 删除聊天室
-func (s *RoomService) DeleteRoom(roomID uint) error {
+// DeleteRoom 删除聊天室（仅群主）
+func (s *RoomService) DeleteRoom(operatorID, roomID uint) error {
+	if _, err := s.checkRoomAuthority(operatorID, roomID, entities.RoomRoleOwner); err != nil {
+		return err
+	}
 	return s.roomDAL.Delete(roomID)
 }
+
+// TransferOwnership 将群主转让给聊天室成员（仅群主），原群主降为管理员
+// 返回聊天室和新群主
+func (s *RoomService) TransferOwnership(operatorID, roomID, newOwnerID uint) (*entities.Room, *entities.User, error) {
+	room, err := s.checkRoomAuthority(operatorID, roomID, entities.RoomRoleOwner)
+	if err != nil {
+		return nil, nil, err
+	}
+	if operatorID == newOwnerID {
+		return nil, nil, ErrTransferSelf
+	}
+
+	isMember, err := s.roomMemberDAL.IsMember(roomID, newOwnerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isMember {
+		return nil, nil, ErrTransferNotMember
+	}
+
+	newOwner, err := s.userDAL.GetByID(newOwnerID)
+	if err != nil {
+		return nil, nil, ErrUserNotFound
+	}
+
+	if err := s.roomMemberDAL.TransferOwnership(roomID, operatorID, newOwnerID); err != nil {
+		return nil, nil, err
+	}
+	return room, newOwner, nil
+}
+
+// checkRoomAuthority 检查用户是否有权限管理聊天室，返回聊天室
+func (s *RoomService) checkRoomAuthority(operatorID, roomID uint, minRole string) (*entities.Room, error) {
+	room, err := s.roomDAL.GetByID(roomID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRoomNotFound
+		}
+		return nil, err
+	}
+	if room.Kind == entities.RoomKindDirect {
+		return nil, ErrDirectRoomManage
+	}
+
+	allowed, err := s.HasRoomRole(operatorID, roomID, minRole)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrRoomNoAuthority
+	}
+	return room, nil
+}
+
+// GetOrCreateDirectRoom 获取或创建两个用户之间的私聊（幂等），返回聊天室以及是否为新创建
+func (s *RoomService) GetOrCreateDirectRoom(userID, peerID uint) (*entities.Room, bool, error) {
+	if userID == peerID {
+		return nil, false, ErrDirectRoomSelf
+	}
+
+	user, err := s.userDAL.GetByID(userID)
+	if err != nil {
+		return nil, false, ErrUserNotFound
+	}
+	peer, err := s.userDAL.GetByID(peerID)
+	if err != nil {
+		return nil, false, ErrUserNotFound
+	}
+
+	directKey := directRoomKey(userID, peerID)
+
+	room, err := s.roomDAL.GetByDirectKey(directKey)
+	if err == nil {
+		// 已删除的私聊恢复使用，否则唯一索引冲突无法重新创建
+		if room.DeletedAt.Valid {
+			if err := s.roomDAL.RestoreRoom(room.ID); err != nil {
+				return nil, false, err
+			}
+			room.DeletedAt = gorm.DeletedAt{}
+		}
+		// 已存在的私聊，确保双方仍是成员（用户可能退出过）
+		for _, memberID := range []uint{userID, peerID} {
+			if err := s.ensureMember(room.ID, memberID); err != nil {
+				return nil, false, err
+			}
+		}
+		return room, false, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, false, err
+	}
+
+	room = &entities.Room{
+		Name:      fmt.Sprintf("%s & %s", user.Username, peer.Username),
+		IsPrivate: true,
+		Kind:      entities.RoomKindDirect,
+		DirectKey: &directKey,
+		CreatedBy: userID,
+	}
+	members := []*entities.RoomMember{
+		{UserID: userID, Role: entities.RoomRoleMember},
+		{UserID: peerID, Role: entities.RoomRoleMember},
+	}
+
+	createdRoom, err := s.roomDAL.CreateWithMembers(room, members)
+	if err != nil {
+		// 并发创建时唯一索引冲突，返回已创建的私聊
+		if existing, findErr := s.roomDAL.GetByDirectKey(directKey); findErr == nil {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+
+	return createdRoom, true, nil
+}
+
+// GetUserDirectRooms 获取用户的私聊列表，包含私聊对象和未读消息数量
+func (s *RoomService) GetUserDirectRooms(userID uint) ([]*responses.DirectRoomItem, error) {
+	rooms, err := s.roomDAL.GetUserDirectRooms(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roomIDs := make([]uint, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
+
+	peers, err := s.roomMemberDAL.GetPeers(roomIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	peerByRoom := make(map[uint]*responses.UserInfo, len(peers))
+	for _, member := range peers {
+		peerByRoom[member.RoomID] = &responses.UserInfo{
+			ID:        member.User.ID,
+			Username:  member.User.Username,
+			Email:     member.User.Email,
+			AvatarURL: member.User.AvatarURL,
+		}
+	}
+
+	unreadCounts, err := s.messageDAL.GetUnreadCounts(userID, roomIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*responses.DirectRoomItem, 0, len(rooms))
+	for _, room := range rooms {
+		items = append(items, &responses.DirectRoomItem{
+			Room:        room,
+			Peer:        peerByRoom[room.ID],
+			UnreadCount: unreadCounts[room.ID],
+		})
+	}
+	return items, nil
+}
+
+// ensureMember 确保用户是聊天室成员，已退出的成员会被恢复
+func (s *RoomService) ensureMember(roomID, userID uint) error {
+	isMember, err := s.roomMemberDAL.IsMember(roomID, userID)
+	if err != nil || isMember {
+		return err
+	}
+
+	if err := s.roomMemberDAL.RestoreMember(roomID, userID); err != nil {
+		return err
+	}
+	isMember, err = s.roomMemberDAL.IsMember(roomID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !isMember {
+		err = s.roomMemberDAL.Create(&entities.RoomMember{
+			RoomID: roomID,
+			UserID: userID,
+			Role:   entities.RoomRoleMember,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	emitMemberEvent(entities.WebhookEventMemberJoined, roomID, userID, "")
+	return nil
+}
+
+// directRoomKey 生成私聊唯一标识，与双方顺序无关
+func directRoomKey(userID, peerID uint) string {
+	if userID > peerID {
+		userID, peerID = peerID, userID
+	}
+	return fmt.Sprintf("dm:%d:%d", userID, peerID)
+}
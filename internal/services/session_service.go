@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"gochat/internal/config"
+	"gochat/internal/dal"
+	"gochat/internal/models/responses"
+
+	"gorm.io/gorm"
+)
+
+// 登录会话错误
+var ErrSessionNotFound = errors.New("会话不存在")
+
+// SessionService 登录会话服务，负责查看和注销用户的登录设备
+type SessionService struct {
+	sessionDAL   *dal.SessionDAL
+	tokenService *TokenService
+}
+
+// NewSessionService 创建登录会话服务实例
+func NewSessionService(cfg *config.JWTConfig) *SessionService {
+	return &SessionService{
+		sessionDAL:   dal.NewSessionDAL(),
+		tokenService: NewTokenService(cfg),
+	}
+}
+
+// ListSessions 获取用户所有有效的登录会话，currentSessionID为当前请求使用的会话
+func (s *SessionService) ListSessions(userID, currentSessionID uint) ([]*responses.SessionInfo, error) {
+	sessions, err := s.sessionDAL.GetActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*responses.SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, &responses.SessionInfo{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return items, nil
+}
+
+// RevokeSession 注销用户的指定登录会话，只能注销自己的会话
+func (s *SessionService) RevokeSession(userID, sessionID uint) error {
+	session, err := s.sessionDAL.GetByID(sessionID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	if session.UserID != userID || !session.IsActive() {
+		return ErrSessionNotFound
+	}
+
+	return s.tokenService.RevokeSession(sessionID)
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"gochat/internal/models/requests"
+	"regexp"
+	"strings"
+)
+
+// slackEntityPattern Slack消息中的尖括号实体，如 <https://a.b|标签>、<@U123>、<!here>
+var slackEntityPattern = regexp.MustCompile(`<([^<>]+)>`)
+
+// slackUnescaper 还原Slack要求转义的三个字符
+var slackUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// RenderSlackMessage 将Slack incoming-webhook消息转换为纯文本消息内容
+// 有blocks时以blocks为正文（text仅作通知摘要），否则使用text；附件依次追加在正文之后
+func RenderSlackMessage(msg *requests.SlackMessage) string {
+	var parts []string
+	if len(msg.Blocks) > 0 {
+		parts = appendNonEmpty(parts, renderSlackBlocks(msg.Blocks))
+	}
+	if len(parts) == 0 {
+		parts = appendNonEmpty(parts, msg.Text)
+	}
+	for i := range msg.Attachments {
+		parts = appendNonEmpty(parts, renderSlackAttachment(&msg.Attachments[i]))
+	}
+	return strings.TrimSpace(convertSlackMarkup(strings.Join(parts, "\n\n")))
+}
+
+// renderSlackBlocks 按顺序渲染Block Kit布局块，不支持的块类型被忽略
+func renderSlackBlocks(blocks []requests.SlackBlock) string {
+	var lines []string
+	for _, block := range blocks {
+		switch block.Type {
+		case "section":
+			if block.Text != nil {
+				lines = appendNonEmpty(lines, block.Text.Text)
+			}
+			for _, field := range block.Fields {
+				lines = appendNonEmpty(lines, field.Text)
+			}
+		case "header":
+			if block.Text != nil {
+				lines = appendNonEmpty(lines, "*"+strings.TrimSpace(block.Text.Text)+"*")
+			}
+		case "context":
+			var texts []string
+			for _, element := range block.Elements {
+				if element.Type == "image" {
+					texts = appendNonEmpty(texts, element.AltText)
+				} else {
+					texts = appendNonEmpty(texts, string(element.Text))
+				}
+			}
+			lines = appendNonEmpty(lines, strings.Join(texts, " "))
+		case "divider":
+			lines = append(lines, "---")
+		case "image":
+			var title string
+			if block.Title != nil {
+				title = block.Title.Text
+			} else {
+				title = block.AltText
+			}
+			lines = appendNonEmpty(lines, slackLink(block.ImageURL, title))
+		case "actions":
+			// 只保留带链接的按钮，交互回调不受支持
+			for _, element := range block.Elements {
+				if element.Type == "button" && element.URL != "" {
+					lines = append(lines, slackLink(element.URL, string(element.Text)))
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderSlackAttachment 渲染旧版消息附件；附件只有fallback时使用fallback
+func renderSlackAttachment(attachment *requests.SlackAttachment) string {
+	var lines []string
+	lines = appendNonEmpty(lines, attachment.Pretext)
+	lines = appendNonEmpty(lines, slackLink(attachment.AuthorLink, attachment.AuthorName))
+	if attachment.Title != "" {
+		lines = append(lines, slackLink(attachment.TitleLink, "*"+strings.TrimSpace(attachment.Title)+"*"))
+	}
+	lines = appendNonEmpty(lines, attachment.Text)
+	for _, field := range attachment.Fields {
+		switch {
+		case field.Title != "" && field.Value != "":
+			lines = append(lines, field.Title+": "+field.Value)
+		default:
+			lines = appendNonEmpty(lines, field.Title+field.Value)
+		}
+	}
+	if len(attachment.Blocks) > 0 {
+		lines = appendNonEmpty(lines, renderSlackBlocks(attachment.Blocks))
+	}
+	lines = appendNonEmpty(lines, attachment.ImageURL)
+	lines = appendNonEmpty(lines, attachment.Footer)
+
+	if len(lines) == 0 {
+		return strings.TrimSpace(attachment.Fallback)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// convertSlackMarkup 将Slack的链接、提及等尖括号实体转换为可读文本，并还原转义字符
+func convertSlackMarkup(text string) string {
+	text = slackEntityPattern.ReplaceAllStringFunc(text, func(entity string) string {
+		inner := entity[1 : len(entity)-1]
+		target, label, _ := strings.Cut(inner, "|")
+
+		switch {
+		case strings.HasPrefix(target, "!"):
+			// <!here>、<!channel>、<!everyone>、<!subteam^ID|@group>、<!date^...|fallback>
+			name := strings.TrimPrefix(target, "!")
+			switch name {
+			case "here", "channel", "everyone":
+				return "@" + name
+			}
+			return label
+		case strings.HasPrefix(target, "@"), strings.HasPrefix(target, "#"):
+			// <@U123|name>、<#C123|general>
+			if label != "" {
+				return target[:1] + label
+			}
+			return target
+		default:
+			return slackLink(target, label)
+		}
+	})
+	return slackUnescaper.Replace(text)
+}
+
+// slackLink 将链接和标签合并为 "标签 (链接)"，任一为空时只保留另一个
+func slackLink(url, label string) string {
+	label = strings.TrimSpace(label)
+	switch {
+	case url == "":
+		return label
+	case label == "" || label == url:
+		return url
+	default:
+		return label + " (" + url + ")"
+	}
+}
+
+// appendNonEmpty 追加去除首尾空白后非空的文本
+func appendNonEmpty(items []string, text string) []string {
+	if text = strings.TrimSpace(text); text != "" {
+		items = append(items, text)
+	}
+	return items
+}
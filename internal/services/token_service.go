@@ -0,0 +1,325 @@
+package services
+
+import (
+	"errors"
+	"gochat/internal/config"
+	"gochat/internal/dal"
+	"gochat/internal/models/entities"
+	"gochat/internal/models/responses"
+	"gochat/pkg/logger"
+	"gochat/pkg/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// refreshTokenBytes 刷新令牌的随机字节数
+const refreshTokenBytes = 32
+
+// sessionTouchInterval 会话最后使用时间的更新间隔，避免每个请求都写数据库
+const sessionTouchInterval = time.Minute
+
+// tokenCleanupInterval 清理过期黑名单记录的间隔
+const tokenCleanupInterval = time.Hour
+
+// 令牌错误
+var (
+	ErrTokenRevoked        = errors.New("token已失效")
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+)
+
+// SessionMeta 登录时记录的设备信息
+type SessionMeta struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+// TokenService 令牌服务，负责签发、刷新和撤销令牌
+type TokenService struct {
+	userDAL            *dal.UserDAL
+	tokenDAL           *dal.TokenDAL
+	sessionDAL         *dal.SessionDAL
+	jwtSecret          string
+	expireHours        time.Duration
+	refreshExpireHours time.Duration
+}
+
+// NewTokenService 创建令牌服务实例
+func NewTokenService(cfg *config.JWTConfig) *TokenService {
+	return &TokenService{
+		userDAL:            dal.NewUserDAL(),
+		tokenDAL:           dal.NewTokenDAL(),
+		sessionDAL:         dal.NewSessionDAL(),
+		jwtSecret:          cfg.Secret,
+		expireHours:        cfg.ExpireHours,
+		refreshExpireHours: cfg.RefreshExpireHours,
+	}
+}
+
+// IssueTokens 为用户创建新的登录会话，并签发访问令牌和刷新令牌
+func (s *TokenService) IssueTokens(user *entities.User, meta *SessionMeta) (*responses.AuthResponse, error) {
+	now := time.Now()
+	session := &entities.UserSession{
+		UserID:     user.ID,
+		ExpiresAt:  now.Add(s.refreshExpireHours),
+		LastUsedAt: now,
+	}
+	if meta != nil {
+		session.DeviceName = truncate(meta.DeviceName, 100)
+		session.UserAgent = truncate(meta.UserAgent, 255)
+		session.IPAddress = truncate(meta.IPAddress, 64)
+	}
+	if err := s.sessionDAL.Create(session); err != nil {
+		return nil, errors.New("会话创建失败")
+	}
+
+	return s.issueSessionTokens(user, session.ID)
+}
+
+// issueSessionTokens 在已有会话上签发访问令牌和刷新令牌
+func (s *TokenService) issueSessionTokens(user *entities.User, sessionID uint) (*responses.AuthResponse, error) {
+	claims := &utils.JWTClaims{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
+		SessionID:    sessionID,
+	}
+	accessToken, err := utils.GenerateToken(claims, s.jwtSecret, s.expireHours)
+	if err != nil {
+		return nil, errors.New("token生成失败")
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, errors.New("token生成失败")
+	}
+	refreshExpiresAt := time.Now().Add(s.refreshExpireHours)
+	if err := s.tokenDAL.CreateRefreshToken(&entities.RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: refreshExpiresAt,
+	}); err != nil {
+		return nil, errors.New("token生成失败")
+	}
+
+	return &responses.AuthResponse{
+		User: &responses.UserInfo{
+			ID:        user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			AvatarURL: user.AvatarURL,
+			Role:      user.Role,
+		},
+		Token:            accessToken,
+		ExpiresAt:        claims.ExpiresAt.Time,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+		SessionID:        sessionID,
+	}, nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧的刷新令牌同时失效
+func (s *TokenService) Refresh(refreshToken string) (*responses.AuthResponse, error) {
+	stored, err := s.tokenDAL.GetRefreshToken(utils.HashToken(refreshToken))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	if stored.RevokedAt != nil || !stored.ExpiresAt.After(time.Now()) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// 已禁用的用户不能刷新
+	user, err := s.userDAL.GetByID(stored.UserID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	// 条件撤销保证同一个刷新令牌只能使用一次
+	revoked, err := s.tokenDAL.RevokeRefreshToken(stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// 没有关联会话的旧刷新令牌创建新会话
+	if stored.SessionID == 0 {
+		return s.IssueTokens(user, nil)
+	}
+
+	session, err := s.sessionDAL.GetByID(stored.SessionID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	if session.RevokedAt != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err := s.sessionDAL.Extend(session.ID, time.Now().Add(s.refreshExpireHours)); err != nil {
+		return nil, err
+	}
+
+	return s.issueSessionTokens(user, session.ID)
+}
+
+// Logout 注销当前访问令牌及其所属会话，refreshToken不为空时同时撤销对应的刷新令牌
+func (s *TokenService) Logout(claims *utils.JWTClaims, refreshToken string) error {
+	if err := s.RevokeAccessToken(claims); err != nil {
+		return err
+	}
+	if claims.SessionID != 0 {
+		if err := s.RevokeSession(claims.SessionID); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	stored, err := s.tokenDAL.GetRefreshToken(utils.HashToken(refreshToken))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	// 只能撤销自己的刷新令牌
+	if stored.UserID != claims.UserID {
+		return nil
+	}
+	_, err = s.tokenDAL.RevokeRefreshToken(stored.ID)
+	return err
+}
+
+// RevokeAccessToken 将访问令牌加入黑名单，直到其自然过期
+func (s *TokenService) RevokeAccessToken(claims *utils.JWTClaims) error {
+	if claims.ID == "" {
+		return nil
+	}
+
+	expiresAt := time.Now().Add(s.expireHours)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return s.tokenDAL.RevokeAccessToken(&entities.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// RevokeSession 撤销登录会话及其刷新令牌，会话上签发的访问令牌随之失效
+func (s *TokenService) RevokeSession(sessionID uint) error {
+	if _, err := s.sessionDAL.Revoke(sessionID); err != nil {
+		return err
+	}
+	return s.tokenDAL.RevokeSessionRefreshTokens(sessionID)
+}
+
+// RevokeAllTokens 撤销用户已签发的所有令牌（递增token版本并撤销所有会话和刷新令牌）
+func (s *TokenService) RevokeAllTokens(userID uint) error {
+	if err := s.userDAL.IncrementTokenVersion(userID); err != nil {
+		return err
+	}
+	if err := s.sessionDAL.RevokeByUser(userID); err != nil {
+		return err
+	}
+	return s.tokenDAL.RevokeUserRefreshTokens(userID)
+}
+
+// ValidateClaims 检查访问令牌是否已被撤销：jti黑名单、所属会话、token版本以及用户是否被禁用
+func (s *TokenService) ValidateClaims(claims *utils.JWTClaims) error {
+	if claims.ID != "" {
+		revoked, err := s.tokenDAL.IsAccessTokenRevoked(claims.ID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+
+	user, err := s.userDAL.GetByID(claims.UserID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrTokenRevoked
+		}
+		return err
+	}
+	if user.TokenVersion != claims.TokenVersion {
+		return ErrTokenRevoked
+	}
+
+	if claims.SessionID != 0 {
+		session, err := s.sessionDAL.GetByID(claims.SessionID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrTokenRevoked
+			}
+			return err
+		}
+		if session.RevokedAt != nil || session.UserID != claims.UserID {
+			return ErrTokenRevoked
+		}
+		if time.Since(session.LastUsedAt) > sessionTouchInterval {
+			if err := s.sessionDAL.Touch(session.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ParseAndValidate 解析访问令牌并检查是否已被撤销
+func (s *TokenService) ParseAndValidate(tokenString string) (*utils.JWTClaims, error) {
+	claims, err := utils.ParseToken(tokenString, s.jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ValidateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// CleanupExpired 清理已过期的黑名单记录
+func (s *TokenService) CleanupExpired() error {
+	return s.tokenDAL.DeleteExpiredRevokedTokens()
+}
+
+// StartTokenCleanup 启动后台任务，定期清理已过期的黑名单记录
+func StartTokenCleanup(cfg *config.JWTConfig) {
+	s := NewTokenService(cfg)
+	go func() {
+		ticker := time.NewTicker(tokenCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.CleanupExpired(); err != nil {
+				logger.Error("Failed to clean up revoked tokens:", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// truncate 按字符截断字符串，避免超出数据库字段长度
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
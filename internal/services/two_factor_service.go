@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"gochat/internal/config"
+	"gochat/internal/dal"
+	"gochat/internal/models/entities"
+	"gochat/internal/models/responses"
+	"gochat/pkg/logger"
+	"gochat/pkg/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 两步验证参数
+const (
+	totpIssuer         = "GoChat"
+	totpSkew           = 1  // 允许前后各一个时间步的时钟偏差
+	recoveryCodeCount  = 10 // 每次生成的恢复码数量
+	recoveryCodeBytes  = 5  // 每个恢复码的随机字节数（10个十六进制字符）
+	recoveryCodeSplitN = 5  // 恢复码显示时的分组长度
+)
+
+// 两步验证错误
+var (
+	ErrTwoFactorEnabled     = errors.New("两步验证已启用")
+	ErrTwoFactorNotEnabled  = errors.New("两步验证未启用")
+	ErrTwoFactorNotEnrolled = errors.New("请先生成两步验证密钥")
+	ErrTwoFactorCodeInvalid = errors.New("验证码错误")
+)
+
+// TwoFactorService 两步验证服务（TOTP + 恢复码），完全离线工作
+type TwoFactorService struct {
+	userDAL      *dal.UserDAL
+	twoFactorDAL *dal.TwoFactorDAL
+	authCfg      *config.AuthConfig
+}
+
+// NewTwoFactorService 创建两步验证服务实例
+func NewTwoFactorService(authCfg *config.AuthConfig) *TwoFactorService {
+	return &TwoFactorService{
+		userDAL:      dal.NewUserDAL(),
+		twoFactorDAL: dal.NewTwoFactorDAL(),
+		authCfg:      authCfg,
+	}
+}
+
+// Enroll 生成新的TOTP密钥和恢复码，需验证一次验证码后才会启用
+func (s *TwoFactorService) Enroll(userID uint, password string) (*responses.TwoFactorEnrollment, error) {
+	user, err := s.checkPassword(userID, password)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.New("系统错误")
+	}
+	codes, err := s.generateRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userDAL.UpdateTOTP(user.ID, secret, false); err != nil {
+		return nil, errors.New("系统错误")
+	}
+
+	return &responses.TwoFactorEnrollment{
+		Secret:        secret,
+		URI:           utils.TOTPURI(totpIssuer, user.Username, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// Verify 验证验证码并启用两步验证
+func (s *TwoFactorService) Verify(userID uint, code string) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		return ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	counter, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrTwoFactorCodeInvalid
+	}
+	if err := s.userDAL.UpdateTOTP(user.ID, user.TOTPSecret, true); err != nil {
+		return errors.New("系统错误")
+	}
+	if _, err := s.userDAL.AdvanceTOTPCounter(user.ID, counter); err != nil {
+		return errors.New("系统错误")
+	}
+	return nil
+}
+
+// Disable 关闭两步验证，需要密码以及验证码或恢复码，错误计入登录失败次数
+func (s *TwoFactorService) Disable(userID uint, password, code string) error {
+	user, err := s.checkPassword(userID, password)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	ok, err := s.CheckCode(user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.recordFailure(user.ID)
+		return ErrTwoFactorCodeInvalid
+	}
+
+	if err := s.userDAL.UpdateTOTP(user.ID, "", false); err != nil {
+		return errors.New("系统错误")
+	}
+	if err := s.twoFactorDAL.DeleteRecoveryCodes(user.ID); err != nil {
+		return errors.New("系统错误")
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，之前的恢复码全部失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, password string) ([]string, error) {
+	user, err := s.checkPassword(userID, password)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return s.generateRecoveryCodes(user.ID)
+}
+
+// GetStatus 获取两步验证状态
+func (s *TwoFactorService) GetStatus(userID uint) (*responses.TwoFactorStatus, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &responses.TwoFactorStatus{Enabled: user.TOTPEnabled}
+	if user.TOTPEnabled {
+		count, err := s.twoFactorDAL.CountUnusedRecoveryCodes(user.ID)
+		if err != nil {
+			return nil, errors.New("系统错误")
+		}
+		status.RecoveryCodesLeft = count
+	}
+	return status, nil
+}
+
+// CheckCode 校验TOTP验证码或恢复码，通过后验证码时间步或恢复码立即失效
+func (s *TwoFactorService) CheckCode(user *entities.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false, nil
+	}
+
+	if counter, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), totpSkew); ok {
+		advanced, err := s.userDAL.AdvanceTOTPCounter(user.ID, counter)
+		if err != nil {
+			return false, errors.New("系统错误")
+		}
+		return advanced, nil
+	}
+
+	consumed, err := s.twoFactorDAL.ConsumeRecoveryCode(user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, errors.New("系统错误")
+	}
+	return consumed, nil
+}
+
+// generateRecoveryCodes 生成并保存新的恢复码，返回明文（只显示一次）
+func (s *TwoFactorService) generateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.GenerateRandomToken(recoveryCodeBytes)
+		if err != nil {
+			return nil, errors.New("系统错误")
+		}
+		codes = append(codes, raw[:recoveryCodeSplitN]+"-"+raw[recoveryCodeSplitN:])
+		hashes = append(hashes, utils.HashToken(raw))
+	}
+
+	if err := s.twoFactorDAL.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, errors.New("系统错误")
+	}
+	return codes, nil
+}
+
+// checkPassword 获取用户并校验密码，与修改密码一样：锁定期间拒绝，密码错误计入登录失败次数
+func (s *TwoFactorService) checkPassword(userID uint, password string) (*entities.User, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if isLocked(user) {
+		return nil, ErrAccountLocked
+	}
+	if !utils.CheckPassword(password, user.PasswordHash) {
+		s.recordFailure(user.ID)
+		return nil, ErrWrongPassword
+	}
+	return user, nil
+}
+
+// recordFailure 记录一次验证失败，防止借已登录的会话暴力猜测密码或验证码
+func (s *TwoFactorService) recordFailure(userID uint) {
+	if err := recordLoginFailure(s.userDAL, s.authCfg, userID); err != nil {
+		logger.Error("Failed to record login failure:", err)
+	}
+}
+
+// getUser 获取用户
+func (s *TwoFactorService) getUser(userID uint) (*entities.User, error) {
+	user, err := s.userDAL.GetByID(userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, errors.New("系统错误")
+	}
+	return user, nil
+}
+
+// normalizeRecoveryCode 去掉分隔符和空白并转为小写
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gochat/internal/config"
+	"gochat/internal/dal"
+	"gochat/internal/models/entities"
+	"gochat/internal/models/requests"
+	"gochat/internal/models/responses"
+	"gochat/internal/storage"
+	"gochat/pkg/logger"
+	"gochat/pkg/utils"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 分片上传参数
+const (
+	uploadSessionIDBytes = 16       // 上传会话ID随机字节数
+	uploadChunkPrefix    = "chunks" // 分片在存储后端中的目录，与附件分类目录并列
+	uploadCleanupBatch   = 20       // 每次清理的过期会话数
+)
+
+// 上传错误
+var (
+	ErrFileTooLarge          = errors.New("文件大小超过限制")
+	ErrFileTypeUnsupported   = errors.New("不支持的文件类型")
+	ErrUploadSessionNotFound = errors.New("上传会话不存在或已过期")
+	ErrUploadCompleted       = errors.New("上传已完成")
+	ErrUploadChunkIndex      = errors.New("无效的分片序号")
+	ErrUploadChunkOffset     = errors.New("分片偏移量与序号不匹配")
+	ErrUploadChunkSize       = errors.New("分片大小不正确")
+	ErrUploadChunkChecksum   = errors.New("分片校验和不匹配")
+	ErrUploadIncomplete      = errors.New("还有分片未上传")
+	ErrUploadChecksum        = errors.New("文件校验和不匹配")
+)
+
+// allowedFileExts 允许上传的文件类型
+var allowedFileExts = map[string]bool{
+	// 图片类型
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".bmp": true, ".webp": true,
+	// 视频类型
+	".mp4": true, ".avi": true, ".mov": true, ".wmv": true, ".flv": true, ".webm": true,
+	// 文档类型
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".ppt": true, ".pptx": true,
+	".txt": true, ".md": true, ".csv": true,
+	// 压缩文件
+	".zip": true, ".rar": true, ".7z": true, ".tar": true, ".gz": true,
+	// 其他常见类型
+	".json": true, ".xml": true, ".log": true,
+}
+
+// UploadService 上传服务，负责文件校验和可续传的分片上传
+// 分片保存在存储后端，多节点部署时各分片可以由不同节点接收
+type UploadService struct {
+	cfg               *config.UploadConfig
+	uploadDAL         *dal.UploadDAL
+	attachmentService *AttachmentService
+	store             storage.BlobStore
+}
+
+// NewUploadService 创建上传服务实例
+func NewUploadService(cfg *config.UploadConfig) *UploadService {
+	return &UploadService{
+		cfg:               cfg,
+		uploadDAL:         dal.NewUploadDAL(),
+		attachmentService: NewAttachmentService(),
+		store:             storage.Store,
+	}
+}
+
+// ValidateFile 验证文件类型和大小，大小上限按文件分类配置
+func (s *UploadService) ValidateFile(fileName string, size int64) error {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if !allowedFileExts[ext] {
+		return fmt.Errorf("%w: %s", ErrFileTypeUnsupported, ext)
+	}
+
+	maxMB := s.maxFileSizeMB(s.attachmentService.getCategoryFromFileName(fileName))
+	if size > int64(maxMB)*1024*1024 {
+		return fmt.Errorf("%w，最大允许%dMB", ErrFileTooLarge, maxMB)
+	}
+	return nil
+}
+
+// CreateUpload 创建分片上传会话，顺便清理过期未完成的会话
+func (s *UploadService) CreateUpload(uploaderID uint, req *requests.CreateUploadRequest) (*responses.UploadSessionInfo, error) {
+	s.cleanupExpired()
+
+	if err := s.ValidateFile(req.FileName, req.FileSize); err != nil {
+		return nil, err
+	}
+	if _, err := s.attachmentService.messageRoomID(req.MessageID, uploaderID); err != nil {
+		return nil, err
+	}
+
+	id, err := utils.GenerateRandomToken(uploadSessionIDBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &entities.UploadSession{
+		ID:         id,
+		UploaderID: uploaderID,
+		FileName:   filepath.Base(req.FileName),
+		FileSize:   req.FileSize,
+		ChunkSize:  int64(s.cfg.ChunkSize) * 1024,
+		Checksum:   strings.ToLower(req.Checksum),
+		ExpiresAt:  time.Now().Add(time.Duration(s.cfg.SessionTTL) * time.Hour),
+	}
+	if req.MessageID > 0 {
+		session.MessageID = &req.MessageID
+	}
+	if err := s.uploadDAL.CreateSession(session); err != nil {
+		return nil, err
+	}
+
+	return newUploadSessionInfo(session, nil), nil
+}
+
+// GetUpload 获取上传会话进度，客户端据此跳过已上传的分片继续上传
+func (s *UploadService) GetUpload(uploaderID uint, sessionID string) (*responses.UploadSessionInfo, error) {
+	session, err := s.getSession(uploaderID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.sessionInfo(session)
+}
+
+// UploadChunk 上传一个分片，offset必须等于 index*chunk_size，checksum为分片的SHA-256
+// 同一序号可以重复上传，以最后一次为准
+func (s *UploadService) UploadChunk(uploaderID uint, sessionID string, index int, offset int64, checksum string, data []byte) (*responses.UploadSessionInfo, error) {
+	session, err := s.getSession(uploaderID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.AttachmentID != nil {
+		return nil, ErrUploadCompleted
+	}
+
+	if index < 0 || index >= session.TotalChunks() {
+		return nil, ErrUploadChunkIndex
+	}
+	if offset != int64(index)*session.ChunkSize {
+		return nil, ErrUploadChunkOffset
+	}
+	expectedSize := session.ChunkSize
+	if remaining := session.FileSize - offset; remaining < expectedSize {
+		expectedSize = remaining
+	}
+	if int64(len(data)) != expectedSize {
+		return nil, ErrUploadChunkSize
+	}
+
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(digest, checksum) {
+		return nil, ErrUploadChunkChecksum
+	}
+
+	key := chunkKey(session.ID, index)
+	if err := s.attachmentService.putBlob(key, bytes.NewReader(data), int64(len(data)), "application/octet-stream"); err != nil {
+		return nil, fmt.Errorf("分片保存失败: %v", err)
+	}
+	if err := s.uploadDAL.SaveChunk(&entities.UploadChunk{
+		SessionID: session.ID,
+		Index:     index,
+		Size:      int64(len(data)),
+		Checksum:  digest,
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.sessionInfo(session)
+}
+
+// CompleteUpload 合并所有分片并创建附件，附件与普通上传的附件完全相同
+// 已完成的会话再次调用返回同一个附件，便于客户端在网络中断后重试
+func (s *UploadService) CompleteUpload(uploaderID uint, sessionID string) (*entities.Attachment, error) {
+	session, err := s.getSession(uploaderID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.AttachmentID != nil {
+		return s.completedAttachment(*session.AttachmentID)
+	}
+
+	chunks, err := s.uploadDAL.GetChunks(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) != session.TotalChunks() {
+		return nil, ErrUploadIncomplete
+	}
+
+	// 分片按顺序合并到临时文件，图片生成缩略图需要可以重复读取的文件
+	file, err := os.CreateTemp("", "gochat-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	hash := sha256.New()
+	for _, chunk := range chunks {
+		if err := s.copyChunk(io.MultiWriter(file, hash), session.ID, chunk.Index); err != nil {
+			return nil, err
+		}
+	}
+	if session.Checksum != "" && hex.EncodeToString(hash.Sum(nil)) != session.Checksum {
+		return nil, ErrUploadChecksum
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var messageID uint
+	if session.MessageID != nil {
+		messageID = *session.MessageID
+	}
+	attachment, err := s.attachmentService.saveFile(file, session.FileName, session.FileSize, uploaderID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	completed, err := s.uploadDAL.MarkCompleted(session.ID, attachment.ID)
+	if err != nil || !completed {
+		// 并发合并时以先完成的为准，丢弃本次生成的附件
+		s.attachmentService.deleteBlobs(attachment)
+		_ = s.attachmentService.attachmentDAL.Delete(attachment.ID)
+		if err != nil {
+			return nil, err
+		}
+		return s.CompleteUpload(uploaderID, sessionID)
+	}
+
+	s.deleteChunkBlobs(session.ID, chunks)
+	return attachment, nil
+}
+
+// AbortUpload 取消上传，删除已上传的分片
+func (s *UploadService) AbortUpload(uploaderID uint, sessionID string) error {
+	session, err := s.getSession(uploaderID, sessionID)
+	if err != nil {
+		return err
+	}
+	return s.deleteSession(session)
+}
+
+// getSession 获取上传者自己的、未过期的上传会话
+func (s *UploadService) getSession(uploaderID uint, sessionID string) (*entities.UploadSession, error) {
+	session, err := s.uploadDAL.GetSession(sessionID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUploadSessionNotFound
+		}
+		return nil, err
+	}
+	if session.UploaderID != uploaderID || time.Now().After(session.ExpiresAt) {
+		return nil, ErrUploadSessionNotFound
+	}
+	return session, nil
+}
+
+// sessionInfo 构建包含进度的上传会话信息
+func (s *UploadService) sessionInfo(session *entities.UploadSession) (*responses.UploadSessionInfo, error) {
+	chunks, err := s.uploadDAL.GetChunks(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return newUploadSessionInfo(session, chunks), nil
+}
+
+// completedAttachment 获取已完成会话生成的附件
+func (s *UploadService) completedAttachment(attachmentID uint) (*entities.Attachment, error) {
+	attachment, err := s.attachmentService.attachmentDAL.GetByID(attachmentID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// copyChunk 将存储后端中的分片写入w
+func (s *UploadService) copyChunk(w io.Writer, sessionID string, index int) error {
+	ctx, cancel := storageContext(int64(s.cfg.ChunkSize) * 1024)
+	defer cancel()
+
+	reader, _, err := s.store.Get(ctx, chunkKey(sessionID, index))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrUploadIncomplete
+		}
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// deleteSession 删除上传会话、分片记录和存储后端中的分片
+func (s *UploadService) deleteSession(session *entities.UploadSession) error {
+	chunks, err := s.uploadDAL.GetChunks(session.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.uploadDAL.DeleteSession(session.ID); err != nil {
+		return err
+	}
+	s.deleteChunkBlobs(session.ID, chunks)
+	return nil
+}
+
+// deleteChunkBlobs 删除存储后端中的分片，失败时只记录日志
+func (s *UploadService) deleteChunkBlobs(sessionID string, chunks []*entities.UploadChunk) {
+	for _, chunk := range chunks {
+		key := chunkKey(sessionID, chunk.Index)
+		if err := s.attachmentService.deleteBlob(key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			logger.Error("Failed to delete upload chunk:", key, "error:", err)
+		}
+	}
+}
+
+// cleanupExpired 清理过期的上传会话，未完成会话的分片一并删除
+func (s *UploadService) cleanupExpired() {
+	sessions, err := s.uploadDAL.GetExpiredSessions(uploadCleanupBatch)
+	if err != nil {
+		logger.Error("Failed to list expired upload sessions:", err)
+		return
+	}
+	for _, session := range sessions {
+		if err := s.deleteSession(session); err != nil {
+			logger.Error("Failed to delete expired upload session:", session.ID, "error:", err)
+		}
+	}
+}
+
+// maxFileSizeMB 获取文件分类的大小上限（MB）
+func (s *UploadService) maxFileSizeMB(category string) int {
+	switch category {
+	case "image":
+		return s.cfg.MaxImageSize
+	case "video":
+		return s.cfg.MaxVideoSize
+	default:
+		return s.cfg.MaxFileSize
+	}
+}
+
+// chunkKey 分片在存储后端中的key，如 chunks/<会话ID>/0
+func chunkKey(sessionID string, index int) string {
+	return path.Join(uploadChunkPrefix, sessionID, strconv.Itoa(index))
+}
+
+// newUploadSessionInfo 构建上传会话信息
+func newUploadSessionInfo(session *entities.UploadSession, chunks []*entities.UploadChunk) *responses.UploadSessionInfo {
+	info := &responses.UploadSessionInfo{
+		ID:             session.ID,
+		FileName:       session.FileName,
+		FileSize:       session.FileSize,
+		ChunkSize:      session.ChunkSize,
+		TotalChunks:    session.TotalChunks(),
+		ReceivedChunks: make([]int, 0, len(chunks)),
+		AttachmentID:   session.AttachmentID,
+		ExpiresAt:      session.ExpiresAt,
+	}
+	for _, chunk := range chunks {
+		info.ReceivedChunks = append(info.ReceivedChunks, chunk.Index)
+		info.ReceivedBytes += chunk.Size
+	}
+	if session.AttachmentID != nil {
+		info.ReceivedBytes = session.FileSize
+	}
+	return info
+}
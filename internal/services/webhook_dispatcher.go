@@ -0,0 +1,491 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gochat/internal/config"
+	"gochat/internal/dal"
+	"gochat/internal/models/entities"
+	"gochat/pkg/logger"
+	"gochat/pkg/utils"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Webhook投递参数
+const (
+	webhookPollInterval   = time.Second // 轮询到期投递记录的间隔
+	webhookBatchSize      = 50          // 每次领取的投递记录数
+	webhookErrorMaxLength = 500         // 错误信息最大长度
+	webhookUserAgent      = "GoChat-Webhook/1.0"
+	webhookSignatureAlgo  = "sha256"
+)
+
+// Webhook请求头
+const (
+	WebhookHeaderEvent     = "X-GoChat-Event"
+	WebhookHeaderDelivery  = "X-GoChat-Delivery"
+	WebhookHeaderTimestamp = "X-GoChat-Timestamp"
+	WebhookHeaderSignature = "X-GoChat-Signature" // sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+)
+
+// errWebhookPrivateAddress 目标地址为内网或本机地址
+var errWebhookPrivateAddress = errors.New("不允许投递到内网或本机地址")
+
+// WebhookPayload Webhook投递的JSON内容
+type WebhookPayload struct {
+	EventID   string      `json:"event_id"` // 同一事件投递到多个Webhook时相同，可用于去重
+	Event     string      `json:"event"`
+	RoomID    uint        `json:"room_id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookMessageData 消息事件的数据
+type WebhookMessageData struct {
+	ID           uint       `json:"id"`
+	UserID       uint       `json:"user_id"`
+	Username     string     `json:"username"`
+	IsBot        bool       `json:"is_bot"`
+	Content      string     `json:"content"`
+	MessageType  string     `json:"message_type"`
+	ReplyToID    *uint      `json:"reply_to_id,omitempty"`
+	ThreadRootID *uint      `json:"thread_root_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	EditedAt     *time.Time `json:"edited_at,omitempty"`
+}
+
+// WebhookMemberData 成员事件的数据
+type WebhookMemberData struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	IsBot    bool   `json:"is_bot"`
+	Reason   string `json:"reason,omitempty"` // member.left：leave、kick或ban
+}
+
+// 成员离开原因
+const (
+	MemberLeftReasonLeave = "leave"
+	MemberLeftReasonKick  = "kick"
+	MemberLeftReasonBan   = "ban"
+)
+
+// webhookDispatcher 全局投递器，由StartWebhookDispatcher启动，未启动时事件不会投递
+var webhookDispatcher *WebhookDispatcher
+
+// WebhookDispatcher Webhook投递器
+// 事件发生时为订阅的Webhook写入投递记录，后台协程轮询到期记录并投递，失败后按指数退避重试，
+// 重试耗尽后标记为死信。投递记录保存在数据库中，服务重启或多节点部署时不会丢失或重复投递
+type WebhookDispatcher struct {
+	cfg        *config.WebhookConfig
+	webhookDAL *dal.WebhookDAL
+	userDAL    *dal.UserDAL
+	client     *http.Client
+	wake       chan struct{}
+}
+
+// StartWebhookDispatcher 创建并启动全局Webhook投递器
+func StartWebhookDispatcher(cfg *config.WebhookConfig) *WebhookDispatcher {
+	d := NewWebhookDispatcher(cfg)
+	webhookDispatcher = d
+	go d.run()
+	return d
+}
+
+// NewWebhookDispatcher 创建Webhook投递器实例
+func NewWebhookDispatcher(cfg *config.WebhookConfig) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		cfg:        cfg,
+		webhookDAL: dal.NewWebhookDAL(),
+		userDAL:    dal.NewUserDAL(),
+		client:     newWebhookHTTPClient(cfg),
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// newWebhookHTTPClient 创建投递用的HTTP客户端
+// 不使用代理、不跟随重定向；未允许内网时在建立连接前检查解析后的IP，防止DNS重绑定绕过
+func newWebhookHTTPClient(cfg *config.WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return errWebhookPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConnsPerHost: 2,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// webhookBlockedNetworks net.IP分类方法未覆盖、但同样不应访问的地址段
+// 包括运营商级NAT、测试及保留地址，以及内嵌IPv4地址的IPv6前缀（映射、NAT64、6to4、Teredo），
+// 后者可能指向任意内网IPv4地址
+var webhookBlockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"),   // 运营商级NAT（共享地址空间）
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF协议分配
+	netip.MustParsePrefix("192.0.2.0/24"),    // 文档示例（TEST-NET-1）
+	netip.MustParsePrefix("198.18.0.0/15"),   // 网络设备基准测试
+	netip.MustParsePrefix("198.51.100.0/24"), // 文档示例（TEST-NET-2）
+	netip.MustParsePrefix("203.0.113.0/24"),  // 文档示例（TEST-NET-3）
+	netip.MustParsePrefix("240.0.0.0/4"),     // 保留地址及广播地址
+	netip.MustParsePrefix("::ffff:0:0/96"),   // IPv4映射地址
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // 本地NAT64
+	netip.MustParsePrefix("100::/64"),        // 丢弃地址
+	netip.MustParsePrefix("2001::/32"),       // Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // 文档示例
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+}
+
+// isPrivateIP 判断是否为内网、本机或其他不可路由的地址
+// IPv4映射的IPv6地址先转换为IPv4再判断
+func isPrivateIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return true
+	}
+	for _, network := range webhookBlockedNetworks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// emitWebhookEvent 触发聊天室事件，异步为订阅该事件的Webhook创建投递记录
+// data只在存在订阅者时才会调用，避免没有Webhook的聊天室产生额外查询
+func emitWebhookEvent(roomID uint, event string, data func(d *WebhookDispatcher) interface{}) {
+	d := webhookDispatcher
+	if d == nil {
+		return
+	}
+	go d.enqueue(roomID, event, data)
+}
+
+// emitMessageEvent 触发消息事件
+func emitMessageEvent(event string, message *entities.Message) {
+	snapshot := *message
+	emitWebhookEvent(message.RoomID, event, func(d *WebhookDispatcher) interface{} {
+		return d.newMessageData(&snapshot)
+	})
+}
+
+// emitMemberEvent 触发成员事件
+func emitMemberEvent(event string, roomID, userID uint, reason string) {
+	emitWebhookEvent(roomID, event, func(d *WebhookDispatcher) interface{} {
+		return d.newMemberData(userID, reason)
+	})
+}
+
+// enqueue 为订阅事件的Webhook写入投递记录并唤醒投递协程
+func (d *WebhookDispatcher) enqueue(roomID uint, event string, data func(d *WebhookDispatcher) interface{}) {
+	webhooks, err := d.webhookDAL.GetActiveByRoom(roomID)
+	if err != nil {
+		logger.Error("Failed to load webhooks:", err)
+		return
+	}
+
+	var targets []*entities.Webhook
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(event) {
+			targets = append(targets, webhook)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	if _, err := d.createDeliveries(targets, roomID, event, data(d)); err != nil {
+		logger.Error("Failed to create webhook deliveries:", err)
+	}
+}
+
+// Ping 向指定Webhook投递一次测试事件
+func (d *WebhookDispatcher) Ping(webhook *entities.Webhook) (*entities.WebhookDelivery, error) {
+	data := map[string]interface{}{
+		"webhook_id": webhook.ID,
+		"message":    "pong",
+	}
+	deliveries, err := d.createDeliveries([]*entities.Webhook{webhook}, webhook.RoomID, entities.WebhookEventPing, data)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries[0], nil
+}
+
+// Wake 唤醒投递协程立即处理到期的投递记录
+func (d *WebhookDispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// createDeliveries 写入投递记录并唤醒投递协程
+func (d *WebhookDispatcher) createDeliveries(webhooks []*entities.Webhook, roomID uint, event string, data interface{}) ([]*entities.WebhookDelivery, error) {
+	deliveries, err := d.buildDeliveries(webhooks, roomID, event, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.webhookDAL.CreateDeliveries(deliveries); err != nil {
+		return nil, err
+	}
+	d.Wake()
+	return deliveries, nil
+}
+
+// buildDeliveries 序列化事件内容，为每个Webhook构建待投递记录
+func (d *WebhookDispatcher) buildDeliveries(webhooks []*entities.Webhook, roomID uint, event string, data interface{}) ([]*entities.WebhookDelivery, error) {
+	eventID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	body, err := json.Marshal(&WebhookPayload{
+		EventID:   eventID,
+		Event:     event,
+		RoomID:    roomID,
+		Timestamp: now,
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*entities.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, &entities.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			RoomID:        roomID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        entities.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	return deliveries, nil
+}
+
+// run 投递循环，定时或被唤醒时处理到期的投递记录
+func (d *WebhookDispatcher) run() {
+	logger.Info("Webhook dispatcher started")
+
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		}
+		d.processDue()
+	}
+}
+
+// processDue 领取并并发投递所有到期的记录
+func (d *WebhookDispatcher) processDue() {
+	workers := d.cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	// 租约需覆盖一批记录按并发数投递的最长耗时
+	lease := time.Duration(d.cfg.TimeoutSeconds)*time.Second*time.Duration(webhookBatchSize/workers+1) + time.Minute
+
+	for {
+		deliveries, err := d.webhookDAL.ClaimDueDeliveries(webhookBatchSize, lease)
+		if err != nil {
+			logger.Error("Failed to claim webhook deliveries:", err)
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(delivery *entities.WebhookDelivery) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				d.deliver(delivery)
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// deliver 投递一条记录并保存结果
+func (d *WebhookDispatcher) deliver(delivery *entities.WebhookDelivery) {
+	attempts := delivery.Attempts + 1
+
+	webhook, err := d.webhookDAL.GetByID(delivery.WebhookID)
+	if err != nil || !webhook.IsActive {
+		// Webhook已删除或停用，不再重试
+		d.saveFailure(delivery, entities.WebhookDeliveryDead, attempts, 0, "webhook已删除或已停用")
+		return
+	}
+
+	statusCode, err := d.send(webhook, delivery)
+	if err == nil {
+		if err := d.webhookDAL.MarkDelivered(delivery.ID, attempts, statusCode); err != nil {
+			logger.Error("Failed to save webhook delivery:", err)
+		}
+		return
+	}
+
+	status := entities.WebhookDeliveryPending
+	if attempts >= d.cfg.MaxAttempts {
+		status = entities.WebhookDeliveryDead
+		logger.Warn("Webhook delivery dead-lettered:", delivery.ID, "WebhookID:", webhook.ID, "Error:", err)
+	}
+	d.saveFailure(delivery, status, attempts, statusCode, err.Error())
+}
+
+// saveFailure 保存失败结果，仍可重试时按指数退避计算下一次投递时间
+func (d *WebhookDispatcher) saveFailure(delivery *entities.WebhookDelivery, status string, attempts, statusCode int, lastError string) {
+	nextAttemptAt := delivery.NextAttemptAt
+	if status == entities.WebhookDeliveryPending {
+		nextAttemptAt = time.Now().Add(d.backoff(attempts))
+	}
+	if len(lastError) > webhookErrorMaxLength {
+		lastError = lastError[:webhookErrorMaxLength]
+	}
+
+	if err := d.webhookDAL.MarkFailed(delivery.ID, status, attempts, statusCode, lastError, nextAttemptAt); err != nil {
+		logger.Error("Failed to save webhook delivery:", err)
+	}
+}
+
+// backoff 第attempts次失败后的重试间隔：initial * 2^(attempts-1)，不超过max
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := time.Duration(d.cfg.InitialBackoff) * time.Second
+	maxDelay := time.Duration(d.cfg.MaxBackoff) * time.Second
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// send 发送签名的HTTP请求，2xx视为成功，返回响应状态码
+func (d *WebhookDispatcher) send(webhook *entities.Webhook, delivery *entities.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.cfg.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(WebhookHeaderEvent, delivery.Event)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("接收端返回状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload 计算Webhook签名，接收端使用相同方法校验
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return webhookSignatureAlgo + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newMessageData 构建消息事件数据，消息未加载发送者时查询用户信息
+func (d *WebhookDispatcher) newMessageData(message *entities.Message) *WebhookMessageData {
+	data := &WebhookMessageData{
+		ID:           message.ID,
+		UserID:       message.UserID,
+		Username:     message.User.Username,
+		IsBot:        message.User.IsBot,
+		Content:      message.Content,
+		MessageType:  message.MessageType,
+		ReplyToID:    message.ReplyToID,
+		ThreadRootID: message.ThreadRootID,
+		CreatedAt:    message.CreatedAt,
+		EditedAt:     message.EditedAt,
+	}
+	if message.User.ID == 0 {
+		if user, err := d.userDAL.GetByIDWithDeleted(message.UserID); err == nil {
+			data.Username = user.Username
+			data.IsBot = user.IsBot
+		}
+	}
+	return data
+}
+
+// newMemberData 构建成员事件数据
+func (d *WebhookDispatcher) newMemberData(userID uint, reason string) *WebhookMemberData {
+	data := &WebhookMemberData{
+		UserID: userID,
+		Reason: reason,
+	}
+	if user, err := d.userDAL.GetByIDWithDeleted(userID); err == nil {
+		data.Username = user.Username
+		data.IsBot = user.IsBot
+	}
+	return data
+}
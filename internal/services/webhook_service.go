@@ -0,0 +1,305 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"gochat/internal/config"
+	"gochat/internal/dal"
+	"gochat/internal/models/entities"
+	"gochat/internal/models/requests"
+	"gochat/internal/models/responses"
+	"gochat/pkg/utils"
+	"net"
+	"net/url"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// webhookSecretBytes Webhook签名密钥字节数
+const webhookSecretBytes = 32
+
+// Webhook错误
+var (
+	ErrWebhookNotFound         = errors.New("Webhook不存在")
+	ErrWebhookDeliveryNotFound = errors.New("投递记录不存在")
+	ErrWebhookURLInvalid       = errors.New("Webhook地址必须是http或https URL")
+	ErrWebhookURLPrivate       = errors.New("不允许使用内网或本机地址")
+	ErrWebhookEventInvalid     = errors.New("无效的Webhook事件")
+	ErrWebhookDisabled         = errors.New("Webhook投递未启用")
+)
+
+// WebhookService 聊天室Webhook管理服务，仅聊天室管理员及以上角色可操作
+type WebhookService struct {
+	cfg         *config.WebhookConfig
+	webhookDAL  *dal.WebhookDAL
+	roomService *RoomService
+}
+
+// NewWebhookService 创建Webhook服务实例
+func NewWebhookService(cfg *config.WebhookConfig) *WebhookService {
+	return &WebhookService{
+		cfg:         cfg,
+		webhookDAL:  dal.NewWebhookDAL(),
+		roomService: NewRoomService(),
+	}
+}
+
+// CreateWebhook 为聊天室创建Webhook，签名密钥只在创建时返回一次
+func (s *WebhookService) CreateWebhook(operatorID, roomID uint, req *requests.WebhookRequest) (*responses.WebhookCreated, error) {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateRandomToken(webhookSecretBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &entities.Webhook{
+		RoomID:    roomID,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    strings.Join(events, " "),
+		IsActive:  req.IsActive == nil || *req.IsActive,
+		CreatedBy: operatorID,
+	}
+	if err := s.webhookDAL.Create(webhook); err != nil {
+		return nil, err
+	}
+
+	return &responses.WebhookCreated{
+		WebhookInfo: *newWebhookInfo(webhook),
+		Secret:      secret,
+	}, nil
+}
+
+// ListWebhooks 获取聊天室的Webhook列表
+func (s *WebhookService) ListWebhooks(operatorID, roomID uint) ([]*responses.WebhookInfo, error) {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhookDAL.GetByRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*responses.WebhookInfo, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		items = append(items, newWebhookInfo(webhook))
+	}
+	return items, nil
+}
+
+// UpdateWebhook 更新Webhook的地址、订阅事件和启用状态
+func (s *WebhookService) UpdateWebhook(operatorID, roomID, webhookID uint, req *requests.WebhookRequest) (*responses.WebhookInfo, error) {
+	webhook, err := s.getWebhook(operatorID, roomID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = strings.Join(events, " ")
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+	if err := s.webhookDAL.Update(webhook); err != nil {
+		return nil, err
+	}
+
+	return newWebhookInfo(webhook), nil
+}
+
+// DeleteWebhook 删除Webhook及其投递记录
+func (s *WebhookService) DeleteWebhook(operatorID, roomID, webhookID uint) error {
+	if _, err := s.getWebhook(operatorID, roomID, webhookID); err != nil {
+		return err
+	}
+	return s.webhookDAL.Delete(webhookID)
+}
+
+// PingWebhook 向Webhook投递一次ping事件，用于验证接收端配置
+func (s *WebhookService) PingWebhook(operatorID, roomID, webhookID uint) (*responses.WebhookDeliveryInfo, error) {
+	webhook, err := s.getWebhook(operatorID, roomID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhookDispatcher == nil {
+		return nil, ErrWebhookDisabled
+	}
+	delivery, err := webhookDispatcher.Ping(webhook)
+	if err != nil {
+		return nil, err
+	}
+	return newWebhookDeliveryInfo(delivery), nil
+}
+
+// ListDeliveries 分页获取Webhook的投递记录，status为dead时即死信列表
+func (s *WebhookService) ListDeliveries(operatorID, roomID, webhookID uint, status string, limit, offset int) (*responses.WebhookDeliveryList, error) {
+	if _, err := s.getWebhook(operatorID, roomID, webhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries, total, err := s.webhookDAL.GetDeliveries(webhookID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*responses.WebhookDeliveryInfo, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		items = append(items, newWebhookDeliveryInfo(delivery))
+	}
+	return &responses.WebhookDeliveryList{
+		Deliveries: items,
+		Total:      total,
+	}, nil
+}
+
+// Redeliver 重新投递一条记录（通常是死信），重试次数从零开始计算
+func (s *WebhookService) Redeliver(operatorID, roomID, webhookID, deliveryID uint) error {
+	if _, err := s.getWebhook(operatorID, roomID, webhookID); err != nil {
+		return err
+	}
+
+	delivery, err := s.webhookDAL.GetDelivery(deliveryID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrWebhookDeliveryNotFound
+		}
+		return err
+	}
+	if delivery.WebhookID != webhookID {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	if err := s.webhookDAL.ResetDelivery(deliveryID); err != nil {
+		return err
+	}
+	if webhookDispatcher != nil {
+		webhookDispatcher.Wake()
+	}
+	return nil
+}
+
+// getWebhook 检查管理权限并获取属于该聊天室的Webhook
+func (s *WebhookService) getWebhook(operatorID, roomID, webhookID uint) (*entities.Webhook, error) {
+	if _, err := s.roomService.checkRoomAuthority(operatorID, roomID, entities.RoomRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	webhook, err := s.webhookDAL.GetByID(webhookID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	if webhook.RoomID != roomID {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// validateURL 校验Webhook地址；未允许内网时拒绝字面量内网地址和localhost
+// 域名解析后的地址在投递时再次检查
+func (s *WebhookService) validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrWebhookURLInvalid
+	}
+	if s.cfg.AllowPrivateNetworks {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrWebhookURLPrivate
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) {
+		return ErrWebhookURLPrivate
+	}
+	return nil
+}
+
+// normalizeWebhookEvents 校验并去重订阅事件
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	seen := make(map[string]bool, len(events))
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !isWebhookEvent(event) {
+			return nil, ErrWebhookEventInvalid
+		}
+		if !seen[event] {
+			seen[event] = true
+			result = append(result, event)
+		}
+	}
+	if len(result) == 0 {
+		return nil, ErrWebhookEventInvalid
+	}
+	return result, nil
+}
+
+// isWebhookEvent 判断是否为可订阅的事件
+func isWebhookEvent(event string) bool {
+	for _, e := range entities.WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// newWebhookInfo 构建Webhook信息
+func newWebhookInfo(webhook *entities.Webhook) *responses.WebhookInfo {
+	return &responses.WebhookInfo{
+		ID:        webhook.ID,
+		RoomID:    webhook.RoomID,
+		URL:       webhook.URL,
+		Events:    webhook.EventList(),
+		IsActive:  webhook.IsActive,
+		CreatedBy: webhook.CreatedBy,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
+
+// newWebhookDeliveryInfo 构建投递记录信息
+func newWebhookDeliveryInfo(delivery *entities.WebhookDelivery) *responses.WebhookDeliveryInfo {
+	info := &responses.WebhookDeliveryInfo{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		Event:          delivery.Event,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+		Payload:        json.RawMessage(delivery.Payload),
+	}
+	if delivery.Status == entities.WebhookDeliveryPending {
+		nextAttemptAt := delivery.NextAttemptAt
+		info.NextAttemptAt = &nextAttemptAt
+	}
+	return info
+}
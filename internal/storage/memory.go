@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// MemoryStore 内存存储，用于测试和本地调试，进程退出后数据丢失
+type MemoryStore struct {
+	mu    sync.RWMutex
+	blobs map[string]*memoryBlob
+}
+
+type memoryBlob struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		blobs: make(map[string]*memoryBlob),
+	}
+}
+
+// Put 读取全部内容后保存
+func (s *MemoryStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.blobs[key] = &memoryBlob{
+		data:        data,
+		contentType: contentType,
+		modTime:     time.Now(),
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Get 读取对象，内容写入后不再修改，可以直接共享
+func (s *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error) {
+	blob, err := s.get(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.NopCloser(bytes.NewReader(blob.data)), blob.info(), nil
+}
+
+// GetRange 读取对象的一部分
+func (s *MemoryStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, *BlobInfo, error) {
+	blob, err := s.get(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkRange(offset, length, int64(len(blob.data))); err != nil {
+		return nil, nil, err
+	}
+	return io.NopCloser(bytes.NewReader(blob.data[offset : offset+length])), blob.info(), nil
+}
+
+// Stat 获取对象元信息
+func (s *MemoryStore) Stat(ctx context.Context, key string) (*BlobInfo, error) {
+	blob, err := s.get(key)
+	if err != nil {
+		return nil, err
+	}
+	return blob.info(), nil
+}
+
+// Delete 删除对象
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.blobs, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// PresignGet 内存存储不支持预签名
+func (s *MemoryStore) PresignGet(ctx context.Context, key string, opts *PresignOptions) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+func (s *MemoryStore) get(key string) (*memoryBlob, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	blob, ok := s.blobs[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return blob, nil
+}
+
+func (b *memoryBlob) info() *BlobInfo {
+	return &BlobInfo{
+		Size:        int64(len(b.data)),
+		ContentType: b.contentType,
+		ModTime:     b.modTime,
+		ETag:        fmt.Sprintf("\"%x-%x\"", b.modTime.UnixNano(), len(b.data)),
+	}
+}
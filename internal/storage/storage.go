@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gochat/internal/config"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// 存储后端
+const (
+	DriverLocal  = "local"  // 本地磁盘
+	DriverS3     = "s3"     // S3兼容对象存储（AWS S3、MinIO等）
+	DriverMemory = "memory" // 内存（测试和本地调试，重启后丢失）
+)
+
+// 存储错误
+var (
+	ErrNotFound            = errors.New("blob not found")
+	ErrInvalidKey          = errors.New("invalid blob key")
+	ErrPresignNotSupported = errors.New("presigned URLs are not supported by this store")
+	ErrInvalidRange        = errors.New("invalid blob range")
+)
+
+// Store 全局附件存储，由Init根据配置创建
+var Store BlobStore
+
+// BlobInfo 对象元信息
+type BlobInfo struct {
+	Size        int64 // 对象总大小，范围读取时也是整个对象的大小
+	ContentType string
+	ModTime     time.Time
+	ETag        string // 强实体标签（含引号），对象内容变化时随之变化
+}
+
+// PresignOptions 预签名下载地址参数
+type PresignOptions struct {
+	Expires            time.Duration
+	ContentType        string // 覆盖响应的Content-Type
+	ContentDisposition string // 覆盖响应的Content-Disposition
+}
+
+// BlobStore 附件存储接口，key为以"/"分隔的相对路径
+type BlobStore interface {
+	// Put 写入对象，已存在时覆盖
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，调用方负责关闭返回的Reader
+	Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error)
+	// GetRange 读取对象从offset开始的length字节，范围必须在对象大小之内，否则返回ErrInvalidRange
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, *BlobInfo, error)
+	// Stat 获取对象元信息
+	Stat(ctx context.Context, key string) (*BlobInfo, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// PresignGet 生成无需认证即可下载的临时地址，不支持时返回ErrPresignNotSupported
+	PresignGet(ctx context.Context, key string, opts *PresignOptions) (string, error)
+}
+
+// New 根据配置创建附件存储
+func New(cfg *config.StorageConfig) (BlobStore, error) {
+	switch cfg.Driver {
+	case "", DriverLocal:
+		return NewLocalStore(cfg.LocalDir)
+	case DriverS3:
+		return NewS3Store(&cfg.S3)
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Driver)
+	}
+}
+
+// Init 根据配置创建全局附件存储
+func Init(cfg *config.StorageConfig) error {
+	store, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	Store = store
+	return nil
+}
+
+// partialReader 只读取对象的一部分，关闭时释放整个底层资源（文件或响应体）
+type partialReader struct {
+	io.Reader
+	io.Closer
+}
+
+// checkRange 检查读取范围是否在对象大小之内
+func checkRange(offset, length, size int64) error {
+	if offset < 0 || length <= 0 || offset+length > size {
+		return ErrInvalidRange
+	}
+	return nil
+}
+
+// cleanKey 规范化对象key，拒绝空key和跳出根目录的路径
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
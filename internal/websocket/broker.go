@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"gochat/internal/config"
+	"gochat/pkg/logger"
+	"sync"
+)
+
+// 广播总线相关常量
+const (
+	BrokerMemory = "memory" // 进程内广播，仅适用于单节点
+	BrokerRedis  = "redis"  // Redis发布订阅，适用于多节点部署
+
+	brokerBufferSize = 1000 // 订阅通道缓冲大小
+)
+
+// 广播总线控制动作
+const (
+	EnvelopeActionEvict      = "evict"      // 将用户移出聊天室
+	EnvelopeActionDisconnect = "disconnect" // 断开用户的连接
+	EnvelopeActionPresence   = "presence"   // 发布节点的聊天室在线列表
+)
+
+// ErrBrokerClosed 广播总线已关闭
+var ErrBrokerClosed = errors.New("broker is closed")
+
+// Envelope 在节点之间传递的广播消息
+type Envelope struct {
+	NodeID          string     `json:"node_id"`                     // 发布消息的节点
+	RoomID          uint       `json:"room_id"`                     // 目标聊天室
+	UserID          uint       `json:"user_id,omitempty"`           // 目标用户（设置时投递给该用户的所有连接，忽略RoomID）
+	Message         *WSMessage `json:"message"`                     // 广播的消息
+	ExcludeClientID string     `json:"exclude_client_id,omitempty"` // 排除的客户端（仅在发布节点生效）
+	Action          string     `json:"action,omitempty"`            // 控制动作（为空时表示普通投递）
+	SessionID       uint       `json:"session_id,omitempty"`        // 断开连接时只断开该登录会话的连接
+	Users           []UserInfo `json:"users,omitempty"`             // 发布节点聊天室中的在线用户
+	Sync            bool       `json:"sync,omitempty"`              // 请求其他节点回复各自的在线列表
+}
+
+// Broker 跨节点广播总线
+// Hub将广播消息发布到总线，每个节点订阅总线并投递给本地聊天室中的客户端
+type Broker interface {
+	// Publish 发布广播消息
+	Publish(envelope *Envelope) error
+	// Subscribe 订阅广播消息，返回的通道在总线关闭后关闭
+	Subscribe() (<-chan *Envelope, error)
+	// Close 关闭总线
+	Close() error
+}
+
+// NewBroker 根据配置创建广播总线
+func NewBroker(wsCfg *config.WebSocketConfig, redisCfg *config.RedisConfig) (Broker, error) {
+	switch wsCfg.Broker {
+	case "", BrokerMemory:
+		return NewMemoryBroker(), nil
+	case BrokerRedis:
+		return NewRedisBroker(redisCfg)
+	default:
+		return nil, fmt.Errorf("unsupported websocket broker: %s", wsCfg.Broker)
+	}
+}
+
+// MemoryBroker 进程内广播总线
+type MemoryBroker struct {
+	subscribers []chan *Envelope
+	closed      bool
+	mutex       sync.RWMutex
+}
+
+// NewMemoryBroker 创建进程内广播总线
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+// Publish 发布广播消息到所有订阅者
+// 订阅通道已满时丢弃该订阅者的消息，不阻塞发布者（与Redis发布订阅一样不保证送达）
+func (b *MemoryBroker) Publish(envelope *Envelope) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.closed {
+		return ErrBrokerClosed
+	}
+
+	for _, subscriber := range b.subscribers {
+		select {
+		case subscriber <- envelope:
+		default:
+			logger.Error("Broker subscriber buffer is full, dropping envelope for room:", envelope.RoomID)
+		}
+	}
+	return nil
+}
+
+// Subscribe 订阅广播消息
+func (b *MemoryBroker) Subscribe() (<-chan *Envelope, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return nil, ErrBrokerClosed
+	}
+
+	subscriber := make(chan *Envelope, brokerBufferSize)
+	b.subscribers = append(b.subscribers, subscriber)
+	return subscriber, nil
+}
+
+// Close 关闭总线并关闭所有订阅通道
+func (b *MemoryBroker) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return nil
+	}
+
+	b.closed = true
+	for _, subscriber := range b.subscribers {
+		close(subscriber)
+	}
+	b.subscribers = nil
+	return nil
+}
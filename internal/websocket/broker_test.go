@@ -0,0 +1,197 @@
+package websocket
+
+import (
+	"encoding/json"
+	"gochat/internal/config"
+	"gochat/internal/mockredis"
+	"gochat/pkg/logger"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+const testRoomID uint = 1
+
+func TestMain(m *testing.M) {
+	// 各节点的goroutine并发写日志，先初始化日志实例，避免延迟初始化的数据竞争
+	logger.Init(&config.LogConfig{Level: "error"})
+	os.Exit(m.Run())
+}
+
+// newTestRedisConfig 启动模拟Redis服务，返回连接配置
+func newTestRedisConfig(t *testing.T) *config.RedisConfig {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go mockredis.NewServer("").Serve(listener)
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return &config.RedisConfig{
+		Host:    addr.IP.String(),
+		Port:    addr.Port,
+		Channel: "gochat:test",
+	}
+}
+
+// newTestHub 创建连接到模拟Redis的Hub，模拟一个独立节点
+func newTestHub(t *testing.T, cfg *config.RedisConfig) *Hub {
+	t.Helper()
+
+	broker, err := NewRedisBroker(cfg)
+	if err != nil {
+		t.Fatalf("new redis broker: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+
+	hub, err := NewHub(broker)
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	return hub
+}
+
+// addTestClient 向Hub的聊天室中加入一个不带连接的客户端
+func addTestClient(hub *Hub, userID uint, username string) *Client {
+	client := &Client{
+		ID:       generateClientID(userID),
+		UserID:   userID,
+		Username: username,
+		Send:     make(chan []byte, 16),
+		Hub:      hub,
+		Rooms:    map[uint]bool{testRoomID: true},
+		resuming: make(map[uint][]*heldMessage),
+		closeCh:  make(chan struct{}),
+	}
+
+	hub.mutex.Lock()
+	hub.clients[client] = true
+	if hub.rooms[testRoomID] == nil {
+		hub.rooms[testRoomID] = make(map[*Client]bool)
+	}
+	hub.rooms[testRoomID][client] = true
+	hub.mutex.Unlock()
+	return client
+}
+
+// receive 等待客户端收到满足条件的消息
+func receive[T any](t *testing.T, client *Client, match func(*T) bool) *T {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case data := <-client.Send:
+			var message T
+			if err := json.Unmarshal(data, &message); err != nil {
+				t.Fatalf("unmarshal message: %v", err)
+			}
+			if match(&message) {
+				return &message
+			}
+		case <-timeout:
+			t.Fatalf("client %s did not receive the expected message", client.Username)
+			return nil
+		}
+	}
+}
+
+func TestRedisBrokerFanOutAcrossHubs(t *testing.T) {
+	cfg := newTestRedisConfig(t)
+	hub1 := newTestHub(t, cfg)
+	hub2 := newTestHub(t, cfg)
+	sender := addTestClient(hub1, 1, "alice")
+	receiver := addTestClient(hub2, 2, "bob")
+
+	hub1.handleBroadcast(&BroadcastMessage{
+		RoomID:  testRoomID,
+		Message: &WSMessage{Type: MessageTypeText, RoomID: testRoomID, Content: "hello"},
+		Exclude: sender,
+	})
+	got := receive(t, receiver, func(m *WSMessage) bool { return m.Type == MessageTypeText })
+	if got.Content != "hello" {
+		t.Fatalf("content = %q, want %q", got.Content, "hello")
+	}
+
+	// 排除的客户端只在发布节点上跳过
+	select {
+	case data := <-sender.Send:
+		t.Fatalf("excluded client received %s", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	hub2.SendToUser(1, &WSMessage{Type: MessageTypeSystem, Content: "direct"})
+	got = receive(t, sender, func(m *WSMessage) bool { return m.Type == MessageTypeSystem })
+	if got.Content != "direct" {
+		t.Fatalf("content = %q, want %q", got.Content, "direct")
+	}
+}
+
+func TestPresenceMergesAndExpiresAcrossHubs(t *testing.T) {
+	cfg := newTestRedisConfig(t)
+	hub1 := newTestHub(t, cfg)
+	hub2 := newTestHub(t, cfg)
+	addTestClient(hub1, 1, "alice")
+	receiver := addTestClient(hub2, 2, "bob")
+
+	hub1.sendUserList(testRoomID)
+	receive(t, receiver, func(m *UserListMessage) bool {
+		return m.Type == MessageTypeUserList && len(m.Users) == 2
+	})
+
+	// 模拟hub1异常退出：其在线列表不再刷新，超过presenceTTL后被清理
+	hub2.mutex.Lock()
+	for _, presence := range hub2.presence[testRoomID] {
+		presence.seenAt = time.Now().Add(-presenceTTL - time.Second)
+	}
+	hub2.mutex.Unlock()
+
+	for _, roomID := range hub2.expirePresence(time.Now()) {
+		hub2.deliverUserList(roomID)
+	}
+	got := receive(t, receiver, func(m *UserListMessage) bool {
+		return m.Type == MessageTypeUserList && len(m.Users) == 1
+	})
+	if got.Users[0].UserID != 2 {
+		t.Fatalf("remaining user = %d, want 2", got.Users[0].UserID)
+	}
+}
+
+func TestMemoryBrokerDropsWhenSubscriberIsFull(t *testing.T) {
+	broker := NewMemoryBroker()
+	subscriber, err := broker.Subscribe()
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < brokerBufferSize+10; i++ {
+			if err := broker.Publish(&Envelope{RoomID: testRoomID}); err != nil {
+				t.Errorf("publish: %v", err)
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publish blocked on a full subscriber")
+	}
+	if len(subscriber) != brokerBufferSize {
+		t.Fatalf("buffered envelopes = %d, want %d", len(subscriber), brokerBufferSize)
+	}
+
+	if err := broker.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := broker.Publish(&Envelope{}); err != ErrBrokerClosed {
+		t.Fatalf("publish after close = %v, want %v", err, ErrBrokerClosed)
+	}
+}
@@ -12,14 +12,29 @@ import (
 
 // Client WebSocket 客户端连接
 type Client struct {
-	ID       string          // 客户端唯一标识
-	UserID   uint            // 用户ID
-	Username string          // 用户名
-	Conn     *websocket.Conn // WebSocket 连接
-	Send     chan []byte     // 发送消息通道
-	Hub      *Hub            // 连接管理中心
-	Rooms    map[uint]bool   // 用户加入的聊天室
-	mutex    sync.RWMutex    // 读写锁
+	ID        string          // 客户端唯一标识
+	UserID    uint            // 用户ID
+	Username  string          // 用户名
+	Conn      *websocket.Conn // WebSocket 连接
+	Send      chan []byte     // 发送消息通道
+	Hub       *Hub            // 连接管理中心
+	Rooms     map[uint]bool   // 用户加入的聊天室
+	SessionID uint            // 建立连接时使用的登录会话ID
+	mutex     sync.RWMutex    // 读写锁
+
+	// 强制断开连接
+	closeCh     chan struct{}
+	closeOnce   sync.Once
+	closeNotice []byte
+
+	// 断线重连补发期间暂存的实时消息，按聊天室区分
+	resuming map[uint][]*heldMessage
+}
+
+// heldMessage 补发历史消息期间暂存的实时消息
+type heldMessage struct {
+	message *WSMessage
+	data    []byte
 }
 
 const (
@@ -27,7 +42,7 @@ const (
 	writeWait      = 10 * time.Second    // 写入超时时间
 	pongWait       = 60 * time.Second    // pong响应等待时间
 	pingPeriod     = (pongWait * 9) / 10 // ping发送周期
-	maxMessageSize = 512                 // 最大消息大小
+	maxMessageSize = 4096                // 最大消息大小（resume消息需要携带多个聊天室游标）
 )
 
 // NewClient 创建新的客户端连接
@@ -40,6 +55,8 @@ func NewClient(userID uint, username string, conn *websocket.Conn, hub *Hub) *Cl
 		Send:     make(chan []byte, 256),
 		Hub:      hub,
 		Rooms:    make(map[uint]bool),
+		resuming: make(map[uint][]*heldMessage),
+		closeCh:  make(chan struct{}),
 	}
 }
 
@@ -75,6 +92,14 @@ func (c *Client) LeaveRoom(roomID uint) {
 	}
 }
 
+// evictRoom 被移出聊天室时清理本地状态（包括未完成的补发）
+func (c *Client) evictRoom(roomID uint) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.Rooms, roomID)
+	delete(c.resuming, roomID)
+}
+
 // IsInRoom 检查是否在聊天室中
 func (c *Client) IsInRoom(roomID uint) bool {
 	c.mutex.RLock()
@@ -108,6 +133,62 @@ func (c *Client) SendMessage(message []byte) {
 	}
 }
 
+// beginResume 开始补发聊天室历史消息，期间该聊天室的实时消息会被暂存
+func (c *Client) beginResume(roomID uint) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, exists := c.resuming[roomID]; !exists {
+		c.resuming[roomID] = []*heldMessage{}
+	}
+}
+
+// holdIfResuming 如果聊天室正在补发历史消息，则暂存实时消息并返回true
+func (c *Client) holdIfResuming(roomID uint, message *WSMessage, data []byte) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	held, exists := c.resuming[roomID]
+	if !exists {
+		return false
+	}
+	c.resuming[roomID] = append(held, &heldMessage{message: message, data: data})
+	return true
+}
+
+// finishResume 结束补发，按顺序投递暂存的实时消息
+// lastReplayedID之前的新消息已经通过补发送达，不再重复投递
+func (c *Client) finishResume(roomID, lastReplayedID uint) {
+	c.mutex.Lock()
+	held := c.resuming[roomID]
+	delete(c.resuming, roomID)
+	c.mutex.Unlock()
+
+	for _, item := range held {
+		if item.message.MessageID > 0 && item.message.MessageID <= lastReplayedID && isChatMessage(item.message.Type) {
+			continue
+		}
+		c.sendBlocking(item.data)
+	}
+}
+
+// sendBlocking 阻塞发送消息，直到写入发送通道或超时（用于按序补发消息）
+func (c *Client) sendBlocking(data []byte) (ok bool) {
+	// 连接关闭后发送通道会被关闭，防止panic
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Panic in sendBlocking for client:", c.Username, "panic:", r)
+			ok = false
+		}
+	}()
+
+	select {
+	case c.Send <- data:
+		return true
+	case <-time.After(writeWait):
+		logger.Error("Timeout sending message to client:", c.Username)
+		return false
+	}
+}
+
 // SendWSMessage 发送WebSocket消息
 func (c *Client) SendWSMessage(msg *WSMessage) error {
 	logger.Info("准备发送WebSocket消息给客户端:", c.Username, "消息类型:", msg.Type, "内容:", msg.Content)
@@ -142,10 +223,16 @@ func (c *Client) SendWSMessage(msg *WSMessage) error {
 
 // SendError 发送错误消息
 func (c *Client) SendError(errorMsg string, code int) {
+	c.SendErrorWithID("", errorMsg, code)
+}
+
+// SendErrorWithID 发送带客户端消息ID的错误消息，便于客户端关联失败的请求
+func (c *Client) SendErrorWithID(clientMsgID string, errorMsg string, code int) {
 	errMsg := &ErrorMessage{
-		Type:  MessageTypeError,
-		Error: errorMsg,
-		Code:  code,
+		Type:        MessageTypeError,
+		Error:       errorMsg,
+		Code:        code,
+		ClientMsgID: clientMsgID,
 	}
 
 	data, err := json.Marshal(errMsg)
@@ -157,6 +244,14 @@ func (c *Client) SendError(errorMsg string, code int) {
 	c.SendMessage(data)
 }
 
+// ForceClose 强制断开连接，notice不为空时在断开前发送给客户端
+func (c *Client) ForceClose(notice []byte) {
+	c.closeOnce.Do(func() {
+		c.closeNotice = notice
+		close(c.closeCh)
+	})
+}
+
 // ReadPump 读取消息循环
 func (c *Client) ReadPump() {
 	defer func() {
@@ -245,6 +340,16 @@ func (c *Client) WritePump() {
 
 			logger.Info("消息发送完成:", c.Username)
 
+		case <-c.closeCh:
+			// 服务端强制断开，先发送通知再关闭连接
+			logger.Info("Force closing client:", c.Username)
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if c.closeNotice != nil {
+				c.Conn.WriteMessage(websocket.TextMessage, c.closeNotice)
+			}
+			c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session revoked"))
+			return
+
 		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -255,7 +360,7 @@ func (c *Client) WritePump() {
 	}
 }
 
-// generateClientID 生成客户端ID
+// generateClientID 生成客户端ID（精确到纳秒，避免同一用户多个连接冲突）
 func generateClientID(userID uint) string {
-	return time.Now().Format("20060102150405") + "_" + strconv.Itoa(int(userID))
+	return time.Now().Format("20060102150405.000000000") + "_" + strconv.Itoa(int(userID))
 }
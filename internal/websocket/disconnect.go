@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"encoding/json"
+	"gochat/pkg/logger"
+	"time"
+)
+
+// DisconnectUser 断开用户在所有节点上的连接，sessionID不为0时只断开该登录会话建立的连接
+func (h *Hub) DisconnectUser(userID uint, sessionID uint, reason string) {
+	envelope := &Envelope{
+		NodeID:    h.nodeID,
+		UserID:    userID,
+		SessionID: sessionID,
+		Action:    EnvelopeActionDisconnect,
+		Message: &WSMessage{
+			Type:      MessageTypeForceLogout,
+			UserID:    userID,
+			Content:   reason,
+			Timestamp: time.Now(),
+		},
+	}
+
+	h.publish(envelope)
+}
+
+// disconnectLocal 断开本节点上匹配的用户连接，连接关闭后由注销流程清理
+func (h *Hub) disconnectLocal(envelope *Envelope) {
+	var noticeData []byte
+	if envelope.Message != nil {
+		data, err := json.Marshal(envelope.Message)
+		if err != nil {
+			logger.Error("Failed to marshal disconnect notice:", err)
+		} else {
+			noticeData = data
+		}
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for client := range h.clients {
+		if client.UserID != envelope.UserID {
+			continue
+		}
+		if envelope.SessionID != 0 && client.SessionID != envelope.SessionID {
+			continue
+		}
+		client.ForceClose(noticeData)
+		logger.Info("Client force disconnected:", client.Username, "ClientID:", client.ID)
+	}
+}
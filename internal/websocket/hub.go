@@ -1,10 +1,15 @@
 package websocket
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"gochat/internal/models/entities"
 	"gochat/internal/services"
 	"gochat/pkg/logger"
+	"strings"
 	"sync"
 	"time"
 )
@@ -25,10 +30,17 @@ type Hub struct {
 	handleMessage chan *ClientMessage    // 处理消息通道
 	broadcast     chan *BroadcastMessage // 广播消息通道
 
+	// 跨节点广播
+	broker       Broker                            // 广播总线
+	publishQueue chan *Envelope                    // 待发布的广播消息，由独立协程发布，避免阻塞主循环
+	nodeID       string                            // 当前节点标识
+	presence     map[uint]map[string]*nodePresence // 其他节点上聊天室的在线用户（聊天室ID → 节点ID → 在线列表）
+
 	// 服务
-	messageService *services.MessageService
-	roomService    *services.RoomService
-	userService    *services.UserService
+	messageService    *services.MessageService
+	roomService       *services.RoomService
+	userService       *services.UserService
+	moderationService *services.ModerationService
 
 	// 线程安全
 	mutex sync.RWMutex
@@ -69,20 +81,29 @@ var (
 	Broadcast     chan *BroadcastMessage
 )
 
-// NewHub 创建新的Hub实例
-func NewHub() *Hub {
+// NewHub 创建新的Hub实例，broker为nil时使用进程内广播总线
+func NewHub(broker Broker) (*Hub, error) {
+	if broker == nil {
+		broker = NewMemoryBroker()
+	}
+
 	hub := &Hub{
-		clients:        make(map[*Client]bool),
-		register:       make(chan *Client, 10), // 添加缓冲
-		unregister:     make(chan *Client, 10), // 添加缓冲
-		rooms:          make(map[uint]map[*Client]bool),
-		joinRoom:       make(chan *JoinRoomMessage, 100),   // 添加缓冲
-		leaveRoom:      make(chan *LeaveRoomMessage, 100),  // 添加缓冲
-		handleMessage:  make(chan *ClientMessage, 1000),    // 添加较大缓冲，处理消息
-		broadcast:      make(chan *BroadcastMessage, 1000), // 添加较大缓冲，处理广播
-		messageService: services.NewMessageService(),
-		roomService:    services.NewRoomService(),
-		userService:    services.NewUserService(),
+		clients:           make(map[*Client]bool),
+		register:          make(chan *Client, 10), // 添加缓冲
+		unregister:        make(chan *Client, 10), // 添加缓冲
+		rooms:             make(map[uint]map[*Client]bool),
+		joinRoom:          make(chan *JoinRoomMessage, 100),   // 添加缓冲
+		leaveRoom:         make(chan *LeaveRoomMessage, 100),  // 添加缓冲
+		handleMessage:     make(chan *ClientMessage, 1000),    // 添加较大缓冲，处理消息
+		broadcast:         make(chan *BroadcastMessage, 1000), // 添加较大缓冲，处理广播
+		broker:            broker,
+		publishQueue:      make(chan *Envelope, brokerBufferSize),
+		nodeID:            generateNodeID(),
+		presence:          make(map[uint]map[string]*nodePresence),
+		messageService:    services.NewMessageService(),
+		roomService:       services.NewRoomService(),
+		userService:       services.NewUserService(),
+		moderationService: services.NewModerationService(),
 	}
 
 	// 设置公开通道
@@ -93,7 +114,16 @@ func NewHub() *Hub {
 	HandleMessage = hub.handleMessage
 	Broadcast = hub.broadcast
 
-	return hub
+	// 订阅广播总线，所有节点（包括本节点）发布的消息都从这里投递给本地客户端
+	envelopes, err := broker.Subscribe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe broker: %w", err)
+	}
+	go hub.consumeBroker(envelopes)
+	go hub.runPublisher()
+	go hub.runPresenceHeartbeat()
+
+	return hub, nil
 }
 
 // Run 启动Hub主循环
@@ -249,6 +279,11 @@ func (h *Hub) handleClientMessage(clientMsg *ClientMessage) {
 
 	logger.Info("开始处理客户端消息:", client.Username, "消息类型:", message.Type, "RoomID:", message.RoomID)
 
+	if len(message.ClientMsgID) > maxClientMsgIDLength {
+		client.SendError("客户端消息ID过长", 400)
+		return
+	}
+
 	switch message.Type {
 	case MessageTypeText:
 		logger.Info("处理文本消息:", client.Username)
@@ -277,6 +312,24 @@ func (h *Hub) handleClientMessage(clientMsg *ClientMessage) {
 	case MessageTypePing:
 		logger.Info("处理心跳ping消息:", client.Username)
 		h.handlePingMessage(client)
+	case MessageTypeEdit:
+		logger.Info("处理编辑消息:", client.Username, "MessageID:", message.MessageID)
+		h.handleEditMessage(client, message)
+	case MessageTypeDelete:
+		logger.Info("处理删除消息:", client.Username, "MessageID:", message.MessageID)
+		h.handleDeleteMessage(client, message)
+	case MessageTypeRead:
+		logger.Info("处理已读回执:", client.Username, "RoomID:", message.RoomID, "MessageID:", message.MessageID)
+		h.handleReadMessage(client, message)
+	case MessageTypeReact, MessageTypeUnreact:
+		logger.Info("处理表情回应:", client.Username, "MessageID:", message.MessageID, "Emoji:", message.Emoji)
+		h.handleReactionMessage(client, message)
+	case MessageTypeResume:
+		logger.Info("处理恢复会话消息:", client.Username, "Rooms:", len(message.Rooms))
+		h.handleResumeMessage(client, message)
+	case MessageTypeKick, MessageTypeBan, MessageTypeUnban, MessageTypeMute, MessageTypeUnmute:
+		logger.Info("处理聊天室管理操作:", client.Username, "Type:", message.Type, "RoomID:", message.RoomID, "Target:", message.TargetUserID)
+		h.handleModerationMessage(client, message)
 	default:
 		logger.Error("不支持的消息类型:", message.Type, "用户:", client.Username)
 		client.SendError("不支持的消息类型", 400)
@@ -288,12 +341,16 @@ func (h *Hub) handleClientMessage(clientMsg *ClientMessage) {
 // handleTextMessage 处理文本消息
 func (h *Hub) handleTextMessage(client *Client, message *WSMessage) {
 	if message.RoomID == 0 {
-		client.SendError("聊天室ID不能为空", 400)
+		client.SendErrorWithID(message.ClientMsgID, "聊天室ID不能为空", 400)
 		return
 	}
 
 	if !client.IsInRoom(message.RoomID) {
-		client.SendError("您不在此聊天室中", 403)
+		client.SendErrorWithID(message.ClientMsgID, "您不在此聊天室中", 403)
+		return
+	}
+
+	if !h.checkNotMuted(client, message) {
 		return
 	}
 
@@ -304,45 +361,77 @@ func (h *Hub) handleTextMessage(client *Client, message *WSMessage) {
 		Content:     message.Content,
 		MessageType: string(MessageTypeText),
 	}
+	if message.ReplyToID > 0 {
+		dbMessage.ReplyToID = &message.ReplyToID
+	}
 
-	savedMessage, err := h.messageService.CreateMessage(dbMessage)
+	savedMessage, created, err := h.messageService.CreateMessageWithClientID(dbMessage, message.ClientMsgID)
 	if err != nil {
 		logger.Error("Failed to save message:", err)
-		client.SendError("消息发送失败", 500)
+		client.SendErrorWithID(message.ClientMsgID, messageErrorText(err, "消息发送失败"), messageErrorCode(err))
+		return
+	}
+
+	// 重复提交的消息只回复确认，不重复广播
+	if !created {
+		logger.Info("Duplicate message ignored:", client.Username, "ClientMsgID:", message.ClientMsgID)
+		h.sendAck(client, message.ClientMsgID, savedMessage)
 		return
 	}
 
-	// 设置消息ID和时间戳
-	message.MessageID = savedMessage.ID
-	message.UserID = client.UserID
-	message.Username = client.Username
-	message.Timestamp = savedMessage.CreatedAt
+	// 广播内容以数据库中的消息为准，客户端提交的其他字段（is_bot、reactions等）一律忽略
+	h.PublishMessage(h.savedMessageWithSender(client, savedMessage))
+	h.sendAck(client, message.ClientMsgID, savedMessage)
+
+	logger.Info("Message sent:", client.Username, "RoomID:", message.RoomID, "Content:", message.Content)
+}
+
+// PublishMessage 广播已持久化的消息（REST接口和WebSocket发送的文本消息共用）
+// savedMessage需已加载发送者信息
+func (h *Hub) PublishMessage(savedMessage *entities.Message) {
+	message := NewWSMessageFromEntity(savedMessage)
 
-	// 广播消息到聊天室
 	h.broadcast <- &BroadcastMessage{
-		RoomID:  message.RoomID,
+		RoomID:  savedMessage.RoomID,
 		Message: message,
-		Exclude: nil, // 不排除任何人，包括发送者
+		Exclude: nil,
 	}
 
-	logger.Info("Message sent:", client.Username, "RoomID:", message.RoomID, "Content:", message.Content)
+	h.broadcastThreadUpdate(savedMessage.ThreadRootID)
+
+	logger.Info("Message posted:", savedMessage.User.Username, "RoomID:", savedMessage.RoomID, "MessageID:", savedMessage.ID)
+}
+
+// savedMessageWithSender 重新加载消息以带上发送者信息，加载失败时使用连接上的用户信息
+func (h *Hub) savedMessageWithSender(client *Client, savedMessage *entities.Message) *entities.Message {
+	message, err := h.messageService.GetMessageByID(savedMessage.ID)
+	if err != nil {
+		logger.Error("Failed to reload message:", err)
+		savedMessage.User = entities.User{ID: client.UserID, Username: client.Username}
+		return savedMessage
+	}
+	return message
 }
 
 // handleMediaMessage 处理多媒体消息
 func (h *Hub) handleMediaMessage(client *Client, message *WSMessage) {
 	if message.RoomID == 0 {
-		client.SendError("聊天室ID不能为空", 400)
+		client.SendErrorWithID(message.ClientMsgID, "聊天室ID不能为空", 400)
 		return
 	}
 
 	if !client.IsInRoom(message.RoomID) {
-		client.SendError("您不在此聊天室中", 403)
+		client.SendErrorWithID(message.ClientMsgID, "您不在此聊天室中", 403)
+		return
+	}
+
+	if !h.checkNotMuted(client, message) {
 		return
 	}
 
 	// 验证附件信息是否存在
 	if len(message.Attachments) == 0 {
-		client.SendError("多媒体消息必须包含附件", 400)
+		client.SendErrorWithID(message.ClientMsgID, "多媒体消息必须包含附件", 400)
 		return
 	}
 
@@ -353,40 +442,319 @@ func (h *Hub) handleMediaMessage(client *Client, message *WSMessage) {
 		Content:     message.Content, // 可能为空，多媒体消息的描述文字
 		MessageType: string(message.Type),
 	}
+	if message.ReplyToID > 0 {
+		dbMessage.ReplyToID = &message.ReplyToID
+	}
 
-	savedMessage, err := h.messageService.CreateMessage(dbMessage)
+	savedMessage, created, err := h.messageService.CreateMessageWithClientID(dbMessage, message.ClientMsgID)
 	if err != nil {
 		logger.Error("Failed to save media message:", err)
-		client.SendError("多媒体消息发送失败", 500)
+		client.SendErrorWithID(message.ClientMsgID, messageErrorText(err, "多媒体消息发送失败"), messageErrorCode(err))
 		return
 	}
 
-	// 设置消息ID和时间戳
-	message.MessageID = savedMessage.ID
-	message.UserID = client.UserID
-	message.Username = client.Username
-	message.Timestamp = savedMessage.CreatedAt
+	// 重复提交的消息只回复确认，附件已在首次提交时关联
+	if !created {
+		logger.Info("Duplicate media message ignored:", client.Username, "ClientMsgID:", message.ClientMsgID)
+		h.sendAck(client, message.ClientMsgID, savedMessage)
+		return
+	}
 
-	// 更新附件的消息ID（如果附件是临时上传的）
+	// 关联发送者自己上传的临时附件，广播的附件信息以数据库为准
+	// 他人上传或已被其他消息使用的附件会被忽略
 	attachmentService := services.NewAttachmentService()
+	attachments := make([]AttachmentInfo, 0, len(message.Attachments))
 	for _, attachment := range message.Attachments {
-		if attachment.ID > 0 {
-			err := attachmentService.UpdateAttachmentMessageID(attachment.ID, savedMessage.ID)
-			if err != nil {
-				logger.Error("Failed to update attachment message ID:", err)
-				// 不阻断消息发送，仅记录错误
-			}
+		if attachment.ID == 0 {
+			continue
 		}
+		attached, err := attachmentService.AttachToMessage(attachment.ID, client.UserID, savedMessage.ID, message.RoomID)
+		if err != nil {
+			logger.Error("Failed to attach attachment to message:", err)
+			// 不阻断消息发送，仅记录错误
+			continue
+		}
+		if attached == nil {
+			logger.Warn("Attachment not attachable:", attachment.ID, "UserID:", client.UserID)
+			continue
+		}
+		attachments = append(attachments, NewAttachmentInfo(attached))
 	}
 
+	// 广播内容以数据库中的消息为准，客户端提交的其他字段（is_bot、reactions等）一律忽略
+	broadcastMessage := NewWSMessageFromEntity(h.savedMessageWithSender(client, savedMessage))
+	broadcastMessage.Attachments = attachments
+
 	// 广播消息到聊天室
 	h.broadcast <- &BroadcastMessage{
-		RoomID:  message.RoomID,
-		Message: message,
+		RoomID:  savedMessage.RoomID,
+		Message: broadcastMessage,
 		Exclude: nil, // 不排除任何人，包括发送者
 	}
 
-	logger.Info("Media message sent:", client.Username, "RoomID:", message.RoomID, "Type:", message.Type, "Attachments:", len(message.Attachments))
+	h.sendAck(client, message.ClientMsgID, savedMessage)
+	h.broadcastThreadUpdate(savedMessage.ThreadRootID)
+
+	logger.Info("Media message sent:", client.Username, "RoomID:", message.RoomID, "Type:", message.Type, "Attachments:", len(attachments))
+}
+
+// handleReadMessage 处理已读回执，推进已读位置并广播给聊天室
+func (h *Hub) handleReadMessage(client *Client, message *WSMessage) {
+	if message.RoomID == 0 || message.MessageID == 0 {
+		client.SendErrorWithID(message.ClientMsgID, "聊天室ID和消息ID不能为空", 400)
+		return
+	}
+
+	if !client.IsInRoom(message.RoomID) {
+		client.SendErrorWithID(message.ClientMsgID, "您不在此聊天室中", 403)
+		return
+	}
+
+	lastReadID, advanced, err := h.messageService.MarkRead(client.UserID, message.RoomID, message.MessageID)
+	if err != nil {
+		logger.Error("Failed to mark message read:", err)
+		client.SendErrorWithID(message.ClientMsgID, messageErrorText(err, "更新已读状态失败"), messageErrorCode(err))
+		return
+	}
+
+	// 已读位置没有前进时不重复广播
+	if !advanced {
+		return
+	}
+
+	receipt := &WSMessage{
+		Type:      MessageTypeRead,
+		RoomID:    message.RoomID,
+		UserID:    client.UserID,
+		Username:  client.Username,
+		MessageID: lastReadID,
+		Timestamp: time.Now(),
+	}
+
+	// 广播已读回执，包括该用户的其他连接，便于同步未读数
+	h.broadcast <- &BroadcastMessage{
+		RoomID:  message.RoomID,
+		Message: receipt,
+		Exclude: nil,
+	}
+}
+
+// handleReactionMessage 处理添加/取消表情回应
+func (h *Hub) handleReactionMessage(client *Client, message *WSMessage) {
+	if message.MessageID == 0 {
+		client.SendErrorWithID(message.ClientMsgID, "消息ID不能为空", 400)
+		return
+	}
+
+	emoji := strings.TrimSpace(message.Emoji)
+	if emoji == "" || len(emoji) > maxEmojiLength {
+		client.SendErrorWithID(message.ClientMsgID, "无效的表情", 400)
+		return
+	}
+
+	// 只有聊天室中的用户可以回应
+	target, err := h.messageService.GetMessageByID(message.MessageID)
+	if err != nil {
+		client.SendErrorWithID(message.ClientMsgID, "消息不存在", 404)
+		return
+	}
+	if !client.IsInRoom(target.RoomID) {
+		client.SendErrorWithID(message.ClientMsgID, "您不在此聊天室中", 403)
+		return
+	}
+
+	var changed bool
+	if message.Type == MessageTypeReact {
+		changed, err = h.messageService.AddReaction(client.UserID, target.ID, emoji)
+	} else {
+		changed, err = h.messageService.RemoveReaction(client.UserID, target.ID, emoji)
+	}
+	if err != nil {
+		logger.Error("Failed to update reaction:", err)
+		client.SendErrorWithID(message.ClientMsgID, messageErrorText(err, "表情回应失败"), messageErrorCode(err))
+		return
+	}
+
+	// 重复添加或取消不存在的回应时不广播
+	if !changed {
+		return
+	}
+
+	reactions, err := h.messageService.GetReactions(target.ID)
+	if err != nil {
+		logger.Error("Failed to load reactions:", err)
+		return
+	}
+
+	reactionMsg := &WSMessage{
+		Type:      message.Type,
+		RoomID:    target.RoomID,
+		UserID:    client.UserID,
+		Username:  client.Username,
+		MessageID: target.ID,
+		Emoji:     emoji,
+		Reactions: reactions,
+		Timestamp: time.Now(),
+	}
+
+	h.broadcast <- &BroadcastMessage{
+		RoomID:  target.RoomID,
+		Message: reactionMsg,
+		Exclude: nil,
+	}
+}
+
+// sendAck 向发送者回复消息确认，携带持久化后的消息ID
+func (h *Hub) sendAck(client *Client, clientMsgID string, savedMessage *entities.Message) {
+	if clientMsgID == "" {
+		return
+	}
+
+	ackMsg := &WSMessage{
+		Type:        MessageTypeAck,
+		RoomID:      savedMessage.RoomID,
+		MessageID:   savedMessage.ID,
+		ClientMsgID: clientMsgID,
+		Timestamp:   savedMessage.CreatedAt,
+	}
+
+	if err := client.SendWSMessage(ackMsg); err != nil {
+		logger.Error("Failed to send ack to client:", client.Username, "error:", err)
+	}
+}
+
+// handleEditMessage 处理编辑消息
+func (h *Hub) handleEditMessage(client *Client, message *WSMessage) {
+	if message.MessageID == 0 {
+		client.SendErrorWithID(message.ClientMsgID, "消息ID不能为空", 400)
+		return
+	}
+
+	if strings.TrimSpace(message.Content) == "" {
+		client.SendErrorWithID(message.ClientMsgID, "消息内容不能为空", 400)
+		return
+	}
+
+	updatedMessage, err := h.messageService.EditMessage(client.UserID, message.MessageID, message.Content)
+	if err != nil {
+		logger.Error("Failed to edit message:", err)
+		client.SendErrorWithID(message.ClientMsgID, messageErrorText(err, "消息编辑失败"), messageErrorCode(err))
+		return
+	}
+
+	// 广播内容以数据库中的消息为准，重新加载以带上作者信息（编辑者可能是协管员）
+	if reloaded, err := h.messageService.GetMessageByID(updatedMessage.ID); err == nil {
+		updatedMessage = reloaded
+	} else {
+		logger.Error("Failed to reload edited message:", err)
+	}
+	editMsg := NewWSMessageFromEntity(updatedMessage)
+	editMsg.Type = MessageTypeEdit
+	editMsg.ClientMsgID = message.ClientMsgID // 回传编辑请求的客户端消息ID，便于发送方对应
+	editMsg.Timestamp = *updatedMessage.EditedAt
+
+	// 广播编辑事件到聊天室，所有窗口同步更新
+	h.broadcast <- &BroadcastMessage{
+		RoomID:  editMsg.RoomID,
+		Message: editMsg,
+		Exclude: nil,
+	}
+
+	logger.Info("Message edited:", client.Username, "RoomID:", editMsg.RoomID, "MessageID:", editMsg.MessageID)
+}
+
+// handleDeleteMessage 处理删除消息
+func (h *Hub) handleDeleteMessage(client *Client, message *WSMessage) {
+	if message.MessageID == 0 {
+		client.SendErrorWithID(message.ClientMsgID, "消息ID不能为空", 400)
+		return
+	}
+
+	deletedMessage, err := h.messageService.RemoveMessage(client.UserID, message.MessageID)
+	if err != nil {
+		logger.Error("Failed to delete message:", err)
+		client.SendErrorWithID(message.ClientMsgID, messageErrorText(err, "消息删除失败"), messageErrorCode(err))
+		return
+	}
+
+	now := time.Now()
+	deleteMsg := &WSMessage{
+		Type:      MessageTypeDelete,
+		RoomID:    deletedMessage.RoomID,
+		UserID:    client.UserID,
+		Username:  client.Username,
+		MessageID: deletedMessage.ID,
+		Timestamp: now,
+		DeletedAt: &now,
+	}
+
+	// 广播删除事件到聊天室
+	h.broadcast <- &BroadcastMessage{
+		RoomID:  deleteMsg.RoomID,
+		Message: deleteMsg,
+		Exclude: nil,
+	}
+	h.broadcastThreadUpdate(deletedMessage.ThreadRootID)
+
+	logger.Info("Message deleted:", client.Username, "RoomID:", deleteMsg.RoomID, "MessageID:", deleteMsg.MessageID)
+}
+
+// broadcastThreadUpdate 广播话题的最新回复数和最后回复时间
+func (h *Hub) broadcastThreadUpdate(rootID *uint) {
+	if rootID == nil {
+		return
+	}
+
+	root, err := h.messageService.GetMessageByID(*rootID)
+	if err != nil {
+		logger.Error("Failed to load thread root:", err, "RootID:", *rootID)
+		return
+	}
+
+	threadMsg := &WSMessage{
+		Type:         MessageTypeThread,
+		RoomID:       root.RoomID,
+		MessageID:    root.ID,
+		ThreadRootID: root.ID,
+		ReplyCount:   root.ReplyCount,
+		LastReplyAt:  root.LastReplyAt,
+		Timestamp:    time.Now(),
+	}
+
+	h.broadcast <- &BroadcastMessage{
+		RoomID:  root.RoomID,
+		Message: threadMsg,
+		Exclude: nil,
+	}
+}
+
+// messageErrorCode 将消息服务错误转换为错误码
+func messageErrorCode(err error) int {
+	switch {
+	case errors.Is(err, services.ErrMessageNotFound):
+		return 404
+	case errors.Is(err, services.ErrMessageNoAuthority):
+		return 403
+	case errors.Is(err, services.ErrReplyTargetInvalid):
+		return 400
+	case errors.Is(err, services.ErrModerationNoAuthority):
+		return 403
+	case errors.Is(err, services.ErrModerationNotMember), errors.Is(err, services.ErrUserNotFound):
+		return 404
+	case errors.Is(err, services.ErrModerationSelf), errors.Is(err, services.ErrModerationInvalidRole),
+		errors.Is(err, services.ErrModerationDirectRoom):
+		return 400
+	default:
+		return 500
+	}
+}
+
+// messageErrorText 将消息服务错误转换为错误提示，系统错误使用默认提示
+func messageErrorText(err error, fallback string) string {
+	if messageErrorCode(err) == 500 {
+		return fallback
+	}
+	return err.Error()
 }
 
 // handleTypingMessage 处理正在输入消息
@@ -421,33 +789,128 @@ func (h *Hub) handlePingMessage(client *Client) {
 	}
 }
 
-// handleBroadcast 处理广播消息
+// handleBroadcast 处理广播消息，发布到广播总线由各节点投递
 func (h *Hub) handleBroadcast(broadcastMsg *BroadcastMessage) {
-	h.mutex.RLock()
-	clients := h.rooms[broadcastMsg.RoomID]
-	h.mutex.RUnlock()
+	envelope := &Envelope{
+		NodeID:  h.nodeID,
+		RoomID:  broadcastMsg.RoomID,
+		Message: broadcastMsg.Message,
+	}
+	if broadcastMsg.Exclude != nil {
+		envelope.ExcludeClientID = broadcastMsg.Exclude.ID
+	}
+
+	h.publish(envelope)
+}
 
-	if clients == nil {
+// publish 将广播消息放入发布队列，队列已满时降级为仅投递本节点
+func (h *Hub) publish(envelope *Envelope) {
+	select {
+	case h.publishQueue <- envelope:
+	default:
+		logger.Error("Broker publish queue is full, delivering locally only, room:", envelope.RoomID)
+		h.deliverLocal(envelope)
+	}
+}
+
+// runPublisher 按顺序发布队列中的广播消息，广播总线较慢（如Redis网络往返）时不影响Hub主循环
+func (h *Hub) runPublisher() {
+	for envelope := range h.publishQueue {
+		if err := h.broker.Publish(envelope); err != nil {
+			// 广播总线不可用时降级为仅投递本节点
+			logger.Error("Failed to publish broadcast message:", err)
+			h.deliverLocal(envelope)
+		}
+	}
+}
+
+// consumeBroker 消费广播总线中的消息
+func (h *Hub) consumeBroker(envelopes <-chan *Envelope) {
+	for envelope := range envelopes {
+		h.deliverLocal(envelope)
+	}
+	logger.Info("Broker subscription closed, node:", h.nodeID)
+}
+
+// deliverLocal 将广播消息投递给本节点聊天室中的客户端
+func (h *Hub) deliverLocal(envelope *Envelope) {
+	switch envelope.Action {
+	case EnvelopeActionEvict:
+		h.evictLocal(envelope)
+		return
+	case EnvelopeActionDisconnect:
+		h.disconnectLocal(envelope)
+		return
+	case EnvelopeActionPresence:
+		h.presenceLocal(envelope)
+		return
+	}
+
+	if envelope.Message == nil {
 		return
 	}
 
-	messageData, err := json.Marshal(broadcastMsg.Message)
+	messageData, err := json.Marshal(envelope.Message)
 	if err != nil {
 		logger.Error("Failed to marshal broadcast message:", err)
 		return
 	}
 
+	// 定向发送给指定用户
+	if envelope.UserID > 0 {
+		h.deliverToUser(envelope.UserID, messageData)
+		return
+	}
+
+	// 排除的客户端只在发布节点上存在
+	excludeClientID := ""
+	if envelope.NodeID == h.nodeID {
+		excludeClientID = envelope.ExcludeClientID
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	clients := h.rooms[envelope.RoomID]
 	for client := range clients {
-		if broadcastMsg.Exclude != nil && client == broadcastMsg.Exclude {
+		if excludeClientID != "" && client.ID == excludeClientID {
+			continue
+		}
+
+		// 正在补发历史消息的客户端先暂存实时消息，保证消息顺序
+		if client.holdIfResuming(envelope.RoomID, envelope.Message, messageData) {
 			continue
 		}
 
 		select {
 		case client.Send <- messageData:
 		default:
-			// 客户端发送缓冲区已满，移除客户端
+			// 客户端发送缓冲区已满，移除出聊天室（发送通道由注销流程关闭）
+			logger.Error("Client send buffer is full, removing from room:", client.Username, "RoomID:", envelope.RoomID)
 			delete(clients, client)
-			close(client.Send)
+		}
+	}
+}
+
+// SendToUser 向指定用户的所有连接发送消息（包括其他节点上的连接）
+func (h *Hub) SendToUser(userID uint, message *WSMessage) {
+	envelope := &Envelope{
+		NodeID:  h.nodeID,
+		UserID:  userID,
+		Message: message,
+	}
+
+	h.publish(envelope)
+}
+
+// deliverToUser 将消息投递给本节点上指定用户的所有连接
+func (h *Hub) deliverToUser(userID uint, messageData []byte) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for client := range h.clients {
+		if client.UserID == userID {
+			client.SendMessage(messageData)
 		}
 	}
 }
@@ -488,42 +951,6 @@ func (h *Hub) notifyUserLeft(roomID uint, client *Client) {
 	}
 }
 
-// sendUserList 发送用户列表
-func (h *Hub) sendUserList(roomID uint) {
-	h.mutex.RLock()
-	clients := h.rooms[roomID]
-	h.mutex.RUnlock()
-
-	if clients == nil {
-		return
-	}
-
-	var users []UserInfo
-	for client := range clients {
-		users = append(users, UserInfo{
-			UserID:   client.UserID,
-			Username: client.Username,
-			IsOnline: true,
-		})
-	}
-
-	userListMsg := &UserListMessage{
-		Type:   MessageTypeUserList,
-		RoomID: roomID,
-		Users:  users,
-	}
-
-	messageData, err := json.Marshal(userListMsg)
-	if err != nil {
-		logger.Error("Failed to marshal user list:", err)
-		return
-	}
-
-	for client := range clients {
-		client.SendMessage(messageData)
-	}
-}
-
 // GetStats 获取Hub统计信息
 func (h *Hub) GetStats() map[string]interface{} {
 	h.mutex.RLock()
@@ -627,3 +1054,12 @@ func (h *Hub) handleLeaveRoomDirect(client *Client, roomID uint) {
 	// 更新用户列表
 	h.sendUserList(roomID)
 }
+
+// generateNodeID 生成节点唯一标识
+func generateNodeID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("node_%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
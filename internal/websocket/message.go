@@ -1,48 +1,169 @@
 package websocket
 
-import "time"
+import (
+	"fmt"
+	"gochat/internal/models/entities"
+	"gochat/internal/services"
+	"strconv"
+	"time"
+)
 
 // MessageType 消息类型枚举
 type MessageType string
 
 const (
-	MessageTypeText     MessageType = "text"     // 文本消息
-	MessageTypeImage    MessageType = "image"    // 图片消息
-	MessageTypeFile     MessageType = "file"     // 文件消息
-	MessageTypeVideo    MessageType = "video"    // 视频消息
-	MessageTypeJoin     MessageType = "join"     // 加入聊天室
-	MessageTypeLeave    MessageType = "leave"    // 离开聊天室
-	MessageTypeTyping   MessageType = "typing"   // 正在输入
-	MessageTypeSystem   MessageType = "system"   // 系统消息
-	MessageTypeUserList MessageType = "userlist" // 用户列表更新
-	MessageTypeError    MessageType = "error"    // 错误消息
-	MessageTypePing     MessageType = "ping"     // 心跳ping消息
-	MessageTypePong     MessageType = "pong"     // 心跳pong响应
+	MessageTypeText         MessageType = "text"           // 文本消息
+	MessageTypeImage        MessageType = "image"          // 图片消息
+	MessageTypeFile         MessageType = "file"           // 文件消息
+	MessageTypeVideo        MessageType = "video"          // 视频消息
+	MessageTypeJoin         MessageType = "join"           // 加入聊天室
+	MessageTypeLeave        MessageType = "leave"          // 离开聊天室
+	MessageTypeTyping       MessageType = "typing"         // 正在输入
+	MessageTypeSystem       MessageType = "system"         // 系统消息
+	MessageTypeUserList     MessageType = "userlist"       // 用户列表更新
+	MessageTypeError        MessageType = "error"          // 错误消息
+	MessageTypePing         MessageType = "ping"           // 心跳ping消息
+	MessageTypePong         MessageType = "pong"           // 心跳pong响应
+	MessageTypeEdit         MessageType = "edit"           // 编辑消息
+	MessageTypeDelete       MessageType = "delete"         // 删除消息
+	MessageTypeAck          MessageType = "ack"            // 消息确认
+	MessageTypeResume       MessageType = "resume"         // 断线重连恢复会话
+	MessageTypeResumed      MessageType = "resumed"        // 会话恢复完成
+	MessageTypeRead         MessageType = "read"           // 已读回执
+	MessageTypeThread       MessageType = "thread_updated" // 话题更新
+	MessageTypeReact        MessageType = "react"          // 添加表情回应
+	MessageTypeUnreact      MessageType = "unreact"        // 取消表情回应
+	MessageTypeDMCreated    MessageType = "dm_created"     // 新的私聊
+	MessageTypeKick         MessageType = "kick"           // 踢出聊天室
+	MessageTypeBan          MessageType = "ban"            // 封禁
+	MessageTypeUnban        MessageType = "unban"          // 解除封禁
+	MessageTypeMute         MessageType = "mute"           // 禁言
+	MessageTypeUnmute       MessageType = "unmute"         // 解除禁言
+	MessageTypeRoomUpdated  MessageType = "room_updated"   // 聊天室信息更新
+	MessageTypeRoomDeleted  MessageType = "room_deleted"   // 聊天室已删除
+	MessageTypeJoinRequest  MessageType = "join_request"   // 新的加入申请（推送给管理员）
+	MessageTypeJoinReviewed MessageType = "join_reviewed"  // 加入申请已审批（推送给申请人）
+	MessageTypeForceLogout  MessageType = "force_logout"   // 登录状态已失效，服务端即将断开连接
 )
 
 // WSMessage WebSocket 消息结构
 type WSMessage struct {
-	Type        MessageType      `json:"type"`                  // 消息类型
-	RoomID      uint             `json:"room_id,omitempty"`     // 聊天室ID
-	UserID      uint             `json:"user_id,omitempty"`     // 用户ID
-	Username    string           `json:"username,omitempty"`    // 用户名
-	Content     string           `json:"content,omitempty"`     // 消息内容
-	Timestamp   time.Time        `json:"timestamp"`             // 时间戳
-	MessageID   uint             `json:"message_id,omitempty"`  // 消息ID（用于持久化）
-	Attachments []AttachmentInfo `json:"attachments,omitempty"` // 附件信息
+	Type         MessageType                `json:"type"`                     // 消息类型
+	RoomID       uint                       `json:"room_id,omitempty"`        // 聊天室ID
+	UserID       uint                       `json:"user_id,omitempty"`        // 用户ID
+	Username     string                     `json:"username,omitempty"`       // 用户名
+	IsBot        bool                       `json:"is_bot,omitempty"`         // 发送者是否为机器人账号
+	Content      string                     `json:"content,omitempty"`        // 消息内容
+	Timestamp    time.Time                  `json:"timestamp"`                // 时间戳
+	MessageID    uint                       `json:"message_id,omitempty"`     // 消息ID（用于持久化）
+	Attachments  []AttachmentInfo           `json:"attachments,omitempty"`    // 附件信息
+	EditedAt     *time.Time                 `json:"edited_at,omitempty"`      // 编辑时间（edit消息）
+	DeletedAt    *time.Time                 `json:"deleted_at,omitempty"`     // 删除时间（delete消息）
+	ClientMsgID  string                     `json:"client_msg_id,omitempty"`  // 客户端消息ID（用于确认和去重）
+	Rooms        []ResumeRoom               `json:"rooms,omitempty"`          // 需要恢复的聊天室（resume消息）
+	Truncated    bool                       `json:"truncated,omitempty"`      // 补发消息是否被截断（resumed消息）
+	ReplyToID    uint                       `json:"reply_to_id,omitempty"`    // 回复/引用的消息ID
+	ThreadRootID uint                       `json:"thread_root_id,omitempty"` // 所属话题的根消息ID
+	ReplyCount   int                        `json:"reply_count,omitempty"`    // 话题回复数（thread_updated消息）
+	LastReplyAt  *time.Time                 `json:"last_reply_at,omitempty"`  // 话题最后回复时间（thread_updated消息）
+	Emoji        string                     `json:"emoji,omitempty"`          // 表情（react/unreact消息）
+	Reactions    []entities.ReactionSummary `json:"reactions,omitempty"`      // 消息的表情回应聚合
+	Room         *entities.Room             `json:"room,omitempty"`           // 聊天室信息（聊天室事件）
+	TargetUserID uint                       `json:"target_user_id,omitempty"` // 管理操作的目标用户
+	Reason       string                     `json:"reason,omitempty"`         // 管理操作原因
+	Duration     int64                      `json:"duration,omitempty"`       // 封禁/禁言时长（秒），0表示永久
+	ExpiresAt    *time.Time                 `json:"expires_at,omitempty"`     // 封禁/禁言到期时间
+	Role         string                     `json:"role,omitempty"`           // 成员角色（角色调整通知）
+	JoinRequest  *entities.RoomJoinRequest  `json:"join_request,omitempty"`   // 加入申请（join_request/join_reviewed消息）
+}
+
+// maxEmojiLength 表情最大长度（字节）
+const maxEmojiLength = 32
+
+// ResumeRoom 断线重连时客户端上报的聊天室游标
+type ResumeRoom struct {
+	RoomID        uint `json:"room_id"`         // 聊天室ID
+	LastMessageID uint `json:"last_message_id"` // 客户端最后收到的消息ID
 }
 
+// maxClientMsgIDLength 客户端消息ID最大长度
+const maxClientMsgIDLength = 64
+
 // AttachmentInfo 附件信息结构
 type AttachmentInfo struct {
-	ID       uint   `json:"id"`                 // 附件ID
-	FileName string `json:"file_name"`          // 文件名
-	FileSize int64  `json:"file_size"`          // 文件大小
-	FileType string `json:"file_type"`          // MIME类型
-	Category string `json:"category"`           // 文件分类：image, file, video
-	Width    int    `json:"width,omitempty"`    // 图片/视频宽度
-	Height   int    `json:"height,omitempty"`   // 图片/视频高度
-	Duration int    `json:"duration,omitempty"` // 视频时长（秒）
-	URL      string `json:"url"`                // 访问URL
+	ID         uint              `json:"id"`                   // 附件ID
+	FileName   string            `json:"file_name"`            // 文件名
+	FileSize   int64             `json:"file_size"`            // 文件大小
+	FileType   string            `json:"file_type"`            // MIME类型
+	Category   string            `json:"category"`             // 文件分类：image, file, video
+	Width      int               `json:"width,omitempty"`      // 图片/视频宽度
+	Height     int               `json:"height,omitempty"`     // 图片/视频高度
+	Duration   int               `json:"duration,omitempty"`   // 视频时长（秒）
+	URL        string            `json:"url"`                  // 访问URL
+	Thumbnails map[string]string `json:"thumbnails,omitempty"` // 图片缩略图URL，键为最长边像素
+}
+
+// isChatMessage 判断是否为会持久化的聊天消息
+func isChatMessage(messageType MessageType) bool {
+	switch messageType {
+	case MessageTypeText, MessageTypeImage, MessageTypeFile, MessageTypeVideo:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewAttachmentInfo 根据附件实体构建附件信息
+func NewAttachmentInfo(attachment *entities.Attachment) AttachmentInfo {
+	info := AttachmentInfo{
+		ID:       attachment.ID,
+		FileName: attachment.FileName,
+		FileSize: attachment.FileSize,
+		FileType: attachment.FileType,
+		Category: attachment.Category,
+		Width:    attachment.Width,
+		Height:   attachment.Height,
+		Duration: attachment.Duration,
+		URL:      fmt.Sprintf("/api/files/%d/preview", attachment.ID),
+	}
+
+	// 能识别宽高的图片都提供缩略图地址，图片本身较小时接口返回原图
+	if attachment.Category == "image" && attachment.Width > 0 {
+		info.Thumbnails = make(map[string]string, len(services.ThumbnailSizes))
+		for _, size := range services.ThumbnailSizes {
+			info.Thumbnails[strconv.Itoa(size)] = fmt.Sprintf("/api/files/%d/thumbnail?size=%d", attachment.ID, size)
+		}
+	}
+	return info
+}
+
+// NewWSMessageFromEntity 根据已持久化的消息构建WebSocket消息
+func NewWSMessageFromEntity(message *entities.Message) *WSMessage {
+	wsMsg := &WSMessage{
+		Type:      MessageType(message.MessageType),
+		RoomID:    message.RoomID,
+		UserID:    message.UserID,
+		Username:  message.User.Username,
+		IsBot:     message.User.IsBot,
+		Content:   message.Content,
+		Timestamp: message.CreatedAt,
+		MessageID: message.ID,
+		EditedAt:  message.EditedAt,
+	}
+	if message.ClientMsgID != nil {
+		wsMsg.ClientMsgID = *message.ClientMsgID
+	}
+	if message.ReplyToID != nil {
+		wsMsg.ReplyToID = *message.ReplyToID
+	}
+	if message.ThreadRootID != nil {
+		wsMsg.ThreadRootID = *message.ThreadRootID
+	}
+	wsMsg.Reactions = message.Reactions
+	for i := range message.Attachments {
+		wsMsg.Attachments = append(wsMsg.Attachments, NewAttachmentInfo(&message.Attachments[i]))
+	}
+	return wsMsg
 }
 
 // JoinRoomRequest 加入聊天室请求
@@ -53,10 +174,11 @@ type JoinRoomRequest struct {
 
 // ErrorMessage 错误消息
 type ErrorMessage struct {
-	Type    MessageType `json:"type"`
-	Error   string      `json:"error"`
-	Code    int         `json:"code,omitempty"`
-	Details string      `json:"details,omitempty"`
+	Type        MessageType `json:"type"`
+	Error       string      `json:"error"`
+	Code        int         `json:"code,omitempty"`
+	Details     string      `json:"details,omitempty"`
+	ClientMsgID string      `json:"client_msg_id,omitempty"` // 出错的客户端消息ID
 }
 
 // SystemMessage 系统消息
@@ -0,0 +1,195 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"gochat/internal/models/entities"
+	"gochat/internal/services"
+	"gochat/pkg/logger"
+	"time"
+)
+
+// roomRoleNames 角色的显示名称
+var roomRoleNames = map[string]string{
+	entities.RoomRoleOwner:     "群主",
+	entities.RoomRoleAdmin:     "管理员",
+	entities.RoomRoleModerator: "协管员",
+	entities.RoomRoleMember:    "普通成员",
+}
+
+// handleModerationMessage 处理聊天室管理操作（踢出、封禁、禁言）
+func (h *Hub) handleModerationMessage(client *Client, message *WSMessage) {
+	if message.RoomID == 0 || message.TargetUserID == 0 {
+		client.SendErrorWithID(message.ClientMsgID, "聊天室ID和目标用户ID不能为空", 400)
+		return
+	}
+	if message.Duration < 0 || message.Duration > services.MaxSanctionSeconds {
+		client.SendErrorWithID(message.ClientMsgID, "时长必须在0到315360000秒（10年）之间", 400)
+		return
+	}
+	if len(message.Reason) > 255 {
+		client.SendErrorWithID(message.ClientMsgID, "原因不能超过255字符", 400)
+		return
+	}
+
+	duration := time.Duration(message.Duration) * time.Second
+
+	var result *services.ModerationResult
+	var err error
+	switch message.Type {
+	case MessageTypeKick:
+		result, err = h.moderationService.KickMember(client.UserID, message.RoomID, message.TargetUserID)
+	case MessageTypeBan:
+		result, err = h.moderationService.BanMember(client.UserID, message.RoomID, message.TargetUserID, message.Reason, duration)
+	case MessageTypeUnban:
+		result, err = h.moderationService.UnbanMember(client.UserID, message.RoomID, message.TargetUserID)
+	case MessageTypeMute:
+		result, err = h.moderationService.MuteMember(client.UserID, message.RoomID, message.TargetUserID, message.Reason, duration)
+	case MessageTypeUnmute:
+		result, err = h.moderationService.UnmuteMember(client.UserID, message.RoomID, message.TargetUserID)
+	}
+	if err != nil {
+		logger.Error("Failed to apply moderation:", client.Username, "Type:", message.Type, "Error:", err)
+		client.SendErrorWithID(message.ClientMsgID, messageErrorText(err, "操作失败"), messageErrorCode(err))
+		return
+	}
+
+	h.NotifyModeration(result)
+}
+
+// NotifyModeration 通知聊天室管理操作结果
+// 被踢出或封禁的用户会立即从聊天室中移除，目标用户会收到对应的管理事件，聊天室收到系统消息
+func (h *Hub) NotifyModeration(result *services.ModerationResult) {
+	content := moderationContent(result)
+	now := time.Now()
+
+	notice := &WSMessage{
+		Type:         MessageType(result.Action),
+		RoomID:       result.RoomID,
+		UserID:       result.OperatorID,
+		Username:     result.OperatorName,
+		Content:      content,
+		TargetUserID: result.TargetID,
+		Reason:       result.Reason,
+		ExpiresAt:    result.ExpiresAt,
+		Role:         result.Role,
+		Timestamp:    now,
+	}
+
+	switch result.Action {
+	case services.ModerationKick, services.ModerationBan:
+		h.EvictUser(result.RoomID, result.TargetID, notice)
+	case services.ModerationRole:
+		// 角色调整只需要系统消息
+	default:
+		h.SendToUser(result.TargetID, notice)
+	}
+
+	systemMsg := *notice
+	systemMsg.Type = MessageTypeSystem
+	h.broadcast <- &BroadcastMessage{
+		RoomID:  result.RoomID,
+		Message: &systemMsg,
+		Exclude: nil,
+	}
+
+	logger.Info("Moderation applied:", result.Action, "RoomID:", result.RoomID, "Operator:", result.OperatorName, "Target:", result.TargetName)
+}
+
+// EvictUser 将用户的所有连接（包括其他节点上的连接）移出聊天室，userID为0时移出所有连接
+// notice不为空时发送给被移出的连接
+func (h *Hub) EvictUser(roomID, userID uint, notice *WSMessage) {
+	envelope := &Envelope{
+		NodeID:  h.nodeID,
+		RoomID:  roomID,
+		UserID:  userID,
+		Message: notice,
+		Action:  EnvelopeActionEvict,
+	}
+
+	h.publish(envelope)
+}
+
+// evictLocal 将本节点上的用户连接移出聊天室，UserID为0时移出聊天室中的所有连接
+func (h *Hub) evictLocal(envelope *Envelope) {
+	var noticeData []byte
+	if envelope.Message != nil {
+		data, err := json.Marshal(envelope.Message)
+		if err != nil {
+			logger.Error("Failed to marshal evict notice:", err)
+		} else {
+			noticeData = data
+		}
+	}
+
+	evicted := false
+	h.mutex.Lock()
+	clients := h.rooms[envelope.RoomID]
+	for client := range clients {
+		if envelope.UserID > 0 && client.UserID != envelope.UserID {
+			continue
+		}
+		delete(clients, client)
+		client.evictRoom(envelope.RoomID)
+		if noticeData != nil {
+			client.SendMessage(noticeData)
+		}
+		evicted = true
+		logger.Info("Client evicted from room:", client.Username, "RoomID:", envelope.RoomID)
+	}
+	if len(clients) == 0 {
+		delete(h.rooms, envelope.RoomID)
+	}
+	h.mutex.Unlock()
+
+	if evicted {
+		h.sendUserList(envelope.RoomID)
+	}
+}
+
+// checkNotMuted 检查用户是否被禁言，被禁言时回复错误并返回false
+func (h *Hub) checkNotMuted(client *Client, message *WSMessage) bool {
+	muted, err := h.roomService.IsMuted(message.RoomID, client.UserID)
+	if err != nil {
+		logger.Error("Failed to check mute status:", err)
+		client.SendErrorWithID(message.ClientMsgID, "消息发送失败", 500)
+		return false
+	}
+	if muted {
+		client.SendErrorWithID(message.ClientMsgID, "你已被禁言", 403)
+		return false
+	}
+	return true
+}
+
+// moderationContent 生成管理操作的提示文字
+func moderationContent(result *services.ModerationResult) string {
+	var content string
+	switch result.Action {
+	case services.ModerationKick:
+		content = fmt.Sprintf("%s 被 %s 移出了聊天室", result.TargetName, result.OperatorName)
+	case services.ModerationBan:
+		content = fmt.Sprintf("%s 被 %s 封禁%s", result.TargetName, result.OperatorName, sanctionPeriod(result.ExpiresAt))
+	case services.ModerationUnban:
+		content = fmt.Sprintf("%s 被 %s 解除封禁", result.TargetName, result.OperatorName)
+	case services.ModerationMute:
+		content = fmt.Sprintf("%s 被 %s 禁言%s", result.TargetName, result.OperatorName, sanctionPeriod(result.ExpiresAt))
+	case services.ModerationUnmute:
+		content = fmt.Sprintf("%s 被 %s 解除禁言", result.TargetName, result.OperatorName)
+	case services.ModerationRole:
+		content = fmt.Sprintf("%s 被 %s 设为%s", result.TargetName, result.OperatorName, roomRoleNames[result.Role])
+	}
+
+	if result.Reason != "" {
+		content += "，原因：" + result.Reason
+	}
+	return content
+}
+
+// sanctionPeriod 生成处罚期限的提示文字
+func sanctionPeriod(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return "（永久）"
+	}
+	return fmt.Sprintf("（至 %s）", expiresAt.Format("2006-01-02 15:04"))
+}
@@ -0,0 +1,199 @@
+package websocket
+
+import (
+	"encoding/json"
+	"gochat/pkg/logger"
+	"slices"
+	"sort"
+	"time"
+)
+
+// 在线列表心跳参数
+const (
+	presenceHeartbeatInterval = 30 * time.Second              // 各节点重新发布本地在线列表的间隔
+	presenceTTL               = 3 * presenceHeartbeatInterval // 超过该时长未收到心跳的节点视为已下线
+)
+
+// nodePresence 其他节点发布的聊天室在线列表
+type nodePresence struct {
+	users  []UserInfo
+	seenAt time.Time // 最近一次收到该节点发布的时间
+}
+
+// sendUserList 本节点聊天室中的连接变化后发布本节点的在线列表，并请求其他节点回复各自的列表
+// 每个节点合并所有节点的列表后发送给本地客户端
+func (h *Hub) sendUserList(roomID uint) {
+	h.publishPresence(roomID, true)
+}
+
+// publishPresence 发布本节点聊天室中的在线列表，sync为true时请求其他节点回复
+func (h *Hub) publishPresence(roomID uint, sync bool) {
+	h.publish(&Envelope{
+		NodeID: h.nodeID,
+		RoomID: roomID,
+		Action: EnvelopeActionPresence,
+		Users:  h.localUsers(roomID),
+		Sync:   sync,
+	})
+}
+
+// localUsers 获取本节点聊天室中的在线用户，按用户排序以便比较列表是否变化
+func (h *Hub) localUsers(roomID uint) []UserInfo {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	clients := h.rooms[roomID]
+	users := make([]UserInfo, 0, len(clients))
+	for client := range clients {
+		users = append(users, UserInfo{
+			UserID:   client.UserID,
+			Username: client.Username,
+			IsOnline: true,
+		})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].UserID != users[j].UserID {
+			return users[i].UserID < users[j].UserID
+		}
+		return users[i].Username < users[j].Username
+	})
+	return users
+}
+
+// presenceLocal 处理节点发布的在线列表
+// 本节点发布的只在请求同步（连接变化）时发送给本地客户端，心跳不重复发送；
+// 其他节点发布的记录下来，列表变化时将合并后的列表发送给本地客户端
+func (h *Hub) presenceLocal(envelope *Envelope) {
+	if envelope.NodeID == h.nodeID {
+		if envelope.Sync {
+			h.deliverUserList(envelope.RoomID)
+		}
+		return
+	}
+
+	h.mutex.Lock()
+	nodes := h.presence[envelope.RoomID]
+	previous := nodes[envelope.NodeID]
+	changed := previous == nil || !slices.Equal(previous.users, envelope.Users)
+	if len(envelope.Users) == 0 {
+		delete(nodes, envelope.NodeID)
+		if len(nodes) == 0 {
+			delete(h.presence, envelope.RoomID)
+		}
+		changed = previous != nil
+	} else {
+		if nodes == nil {
+			nodes = make(map[string]*nodePresence)
+			h.presence[envelope.RoomID] = nodes
+		}
+		nodes[envelope.NodeID] = &nodePresence{users: envelope.Users, seenAt: time.Now()}
+	}
+	hasLocal := len(h.rooms[envelope.RoomID]) > 0
+	h.mutex.Unlock()
+
+	// 回复同步请求（回复不再请求同步，避免循环）
+	if envelope.Sync && hasLocal {
+		h.publishPresence(envelope.RoomID, false)
+	}
+	if changed {
+		h.deliverUserList(envelope.RoomID)
+	}
+}
+
+// runPresenceHeartbeat 定期重新发布本节点各聊天室的在线列表，并清理心跳超时的节点
+// 节点异常退出时不会发布空列表，其用户在presenceTTL后从列表中移除
+func (h *Hub) runPresenceHeartbeat() {
+	ticker := time.NewTicker(presenceHeartbeatInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.mutex.RLock()
+		roomIDs := make([]uint, 0, len(h.rooms))
+		for roomID, clients := range h.rooms {
+			if len(clients) > 0 {
+				roomIDs = append(roomIDs, roomID)
+			}
+		}
+		h.mutex.RUnlock()
+
+		for _, roomID := range roomIDs {
+			h.publishPresence(roomID, false)
+		}
+
+		for _, roomID := range h.expirePresence(time.Now()) {
+			h.deliverUserList(roomID)
+		}
+	}
+}
+
+// expirePresence 删除心跳超时的节点记录，返回在线列表发生变化的聊天室
+func (h *Hub) expirePresence(now time.Time) []uint {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var changed []uint
+	for roomID, nodes := range h.presence {
+		expired := false
+		for nodeID, presence := range nodes {
+			if now.Sub(presence.seenAt) > presenceTTL {
+				delete(nodes, nodeID)
+				expired = true
+			}
+		}
+		if len(nodes) == 0 {
+			delete(h.presence, roomID)
+		}
+		if expired {
+			changed = append(changed, roomID)
+		}
+	}
+	return changed
+}
+
+// deliverUserList 将所有节点合并后的在线列表发送给本节点聊天室中的客户端，忽略心跳超时的节点
+func (h *Hub) deliverUserList(roomID uint) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	clients := h.rooms[roomID]
+	if len(clients) == 0 {
+		return
+	}
+
+	users := make([]UserInfo, 0, len(clients))
+	for client := range clients {
+		users = append(users, UserInfo{
+			UserID:   client.UserID,
+			Username: client.Username,
+			IsOnline: true,
+		})
+	}
+
+	// 按节点ID排序，保证各节点发送的列表顺序稳定
+	now := time.Now()
+	nodes := h.presence[roomID]
+	nodeIDs := make([]string, 0, len(nodes))
+	for nodeID, presence := range nodes {
+		if now.Sub(presence.seenAt) <= presenceTTL {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+	}
+	sort.Strings(nodeIDs)
+	for _, nodeID := range nodeIDs {
+		users = append(users, nodes[nodeID].users...)
+	}
+
+	messageData, err := json.Marshal(&UserListMessage{
+		Type:   MessageTypeUserList,
+		RoomID: roomID,
+		Users:  users,
+	})
+	if err != nil {
+		logger.Error("Failed to marshal user list:", err)
+		return
+	}
+
+	for client := range clients {
+		client.SendMessage(messageData)
+	}
+}
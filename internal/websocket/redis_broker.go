@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gochat/internal/config"
+	"gochat/pkg/logger"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisDialTimeout 连接Redis的超时时间
+const redisDialTimeout = 5 * time.Second
+
+// RedisBroker 基于Redis发布订阅的广播总线
+type RedisBroker struct {
+	client  *redis.Client
+	channel string
+	pubsubs []*redis.PubSub
+}
+
+// NewRedisBroker 创建Redis广播总线
+func NewRedisBroker(cfg *config.RedisConfig) (*RedisBroker, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisDialTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisBroker{
+		client:  client,
+		channel: cfg.Channel,
+	}, nil
+}
+
+// Publish 发布广播消息到Redis频道
+func (b *RedisBroker) Publish(envelope *Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+// Subscribe 订阅Redis频道
+func (b *RedisBroker) Subscribe() (<-chan *Envelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisDialTimeout)
+	defer cancel()
+
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	// 等待订阅确认，确保订阅建立后再返回
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe redis channel: %w", err)
+	}
+	b.pubsubs = append(b.pubsubs, pubsub)
+
+	envelopes := make(chan *Envelope, brokerBufferSize)
+	go func() {
+		defer close(envelopes)
+		for msg := range pubsub.Channel() {
+			var envelope Envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				logger.Error("Failed to unmarshal broker envelope:", err)
+				continue
+			}
+			envelopes <- &envelope
+		}
+	}()
+
+	return envelopes, nil
+}
+
+// Close 关闭订阅和Redis连接
+func (b *RedisBroker) Close() error {
+	for _, pubsub := range b.pubsubs {
+		pubsub.Close()
+	}
+	return b.client.Close()
+}
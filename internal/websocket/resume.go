@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"encoding/json"
+	"gochat/pkg/logger"
+	"time"
+)
+
+// maxResumeMessages 每个聊天室单次最多补发的消息数量
+const maxResumeMessages = 200
+
+// handleResumeMessage 处理断线重连恢复会话
+// 客户端上报每个聊天室最后收到的消息ID，Hub自动重新加入这些聊天室，
+// 并在投递实时消息之前按顺序补发断线期间遗漏的消息
+func (h *Hub) handleResumeMessage(client *Client, message *WSMessage) {
+	if len(message.Rooms) == 0 {
+		client.SendErrorWithID(message.ClientMsgID, "恢复的聊天室列表不能为空", 400)
+		return
+	}
+
+	rooms := message.Rooms
+
+	// 在单独的goroutine中处理数据库查询和补发，避免阻塞Hub主循环
+	go func() {
+		for _, room := range rooms {
+			h.resumeRoom(client, room)
+		}
+	}()
+}
+
+// resumeRoom 恢复单个聊天室：重新加入并补发遗漏的消息
+func (h *Hub) resumeRoom(client *Client, room ResumeRoom) {
+	roomID := room.RoomID
+	if roomID == 0 {
+		return
+	}
+
+	// 验证用户是否有权限加入聊天室
+	canJoin, err := h.roomService.CanUserJoinRoom(client.UserID, roomID)
+	if err != nil || !canJoin {
+		logger.Error("用户无权限恢复聊天室:", client.Username, "RoomID:", roomID, "Error:", err)
+		client.SendError("无权限加入聊天室", 403)
+		return
+	}
+
+	// 先开始暂存实时消息再加入聊天室，补发期间产生的新消息不会丢失也不会插队
+	client.beginResume(roomID)
+
+	h.mutex.Lock()
+	if _, registered := h.clients[client]; !registered {
+		// 补发开始前连接已经断开
+		h.mutex.Unlock()
+		client.finishResume(roomID, 0)
+		return
+	}
+	if h.rooms[roomID] == nil {
+		h.rooms[roomID] = make(map[*Client]bool)
+	}
+	h.rooms[roomID][client] = true
+	h.mutex.Unlock()
+
+	client.mutex.Lock()
+	client.Rooms[roomID] = true
+	client.mutex.Unlock()
+
+	lastMessageID := room.LastMessageID
+	truncated := false
+
+	// LastMessageID为0表示客户端没有本地消息，只重新加入，历史消息通过REST接口获取
+	if room.LastMessageID > 0 {
+		messages, err := h.messageService.GetMessagesAfter(roomID, room.LastMessageID, maxResumeMessages+1)
+		if err != nil {
+			logger.Error("Failed to load missed messages:", err, "RoomID:", roomID)
+			client.SendError("获取遗漏消息失败", 500)
+			client.finishResume(roomID, lastMessageID)
+			return
+		}
+
+		if len(messages) > maxResumeMessages {
+			truncated = true
+			messages = messages[:maxResumeMessages]
+		}
+
+		for _, message := range messages {
+			data, err := json.Marshal(NewWSMessageFromEntity(message))
+			if err != nil {
+				logger.Error("Failed to marshal missed message:", err)
+				continue
+			}
+			if !client.sendBlocking(data) {
+				client.finishResume(roomID, lastMessageID)
+				return
+			}
+			lastMessageID = message.ID
+		}
+	}
+
+	// 通知客户端补发完成，truncated为true时客户端应以message_id继续恢复
+	resumedMsg := &WSMessage{
+		Type:      MessageTypeResumed,
+		RoomID:    roomID,
+		MessageID: lastMessageID,
+		Truncated: truncated,
+		Timestamp: time.Now(),
+	}
+	if data, err := json.Marshal(resumedMsg); err == nil {
+		client.sendBlocking(data)
+	}
+
+	client.finishResume(roomID, lastMessageID)
+
+	logger.Info("会话已恢复:", client.Username, "RoomID:", roomID, "LastMessageID:", lastMessageID)
+
+	h.notifyUserJoined(roomID, client)
+	h.sendUserList(roomID)
+}
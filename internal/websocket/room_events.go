@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"gochat/internal/models/entities"
+	"gochat/pkg/logger"
+	"time"
+)
+
+// NotifyRoomUpdated 向聊天室广播聊天室信息更新事件
+func (h *Hub) NotifyRoomUpdated(room *entities.Room, operatorID uint, operatorName, content string) {
+	message := &WSMessage{
+		Type:      MessageTypeRoomUpdated,
+		RoomID:    room.ID,
+		UserID:    operatorID,
+		Username:  operatorName,
+		Content:   content,
+		Room:      room,
+		Timestamp: time.Now(),
+	}
+
+	h.broadcast <- &BroadcastMessage{
+		RoomID:  room.ID,
+		Message: message,
+		Exclude: nil,
+	}
+
+	logger.Info("Room updated:", room.ID, "Operator:", operatorName)
+}
+
+// NotifyRoomDeleted 通知聊天室已删除，并将所有节点上的连接移出该聊天室
+func (h *Hub) NotifyRoomDeleted(roomID, operatorID uint, operatorName string) {
+	notice := &WSMessage{
+		Type:      MessageTypeRoomDeleted,
+		RoomID:    roomID,
+		UserID:    operatorID,
+		Username:  operatorName,
+		Content:   "聊天室已被 " + operatorName + " 删除",
+		Timestamp: time.Now(),
+	}
+
+	h.EvictUser(roomID, 0, notice)
+
+	logger.Info("Room deleted:", roomID, "Operator:", operatorName)
+}
+
+// NotifyJoinRequest 将新的加入申请推送给聊天室的在线管理员
+func (h *Hub) NotifyJoinRequest(request *entities.RoomJoinRequest, adminIDs []uint) {
+	message := &WSMessage{
+		Type:        MessageTypeJoinRequest,
+		RoomID:      request.RoomID,
+		UserID:      request.UserID,
+		Username:    request.User.Username,
+		Content:     request.User.Username + " 申请加入聊天室",
+		JoinRequest: request,
+		Timestamp:   time.Now(),
+	}
+
+	for _, adminID := range adminIDs {
+		h.SendToUser(adminID, message)
+	}
+}
+
+// NotifyJoinReviewed 将加入申请的审批结果推送给申请人
+func (h *Hub) NotifyJoinReviewed(request *entities.RoomJoinRequest, operatorName string) {
+	content := "你的加入申请已被 " + operatorName + " 拒绝"
+	if request.Status == entities.JoinRequestApproved {
+		content = "你的加入申请已被 " + operatorName + " 同意"
+	}
+
+	h.SendToUser(request.UserID, &WSMessage{
+		Type:        MessageTypeJoinReviewed,
+		RoomID:      request.RoomID,
+		Username:    operatorName,
+		Content:     content,
+		JoinRequest: request,
+		Timestamp:   time.Now(),
+	})
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif" // 注册GIF解码器
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // 注册WebP解码器
+)
+
+// 图片处理参数
+const (
+	MaxImagePixels       = 50_000_000 // 允许完整解码的最大像素数，避免解压炸弹耗尽内存
+	thumbnailJPEGQuality = 85
+)
+
+// ErrImageTooLarge 图片像素数超过完整解码的上限
+var ErrImageTooLarge = errors.New("image too large to decode")
+
+// DecodeImageConfig 只读取文件头，返回图片宽高和格式（jpeg、png、gif、webp）
+func DecodeImageConfig(r io.Reader) (image.Config, string, error) {
+	return image.DecodeConfig(r)
+}
+
+// DecodeImage 解码图片（GIF取第一帧），像素数超过MaxImagePixels时返回ErrImageTooLarge
+func DecodeImage(r io.ReadSeeker) (image.Image, string, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return nil, "", ErrImageTooLarge
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	return image.Decode(r)
+}
+
+// ResizeToFit 等比缩放图片，使最长边不超过maxSize；图片本身不超过时原样返回
+func ResizeToFit(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSize && height <= maxSize {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxSize/width)
+		width = maxSize
+	} else {
+		width = max(1, width*maxSize/height)
+		height = maxSize
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// EncodeThumbnail 编码缩略图，asJPEG为false时使用PNG以保留透明度
+func EncodeThumbnail(img image.Image, asJPEG bool) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if asJPEG {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: thumbnailJPEGQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
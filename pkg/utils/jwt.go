@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -9,25 +12,31 @@ import (
 
 // JWTClaims JWT声明
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	Role         string `json:"role"`
+	TokenVersion int    `json:"ver"`           // 用户token版本，版本变化后旧token全部失效
+	SessionID    uint   `json:"sid,omitempty"` // 登录会话ID，会话撤销后token失效
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID uint, username string, secret string, expireHours time.Duration) (string, error) {
-	claims := JWTClaims{
-		UserID:   userID,
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expireHours)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "gochat",
-			Subject:   "user",
-		},
+// GenerateToken 生成JWT token，claims中未设置ID时自动生成jti
+func GenerateToken(claims *JWTClaims, secret string, expireHours time.Duration) (string, error) {
+	if claims.ID == "" {
+		tokenID, err := GenerateRandomToken(16)
+		if err != nil {
+			return "", err
+		}
+		claims.ID = tokenID
 	}
 
+	now := time.Now()
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(expireHours))
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.Issuer = "gochat"
+	claims.Subject = "user"
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
@@ -53,18 +62,17 @@ func ParseToken(tokenString string, secret string) (*JWTClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// RefreshToken 刷新token
-func RefreshToken(tokenString string, secret string, expireHours time.Duration) (string, error) {
-	claims, err := ParseToken(tokenString, secret)
-	if err != nil {
+// GenerateRandomToken 生成指定字节数的随机令牌（十六进制编码）
+func GenerateRandomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
 		return "", err
 	}
+	return hex.EncodeToString(buf), nil
+}
 
-	// 检查token是否即将过期（在最后30分钟内）
-	if time.Until(claims.ExpiresAt.Time) > 30*time.Minute {
-		return "", errors.New("token is still valid")
-	}
-
-	// 生成新token
-	return GenerateToken(claims.UserID, claims.Username, secret, expireHours)
+// HashToken 计算令牌的SHA-256摘要，数据库中只保存摘要
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }